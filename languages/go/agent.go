@@ -0,0 +1,629 @@
+package main
+
+// Mock Voice Agent conversations
+// Voice Agent samples hold a WebSocket conversation: they send Settings and
+// microphone audio, and the agent answers with JSON events, asks for
+// function calls, and speaks back in binary audio. The mock serves the
+// agent endpoint (/v1/agent/converse, and the older /agent) and plays a
+// scripted conversation to every client:
+//
+//	await: audio          wait for the client's audio
+//	event: {type: ...}    send an event
+//	function_call: {}     ask for a call of a function Settings declared (the
+//	                      first, unless name is given; skipped when none are)
+//	                      and wait for its FunctionCallResponse
+//	audio: 1.5            speak this many seconds of agent audio
+//
+// mocking.agent in the framework config replaces the script, the fixture
+// audio the agent speaks, and the audio written into agent samples'
+// workspaces under the file names they open, so samples streaming a
+// recording have one to stream.
+//
+// Each Voice Agent sample runs with its own API key, and a sample that
+// takes its key from DEEPGRAM_API_KEY, as the SDK's constructors do, fails
+// unless its conversation followed the script to the end without protocol
+// errors: Settings first, function calls answered, no unknown messages.
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Steps of a mock agent script
+const (
+	AgentStepAwait        = "await"
+	AgentStepEvent        = "event"
+	AgentStepFunctionCall = "function_call"
+	AgentStepAudio        = "audio"
+)
+
+// Sample rates of generated agent audio when Settings don't name one, and
+// of the generated input fixture
+const (
+	agentOutputRate = 24000
+	agentInputRate  = 16000
+)
+
+// agentPaths are the paths the Voice Agent API has been served at
+var agentPaths = []string{"/v1/agent", "/agent"}
+
+// audioLiteralRegex matches string literals naming a local audio file
+var audioLiteralRegex = regexp.MustCompile(`"([^"\s:]+\.(?:wav|mp3|flac|ogg|opus|m4a|webm|raw|pcm))"`)
+
+// agentStep is one step of a mock agent script
+type agentStep struct {
+	Kind string
+	// Event is the event sent, or the function call's name and arguments
+	Event   map[string]interface{}
+	Seconds float64
+}
+
+// defaultAgentScript greets a caller, calls one of their functions, and
+// answers in a second of audio
+var defaultAgentScript = []agentStep{
+	{Kind: AgentStepAwait},
+	{Kind: AgentStepEvent, Event: map[string]interface{}{"type": "UserStartedSpeaking"}},
+	{Kind: AgentStepEvent, Event: map[string]interface{}{"type": "ConversationText", "role": "user", "content": "hello from the mock server"}},
+	{Kind: AgentStepFunctionCall, Event: map[string]interface{}{}},
+	{Kind: AgentStepEvent, Event: map[string]interface{}{"type": "ConversationText", "role": "assistant", "content": "Hello from the mock agent."}},
+	{Kind: AgentStepEvent, Event: map[string]interface{}{"type": "AgentStartedSpeaking", "total_latency": 0.5, "tts_latency": 0.2, "ttt_latency": 0.3}},
+	{Kind: AgentStepAudio, Seconds: 1},
+	{Kind: AgentStepEvent, Event: map[string]interface{}{"type": "AgentAudioDone"}},
+}
+
+// MockAgent plays scripted conversations and records them by API key
+type MockAgent struct {
+	script       []agentStep
+	awaitTimeout time.Duration
+	// outputAudio is spoken by the agent, or generated when nil
+	outputAudio []byte
+
+	mu            sync.Mutex
+	conversations map[string][]*AgentConversation
+}
+
+// AgentConversation is what happened on one connection to the mock agent
+type AgentConversation struct {
+	Settings      bool     `json:"settings"`
+	AudioReceived int      `json:"audio_received_bytes"`
+	AudioSent     int      `json:"audio_sent_bytes"`
+	FunctionCalls int      `json:"function_calls"`
+	Steps         int      `json:"steps"`
+	Completed     bool     `json:"completed"`
+	Waiting       string   `json:"waiting,omitempty"`
+	Problems      []string `json:"problems,omitempty"`
+
+	functions  []map[string]interface{}
+	outputRate int
+	pending    map[string]bool
+	// scripted is closed once the script has finished or failed
+	scripted chan struct{}
+}
+
+// agentSettleTimeout bounds how long a sample's conversation is given to
+// finish its script after the sample exits, since a client may hang up as
+// soon as the last scripted frame arrives
+const agentSettleTimeout = 2 * time.Second
+
+// agentMessage is a message received from a client
+type agentMessage struct {
+	opcode int
+	data   []byte
+}
+
+// mockAgent builds the mock agent from mocking.agent
+func (e *GoExecutor) mockAgent() (*MockAgent, error) {
+	config := configSection(configSection(e.FrameworkConfig, "mocking"), "agent")
+	agent := &MockAgent{
+		script:        defaultAgentScript,
+		awaitTimeout:  time.Duration(configFloat(config, "await_seconds", 5) * float64(time.Second)),
+		conversations: make(map[string][]*AgentConversation),
+	}
+
+	if path := configString(config, "output_audio", ""); path != "" {
+		audio, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("mocking.agent.output_audio: %v", err)
+		}
+		agent.outputAudio = audio
+	}
+
+	if configured, ok := config["script"].([]interface{}); ok && len(configured) > 0 {
+		agent.script = nil
+		for i, entry := range configured {
+			step, err := parseAgentStep(entry)
+			if err != nil {
+				return nil, fmt.Errorf("mocking.agent.script step %d: %v", i+1, err)
+			}
+			agent.script = append(agent.script, step)
+		}
+	}
+	return agent, nil
+}
+
+// parseAgentStep reads one configured script step
+func parseAgentStep(entry interface{}) (agentStep, error) {
+	section, _ := entry.(map[string]interface{})
+	if len(section) != 1 {
+		return agentStep{}, fmt.Errorf("expected one of %s, %s, %s, or %s", AgentStepAwait, AgentStepEvent, AgentStepFunctionCall, AgentStepAudio)
+	}
+	for kind, value := range section {
+		switch kind {
+		case AgentStepAwait:
+			if value != "audio" {
+				return agentStep{}, fmt.Errorf("%s: unknown value %v (expected audio)", kind, value)
+			}
+			return agentStep{Kind: kind}, nil
+		case AgentStepEvent:
+			event, _ := value.(map[string]interface{})
+			if configString(event, "type", "") == "" {
+				return agentStep{}, fmt.Errorf("%s has no type", kind)
+			}
+			return agentStep{Kind: kind, Event: event}, nil
+		case AgentStepFunctionCall:
+			call, _ := value.(map[string]interface{})
+			if call == nil {
+				call = map[string]interface{}{}
+			}
+			return agentStep{Kind: kind, Event: call}, nil
+		case AgentStepAudio:
+			seconds, _ := value.(float64)
+			if seconds <= 0 {
+				return agentStep{}, fmt.Errorf("%s: expected a number of seconds", kind)
+			}
+			return agentStep{Kind: kind, Seconds: seconds}, nil
+		default:
+			return agentStep{}, fmt.Errorf("unknown step %q", kind)
+		}
+	}
+	return agentStep{}, nil
+}
+
+// isAgentPath reports whether a request path is the Voice Agent endpoint
+func isAgentPath(path string) bool {
+	for _, prefix := range agentPaths {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP holds a scripted conversation with a WebSocket client
+func (a *MockAgent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isWebSocketUpgrade(r) {
+		writeMockError(w, http.StatusUpgradeRequired, "UPGRADE_REQUIRED", "The Voice Agent API is only served over WebSocket.")
+		return
+	}
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	// The reader stops at EOF without a close frame, leaving the connection open
+	defer conn.conn.Close()
+
+	conversation := &AgentConversation{outputRate: agentOutputRate, pending: make(map[string]bool), scripted: make(chan struct{})}
+	a.mu.Lock()
+	key := mockAPIKey(r)
+	a.conversations[key] = append(a.conversations[key], conversation)
+	a.mu.Unlock()
+
+	// Messages are read in the background so pings are answered while the
+	// script sends
+	incoming := make(chan agentMessage)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(incoming)
+		for {
+			opcode, data, err := conn.readMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case incoming <- agentMessage{opcode, data}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = a.converse(conn, conversation, incoming)
+	close(conversation.scripted)
+	if err != nil {
+		conn.close(wsClosePolicyViolation, err.Error())
+		return
+	}
+	// The agent keeps listening until the client hangs up
+	for message := range incoming {
+		a.handle(conn, conversation, message)
+	}
+}
+
+// converse plays the script, failing when the client doesn't do its part
+func (a *MockAgent) converse(conn *wsConn, conversation *AgentConversation, incoming <-chan agentMessage) error {
+	send := func(event map[string]interface{}) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return conn.writeFrame(wsText, data)
+	}
+	await := func(waiting string, ready func() bool) error {
+		a.update(conversation, func() { conversation.Waiting = waiting })
+		timeout := time.NewTimer(a.awaitTimeout)
+		defer timeout.Stop()
+		for !a.check(conversation, ready) {
+			select {
+			case message, ok := <-incoming:
+				if !ok {
+					return fmt.Errorf("the client closed the connection %s", waiting)
+				}
+				a.handle(conn, conversation, message)
+			case <-timeout.C:
+				err := fmt.Errorf("timed out after %s %s", a.awaitTimeout, waiting)
+				a.problem(conn, conversation, err.Error())
+				return err
+			}
+		}
+		a.update(conversation, func() { conversation.Waiting = "" })
+		return nil
+	}
+
+	if err := send(map[string]interface{}{"type": "Welcome", "request_id": "00000000-0000-0000-0000-000000000000", "session_id": "00000000-0000-0000-0000-000000000000"}); err != nil {
+		return err
+	}
+	if err := await("waiting for Settings", func() bool { return conversation.Settings }); err != nil {
+		return err
+	}
+
+	for i, step := range a.script {
+		var err error
+		switch step.Kind {
+		case AgentStepAwait:
+			err = await("waiting for audio", func() bool { return conversation.AudioReceived > 0 })
+		case AgentStepEvent:
+			err = send(step.Event)
+		case AgentStepAudio:
+			err = a.speak(conn, conversation, step.Seconds)
+		case AgentStepFunctionCall:
+			function, arguments := a.functionCall(conversation, step.Event)
+			if function == "" {
+				break
+			}
+			id := fmt.Sprintf("fc_mock_%d", i+1)
+			a.update(conversation, func() {
+				conversation.FunctionCalls++
+				conversation.pending[id] = true
+			})
+			err = send(map[string]interface{}{
+				"type": "FunctionCallRequest",
+				"functions": []interface{}{map[string]interface{}{
+					"id": id, "name": function, "arguments": arguments, "client_side": true,
+				}},
+				// Fields of the request before functions were batched
+				"function_call_id": id,
+				"function_name":    function,
+				"input":            stringArguments(arguments),
+			})
+			if err == nil {
+				err = await("waiting for the response to function call "+function, func() bool { return !conversation.pending[id] })
+			}
+		}
+		if err != nil {
+			return err
+		}
+		a.update(conversation, func() { conversation.Steps = i + 1 })
+	}
+	a.update(conversation, func() { conversation.Completed = true })
+	return nil
+}
+
+// handle answers one message from the client
+func (a *MockAgent) handle(conn *wsConn, conversation *AgentConversation, message agentMessage) {
+	if message.opcode == wsBinary {
+		a.update(conversation, func() { conversation.AudioReceived += len(message.data) })
+		if !a.check(conversation, func() bool { return conversation.Settings }) {
+			a.problem(conn, conversation, "audio was sent before Settings")
+		}
+		return
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(message.data, &event); err != nil {
+		a.problem(conn, conversation, "invalid JSON message: "+err.Error())
+		return
+	}
+	reply := func(event map[string]interface{}) {
+		if data, err := json.Marshal(event); err == nil {
+			conn.writeFrame(wsText, data)
+		}
+	}
+
+	switch messageType := configString(event, "type", ""); messageType {
+	case "Settings", "SettingsConfiguration":
+		think := configSection(configSection(event, "agent"), "think")
+		functions, _ := think["functions"].([]interface{})
+		a.update(conversation, func() {
+			conversation.Settings = true
+			conversation.functions = nil
+			for _, function := range functions {
+				if function, ok := function.(map[string]interface{}); ok {
+					conversation.functions = append(conversation.functions, function)
+				}
+			}
+			if rate := configInt(configSection(configSection(event, "audio"), "output"), "sample_rate", 0); rate > 0 {
+				conversation.outputRate = rate
+			}
+		})
+		reply(map[string]interface{}{"type": "SettingsApplied"})
+	case "KeepAlive", "AgentKeepAlive":
+	case "FunctionCallResponse":
+		id := configString(event, "id", configString(event, "function_call_id", ""))
+		answered := false
+		a.update(conversation, func() {
+			answered = conversation.pending[id]
+			delete(conversation.pending, id)
+		})
+		if !answered {
+			a.problem(conn, conversation, fmt.Sprintf("FunctionCallResponse for unknown function call %q", id))
+		}
+	case "Close":
+		conn.close(wsCloseNormal, "")
+	case "UpdatePrompt", "UpdateInstructions":
+		reply(map[string]interface{}{"type": "PromptUpdated"})
+	case "UpdateSpeak":
+		reply(map[string]interface{}{"type": "SpeakUpdated"})
+	case "InjectAgentMessage":
+		reply(map[string]interface{}{"type": "ConversationText", "role": "assistant", "content": configString(event, "message", configString(event, "content", ""))})
+	case "InjectUserMessage":
+		reply(map[string]interface{}{"type": "ConversationText", "role": "user", "content": configString(event, "content", "")})
+	default:
+		a.problem(conn, conversation, fmt.Sprintf("unknown message type %q", messageType))
+	}
+}
+
+// functionCall picks the function a script step calls, with its arguments
+// as JSON: the step's, else a value of each declared parameter's type
+func (a *MockAgent) functionCall(conversation *AgentConversation, step map[string]interface{}) (name, arguments string) {
+	var function map[string]interface{}
+	a.check(conversation, func() bool {
+		for _, declared := range conversation.functions {
+			if want := configString(step, "name", ""); want == "" || want == configString(declared, "name", "") {
+				function = declared
+				return true
+			}
+		}
+		return false
+	})
+	name = configString(step, "name", configString(function, "name", ""))
+	if name == "" {
+		return "", ""
+	}
+
+	values, ok := step["arguments"].(map[string]interface{})
+	if !ok {
+		values = make(map[string]interface{})
+		for parameter := range configSection(configSection(function, "parameters"), "properties") {
+			schema := configSection(configSection(configSection(function, "parameters"), "properties"), parameter)
+			switch configString(schema, "type", "") {
+			case "number", "integer":
+				values[parameter] = 1
+			case "boolean":
+				values[parameter] = true
+			default:
+				values[parameter] = "mock"
+			}
+		}
+	}
+	encoded, _ := json.Marshal(values)
+	return name, string(encoded)
+}
+
+// stringArguments converts function call arguments to the string map older
+// SDKs decode input into
+func stringArguments(arguments string) map[string]string {
+	var values map[string]interface{}
+	json.Unmarshal([]byte(arguments), &values)
+	input := make(map[string]string, len(values))
+	for name, value := range values {
+		input[name] = fmt.Sprint(value)
+	}
+	return input
+}
+
+// speak sends seconds of agent audio, from mocking.agent.output_audio or a
+// generated tone at the output rate Settings asked for
+func (a *MockAgent) speak(conn *wsConn, conversation *AgentConversation, seconds float64) error {
+	audio := a.outputAudio
+	if audio == nil {
+		rate := agentOutputRate
+		a.check(conversation, func() bool { rate = conversation.outputRate; return true })
+		audio = tonePCM(seconds, rate)
+	}
+	for start := 0; start < len(audio); start += 8192 {
+		end := start + 8192
+		if end > len(audio) {
+			end = len(audio)
+		}
+		if err := conn.writeFrame(wsBinary, audio[start:end]); err != nil {
+			return err
+		}
+		a.update(conversation, func() { conversation.AudioSent += end - start })
+	}
+	return nil
+}
+
+// problem records a protocol error and reports it to the client, as the
+// API does, in an Error event
+func (a *MockAgent) problem(conn *wsConn, conversation *AgentConversation, description string) {
+	a.update(conversation, func() { conversation.Problems = append(conversation.Problems, description) })
+	data, _ := json.Marshal(map[string]interface{}{"type": "Error", "code": "MOCK_PROTOCOL_ERROR", "description": description, "message": description})
+	conn.writeFrame(wsText, data)
+}
+
+// update changes a conversation while holding the agent's lock
+func (a *MockAgent) update(conversation *AgentConversation, change func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	change()
+}
+
+// check evaluates a condition on a conversation while holding the agent's lock
+func (a *MockAgent) check(conversation *AgentConversation, condition func() bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return condition()
+}
+
+// Conversations returns copies of the conversations held with an API key
+func (a *MockAgent) Conversations(key string) []AgentConversation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	conversations := make([]AgentConversation, 0, len(a.conversations[key]))
+	for _, conversation := range a.conversations[key] {
+		copied := *conversation
+		copied.Problems = append([]string(nil), conversation.Problems...)
+		conversations = append(conversations, copied)
+	}
+	return conversations
+}
+
+// problem explains how a conversation fell short of the script, or is ""
+func (c AgentConversation) problem(steps int) string {
+	switch {
+	case len(c.Problems) > 0:
+		return strings.Join(c.Problems, "; ")
+	case !c.Completed && c.Waiting != "":
+		return fmt.Sprintf("the conversation stopped after step %d of %d, %s", c.Steps, steps, c.Waiting)
+	case !c.Completed:
+		return fmt.Sprintf("the conversation stopped after step %d of %d", c.Steps, steps)
+	}
+	return ""
+}
+
+// mockAPIKey returns the key a request authenticates with, from its
+// Authorization header or, as browsers send it, its WebSocket subprotocols
+func mockAPIKey(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if _, key, ok := strings.Cut(auth, " "); ok {
+			return strings.TrimSpace(key)
+		}
+	}
+	protocols := strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")
+	if len(protocols) == 2 && strings.EqualFold(strings.TrimSpace(protocols[0]), "token") {
+		return strings.TrimSpace(protocols[1])
+	}
+	return ""
+}
+
+// agentAPIKey is the key a Voice Agent sample runs with against the mock,
+// naming the sample so its conversation can be found; "" otherwise
+func (e *GoExecutor) agentAPIKey(sample CodeSample) string {
+	if e.Mock == nil || e.Mock.Agent == nil || sample.Product != ProductVoiceAgent {
+		return ""
+	}
+	return "test_key_agent_" + sample.ID
+}
+
+// checkAgentConversation fails a passed Voice Agent sample whose latest
+// conversation with the mock agent didn't follow the script. Samples that
+// didn't connect with their own key, e.g. because they hard-code one,
+// aren't checked.
+func (e *GoExecutor) checkAgentConversation(result *TestResult) {
+	key := e.agentAPIKey(result.Sample)
+	if key == "" {
+		return
+	}
+	conversations := e.Mock.Agent.Conversations(key)
+	if len(conversations) == 0 {
+		return
+	}
+	select {
+	case <-conversations[len(conversations)-1].scripted:
+		conversations = e.Mock.Agent.Conversations(key)
+	case <-time.After(agentSettleTimeout):
+	}
+	if problem := conversations[len(conversations)-1].problem(len(e.Mock.Agent.script)); problem != "" {
+		result.Success = false
+		result.Status = StatusFailed
+		result.ErrorCategory = ErrorCategoryOutput
+		result.ErrorMessage = "agent conversation failed: " + problem
+	}
+}
+
+// writeAudioFixtures writes input audio into a Voice Agent sample's
+// workspace under each relative audio file name the sample mentions, from
+// mocking.agent.input_audio or a generated WAV, unless
+// mocking.create_mock_audio is false
+func (e *GoExecutor) writeAudioFixtures(dir string, sample CodeSample) error {
+	mocking := configSection(e.FrameworkConfig, "mocking")
+	if sample.Product != ProductVoiceAgent || !configBool(mocking, "create_mock_audio", true) {
+		return nil
+	}
+
+	var audio []byte
+	for _, match := range audioLiteralRegex.FindAllStringSubmatch(sample.Code, -1) {
+		name := filepath.FromSlash(match[1])
+		if !filepath.IsLocal(name) {
+			continue
+		}
+		if audio == nil {
+			if path := configString(configSection(mocking, "agent"), "input_audio", ""); path != "" {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return fmt.Errorf("mocking.agent.input_audio: %v", err)
+				}
+				audio = content
+			} else {
+				audio = toneWAV(2, agentInputRate)
+			}
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, audio, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tonePCM generates seconds of a quiet 440 Hz tone as mono linear16 audio
+func tonePCM(seconds float64, rate int) []byte {
+	samples := int(seconds * float64(rate))
+	audio := make([]byte, 0, 2*samples)
+	for i := 0; i < samples; i++ {
+		value := int16(3000 * math.Sin(2*math.Pi*440*float64(i)/float64(rate)))
+		audio = binary.LittleEndian.AppendUint16(audio, uint16(value))
+	}
+	return audio
+}
+
+// toneWAV wraps tonePCM in a WAV header
+func toneWAV(seconds float64, rate int) []byte {
+	pcm := tonePCM(seconds, rate)
+	header := []byte("RIFF")
+	header = binary.LittleEndian.AppendUint32(header, uint32(36+len(pcm)))
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, 1) // PCM
+	header = binary.LittleEndian.AppendUint16(header, 1) // mono
+	header = binary.LittleEndian.AppendUint32(header, uint32(rate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(2*rate))
+	header = binary.LittleEndian.AppendUint16(header, 2)
+	header = binary.LittleEndian.AppendUint16(header, 16)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(pcm)))
+	return append(header, pcm...)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAgentStep(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry interface{}
+		want  agentStep
+		err   string
+	}{
+		{"await audio", map[string]interface{}{"await": "audio"}, agentStep{Kind: AgentStepAwait}, ""},
+		{
+			"event",
+			map[string]interface{}{"event": map[string]interface{}{"type": "ConversationText", "content": "hi"}},
+			agentStep{Kind: AgentStepEvent, Event: map[string]interface{}{"type": "ConversationText", "content": "hi"}},
+			"",
+		},
+		{
+			"function call",
+			map[string]interface{}{"function_call": map[string]interface{}{"name": "get_weather"}},
+			agentStep{Kind: AgentStepFunctionCall, Event: map[string]interface{}{"name": "get_weather"}},
+			"",
+		},
+		{"function call with no details", map[string]interface{}{"function_call": nil}, agentStep{Kind: AgentStepFunctionCall, Event: map[string]interface{}{}}, ""},
+		{"audio", map[string]interface{}{"audio": 1.5}, agentStep{Kind: AgentStepAudio, Seconds: 1.5}, ""},
+		{"await something else", map[string]interface{}{"await": "text"}, agentStep{}, "unknown value text"},
+		{"event without a type", map[string]interface{}{"event": map[string]interface{}{"content": "hi"}}, agentStep{}, "event has no type"},
+		{"no audio length", map[string]interface{}{"audio": "long"}, agentStep{}, "expected a number of seconds"},
+		{"negative audio length", map[string]interface{}{"audio": -1.0}, agentStep{}, "expected a number of seconds"},
+		{"unknown step", map[string]interface{}{"sleep": 1.0}, agentStep{}, `unknown step "sleep"`},
+		{"two steps", map[string]interface{}{"await": "audio", "audio": 1.0}, agentStep{}, "expected one of"},
+		{"not a map", "await", agentStep{}, "expected one of"},
+	}
+	for _, test := range tests {
+		got, err := parseAgentStep(test.entry)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: error = %v, want one containing %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: parseAgentStep = %+v, %v, want %+v", test.name, got, err, test.want)
+		}
+	}
+}
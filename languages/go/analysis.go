@@ -0,0 +1,323 @@
+package main
+
+// Static analysis of Go samples
+// Samples are parsed into an AST so classification is driven by what the
+// code actually imports and calls rather than by substrings, which match
+// comments, variable names, and prose as easily as code.
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sdkModulePrefix identifies Deepgram Go SDK import paths across major versions
+const sdkModulePrefix = "github.com/deepgram/deepgram-go-sdk"
+
+// networkFunctions lists package-level functions that perform network I/O
+var networkFunctions = map[string][]string{
+	"net/http":                     {"Get", "Head", "Post", "PostForm"},
+	"net":                          {"Dial", "DialTimeout", "DialTCP", "DialUDP"},
+	"github.com/gorilla/websocket": {"Dial"},
+	"nhooyr.io/websocket":          {"Dial"},
+	"github.com/coder/websocket":   {"Dial"},
+}
+
+// networkMethods lists methods that perform network I/O on stdlib and
+// websocket clients, used when the owning package is imported
+var networkMethods = map[string][]string{
+	"net/http":                     {"Do"},
+	"github.com/gorilla/websocket": {"Dial", "DialContext"},
+}
+
+// audioInputMethods are SDK request methods that read local audio
+var audioInputMethods = map[string]bool{
+	"FromFile":   true,
+	"FromStream": true,
+}
+
+var audioFileRegex = regexp.MustCompile(`(?i)\.(wav|mp3|m4a|flac|ogg|opus|webm|aac|mulaw|raw)$`)
+
+// sampleAnalysis is what static analysis learned about a sample
+type sampleAnalysis struct {
+	Parsed             bool
+	File               *ast.File
+	Imports            []string
+	SDKSymbols         []string
+	SDKFields          []string
+	MemberNames        []string
+	ClientConstructors []string
+	NetworkCalls       []string
+	MethodCalls        []string
+	URLs               []string
+	AudioFiles         []string
+	ReadsAPIKeyEnv     bool
+}
+
+// NetworkBound reports whether running the sample reaches a remote service
+func (a sampleAnalysis) NetworkBound() bool {
+	return len(a.ClientConstructors) > 0 || len(a.NetworkCalls) > 0
+}
+
+// RequiresAPIKey reports whether the sample needs real Deepgram credentials
+func (a sampleAnalysis) RequiresAPIKey() bool {
+	if a.ReadsAPIKeyEnv || len(a.ClientConstructors) > 0 {
+		return true
+	}
+	if len(a.NetworkCalls) == 0 {
+		return false
+	}
+	for _, url := range a.URLs {
+		if strings.Contains(url, "deepgram.com") {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresAudioFile reports whether the sample reads local audio
+func (a sampleAnalysis) RequiresAudioFile() bool {
+	if len(a.AudioFiles) > 0 {
+		return true
+	}
+	for _, method := range a.MethodCalls {
+		if audioInputMethods[method] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSample parses a sample as a Go file. Snippets without a package
+// clause get one, and snippets made of bare statements are retried inside a
+// main function with any leading imports kept at file scope.
+func parseSample(code string) (*token.FileSet, *ast.File, error) {
+	fset := token.NewFileSet()
+
+	src := code
+	if !strings.HasPrefix(strings.TrimSpace(code), "package") {
+		src = "package main\n\n" + code
+	}
+
+	file, err := parser.ParseFile(fset, "main.go", src, parser.ParseComments)
+	if err == nil {
+		return fset, file, nil
+	}
+
+	imports, body := splitLeadingImports(code)
+	wrapped := "package main\n\n" + imports + "\nfunc main() {\n" + body + "\n}\n"
+
+	fset = token.NewFileSet()
+	file, wrappedErr := parser.ParseFile(fset, "main.go", wrapped, parser.ParseComments)
+	if wrappedErr != nil {
+		return nil, nil, err
+	}
+	return fset, file, nil
+}
+
+// splitLeadingImports separates the import declarations at the top of a
+// fragment from the statements that follow them
+func splitLeadingImports(code string) (imports, body string) {
+	lines := strings.Split(code, "\n")
+	inBlock := false
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inBlock:
+			if strings.HasPrefix(trimmed, ")") {
+				inBlock = false
+			}
+		case trimmed == "" || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "package"):
+		case strings.HasPrefix(trimmed, "import ("):
+			inBlock = true
+		case strings.HasPrefix(trimmed, "import "):
+		default:
+			return strings.Join(lines[:i], "\n"), strings.Join(lines[i:], "\n")
+		}
+	}
+
+	return code, ""
+}
+
+// analyzeSample parses a sample and records its imports, SDK usage, and I/O
+func analyzeSample(code string) sampleAnalysis {
+	var analysis sampleAnalysis
+
+	_, file, err := parseSample(code)
+	if err != nil {
+		return analysis
+	}
+	analysis.Parsed = true
+	analysis.File = file
+
+	// Map the local name of every import to its path
+	aliases := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		analysis.Imports = append(analysis.Imports, importPath)
+
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else if isMajorVersionSuffix(name) {
+			name = path.Base(path.Dir(importPath))
+		}
+		aliases[name] = importPath
+	}
+
+	imported := func(importPath string) bool {
+		for _, p := range aliases {
+			if p == importPath {
+				return true
+			}
+		}
+		return false
+	}
+
+	sdkSymbols := make(map[string]bool)
+	sdkFields := make(map[string]bool)
+	memberNames := make(map[string]bool)
+	constructors := make(map[string]bool)
+	networkCalls := make(map[string]bool)
+	methodCalls := make(map[string]bool)
+	urls := make(map[string]bool)
+	audioFiles := make(map[string]bool)
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			ident, ok := node.X.(*ast.Ident)
+			if !ok {
+				memberNames[node.Sel.Name] = true
+				return true
+			}
+			importPath, isPackage := aliases[ident.Name]
+			if !isPackage || ident.Obj != nil {
+				memberNames[node.Sel.Name] = true
+			} else if strings.HasPrefix(importPath, sdkModulePrefix) {
+				sdkSymbols[importPath+"."+node.Sel.Name] = true
+			}
+
+		case *ast.CompositeLit:
+			// Fields set in SDK struct literals, e.g. interfaces.PreRecordedTranscriptionOptions{Model: ...}
+			selector, ok := node.Type.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			ident, ok := selector.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			importPath, isPackage := aliases[ident.Name]
+			if !isPackage || !strings.HasPrefix(importPath, sdkModulePrefix) {
+				return true
+			}
+			for _, element := range node.Elts {
+				if pair, ok := element.(*ast.KeyValueExpr); ok {
+					if key, ok := pair.Key.(*ast.Ident); ok {
+						sdkFields[importPath+"."+selector.Sel.Name+"."+key.Name] = true
+					}
+				}
+			}
+
+		case *ast.CallExpr:
+			selector, ok := node.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			name := selector.Sel.Name
+
+			if ident, ok := selector.X.(*ast.Ident); ok && ident.Obj == nil {
+				if importPath, isPackage := aliases[ident.Name]; isPackage {
+					qualified := ident.Name + "." + name
+					if strings.HasPrefix(importPath, sdkModulePrefix) && strings.Contains(importPath, "/pkg/client") && strings.HasPrefix(name, "New") {
+						constructors[qualified] = true
+					}
+					if containsString(networkFunctions[importPath], name) {
+						networkCalls[qualified] = true
+					}
+					if importPath == "os" && (name == "Getenv" || name == "LookupEnv") && len(node.Args) > 0 {
+						if value, ok := stringLiteral(node.Args[0]); ok && strings.Contains(value, "DEEPGRAM") {
+							analysis.ReadsAPIKeyEnv = true
+						}
+					}
+					return true
+				}
+			}
+
+			methodCalls[name] = true
+			for importPath, methods := range networkMethods {
+				if imported(importPath) && containsString(methods, name) {
+					networkCalls["."+name] = true
+				}
+			}
+
+		case *ast.BasicLit:
+			value, ok := stringLiteral(node)
+			if !ok {
+				return true
+			}
+			if strings.Contains(value, "://") {
+				urls[value] = true
+			}
+			if audioFileRegex.MatchString(value) && !strings.Contains(value, "://") {
+				audioFiles[value] = true
+			}
+		}
+		return true
+	})
+
+	analysis.SDKSymbols = sortedKeys(sdkSymbols)
+	analysis.SDKFields = sortedKeys(sdkFields)
+	analysis.MemberNames = sortedKeys(memberNames)
+	analysis.ClientConstructors = sortedKeys(constructors)
+	analysis.NetworkCalls = sortedKeys(networkCalls)
+	analysis.MethodCalls = sortedKeys(methodCalls)
+	analysis.URLs = sortedKeys(urls)
+	analysis.AudioFiles = sortedKeys(audioFiles)
+
+	return analysis
+}
+
+// isMajorVersionSuffix reports whether an import path element is a module
+// major version such as "v3", which Go drops when naming the package
+func isMajorVersionSuffix(element string) bool {
+	if len(element) < 2 || element[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(element[1:])
+	return err == nil
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	return value, err == nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,279 @@
+package main
+
+// Documented API surface
+// Coverage from runs only sees samples that execute. This cross-references
+// the SDK's exported API with what samples reference in their source, so
+// public functions, methods, and options that no page ever shows stand out.
+// Without type information a method or field used in a sample is matched by
+// name, which errs towards counting it as documented.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kinds of exported SDK symbols
+const (
+	SymbolFunction = "function"
+	SymbolType     = "type"
+	SymbolMethod   = "method"
+	SymbolOption   = "option"
+)
+
+// APISymbol is one exported symbol of the SDK
+type APISymbol struct {
+	Package string `json:"package"`
+	// Name is Func, Type, Type.Method, or Options.Field
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Position   string `json:"position"`
+	Documented bool   `json:"documented"`
+}
+
+// APISurfaceReport lists the SDK's exported symbols and whether any sample uses them
+type APISurfaceReport struct {
+	Module     string      `json:"module"`
+	Total      int         `json:"total"`
+	Documented int         `json:"documented"`
+	Symbols    []APISymbol `json:"symbols"`
+}
+
+// sampleUsage is what the samples reference, merged across all of them
+type sampleUsage struct {
+	// qualified holds importPath.Name for package-level symbols and
+	// importPath.Type.Field for fields set in SDK composite literals
+	qualified map[string]bool
+	// members holds method and field names used on values of unknown type
+	members map[string]bool
+}
+
+// sdkSurfaceSkipDirs are checkout directories that aren't part of the public API
+var sdkSurfaceSkipDirs = map[string]bool{
+	"internal": true,
+	"testdata": true,
+	"examples": true,
+	"tests":    true,
+	"vendor":   true,
+}
+
+// BuildAPISurfaceReport compares the local SDK checkout's exported API with
+// the symbols referenced by samples
+func (e *GoExecutor) BuildAPISurfaceReport(samples []CodeSample) (APISurfaceReport, error) {
+	modulePath, sdkDir, ok := e.localSDKModule()
+	if !ok {
+		return APISurfaceReport{}, fmt.Errorf("the API surface report needs a local SDK checkout with a go.mod at %s", e.SDKPath)
+	}
+
+	symbols, err := sdkSurface(modulePath, sdkDir)
+	if err != nil {
+		return APISurfaceReport{}, err
+	}
+
+	usage := collectSampleUsage(samples)
+	usedTypes := make(map[string]bool)
+	for i, symbol := range symbols {
+		symbols[i].Documented = usage.uses(symbol)
+		if dot := strings.Index(symbol.Name, "."); dot >= 0 && symbols[i].Documented {
+			usedTypes[symbol.Package+"."+symbol.Name[:dot]] = true
+		}
+	}
+
+	// A type is shown whenever one of its methods or options is, even if
+	// samples only get values of it from a constructor
+	report := APISurfaceReport{Module: modulePath, Total: len(symbols)}
+	for i, symbol := range symbols {
+		if symbol.Kind == SymbolType && usedTypes[symbol.Package+"."+symbol.Name] {
+			symbols[i].Documented = true
+		}
+		if symbols[i].Documented {
+			report.Documented++
+		}
+	}
+	report.Symbols = symbols
+	return report, nil
+}
+
+// sdkSurface parses every package of the SDK checkout and returns its
+// exported functions, types, methods, and option fields
+func sdkSurface(modulePath, sdkDir string) ([]APISymbol, error) {
+	var symbols []APISymbol
+	fset := token.NewFileSet()
+
+	err := filepath.WalkDir(sdkDir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if file != sdkDir && (sdkSurfaceSkipDirs[name] || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+			return nil
+		}
+
+		parsed, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil || parsed.Name.Name == "main" {
+			return nil // Not part of the importable API
+		}
+
+		rel, err := filepath.Rel(sdkDir, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		pkg := modulePath
+		if rel != "." {
+			pkg = path.Join(modulePath, filepath.ToSlash(rel))
+		}
+
+		position := func(pos token.Pos) string {
+			p := fset.Position(pos)
+			relFile, _ := filepath.Rel(sdkDir, p.Filename)
+			return fmt.Sprintf("%s:%d", filepath.ToSlash(relFile), p.Line)
+		}
+		add := func(name, kind string, pos token.Pos) {
+			symbols = append(symbols, APISymbol{Package: pkg, Name: name, Kind: kind, Position: position(pos)})
+		}
+
+		for _, decl := range parsed.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+				if decl.Recv == nil {
+					add(decl.Name.Name, SymbolFunction, decl.Pos())
+				} else if receiver := receiverType(decl.Recv); ast.IsExported(receiver) {
+					add(receiver+"."+decl.Name.Name, SymbolMethod, decl.Pos())
+				}
+
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					if !typeSpec.Name.IsExported() {
+						continue
+					}
+					add(typeSpec.Name.Name, SymbolType, typeSpec.Pos())
+
+					structType, ok := typeSpec.Type.(*ast.StructType)
+					if !ok || !strings.HasSuffix(typeSpec.Name.Name, "Options") {
+						continue
+					}
+					for _, field := range structType.Fields.List {
+						for _, name := range field.Names {
+							if name.IsExported() {
+								add(typeSpec.Name.Name+"."+name.Name, SymbolOption, name.Pos())
+							}
+						}
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Package != symbols[j].Package {
+			return symbols[i].Package < symbols[j].Package
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+	return symbols, nil
+}
+
+// receiverType returns the type name of a method receiver
+func receiverType(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// collectSampleUsage gathers the SDK symbols and member names samples reference
+func collectSampleUsage(samples []CodeSample) sampleUsage {
+	usage := sampleUsage{qualified: make(map[string]bool), members: make(map[string]bool)}
+
+	for _, sample := range samples {
+		analysis := analyzeSample(sample.Code)
+		for _, symbol := range analysis.SDKSymbols {
+			usage.qualified[symbol] = true
+		}
+		for _, field := range analysis.SDKFields {
+			usage.qualified[field] = true
+		}
+		for _, member := range analysis.MemberNames {
+			usage.members[member] = true
+		}
+	}
+	return usage
+}
+
+// uses reports whether the samples reference an SDK symbol
+func (u sampleUsage) uses(symbol APISymbol) bool {
+	switch symbol.Kind {
+	case SymbolFunction, SymbolType:
+		return u.qualified[symbol.Package+"."+symbol.Name]
+	case SymbolOption:
+		if u.qualified[symbol.Package+"."+symbol.Name] {
+			return true
+		}
+		fallthrough
+	default:
+		// Type.Member: match the member by name
+		return u.members[symbol.Name[strings.LastIndex(symbol.Name, ".")+1:]]
+	}
+}
+
+// undocumented returns the symbols no sample references
+func (r APISurfaceReport) undocumented() []APISymbol {
+	var symbols []APISymbol
+	for _, symbol := range r.Symbols {
+		if !symbol.Documented {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// writeAPISurfacePlain lists undocumented symbols grouped by package
+func writeAPISurfacePlain(report APISurfaceReport) {
+	fmt.Printf("📚 %d of %d exported %s symbols appear in docs samples\n", report.Documented, report.Total, report.Module)
+
+	pkg := ""
+	for _, symbol := range report.undocumented() {
+		if symbol.Package != pkg {
+			pkg = symbol.Package
+			fmt.Printf("\n[%s]\n", pkg)
+		}
+		fmt.Printf("   %-8s %-50s %s\n", symbol.Kind, symbol.Name, symbol.Position)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+// Response assertions
+// A sample that exits cleanly can still print an empty transcript. Writers
+// can name built-in checks of the response a sample prints on its fence,
+// without writing JSON paths:
+//
+//	```go assert=transcript,confidence,request_id,words
+//
+// The checks read the JSON the sample prints, all of its output or the
+// last line that is JSON, and look for fields wherever the response nests
+// them, so they work for pre-recorded, live, and read responses alike.
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// metadataAssert is the metadata key of a sample's assertions
+const metadataAssert = "assert"
+
+// fenceAssertRegex matches a fence's assert attribute
+var fenceAssertRegex = regexp.MustCompile(`\bassert=["']?([\w,-]+)`)
+
+// Built-in assertions
+const (
+	AssertTranscript = "transcript"
+	AssertConfidence = "confidence"
+	AssertRequestID  = "request_id"
+	AssertWords      = "words"
+)
+
+// responseAssertions check a parsed response, returning what's wrong
+var responseAssertions = map[string]func(response interface{}) string{
+	AssertTranscript: assertTranscript,
+	AssertConfidence: assertConfidence,
+	AssertRequestID:  assertRequestID,
+	AssertWords:      assertWords,
+}
+
+// sampleAssertions records the assertions a sample's fence names
+func sampleAssertions(sample *CodeSample, fenceMeta string) {
+	if match := fenceAssertRegex.FindStringSubmatch(fenceMeta); match != nil {
+		sample.Metadata[metadataAssert] = strings.Trim(match[1], ",")
+	}
+}
+
+// checkAssertions runs a passed sample's assertions on its output, failing
+// the result in the output category when one doesn't hold
+func checkAssertions(result *TestResult) {
+	names := result.Sample.Metadata[metadataAssert]
+	if names == "" {
+		return
+	}
+
+	var problems []string
+	response, err := outputJSON(result.Stdout)
+	for _, name := range strings.Split(names, ",") {
+		check, ok := responseAssertions[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("unknown assertion %q (expected %s, %s, %s, or %s)", name, AssertTranscript, AssertConfidence, AssertRequestID, AssertWords))
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v; print the response as JSON", name, err))
+		default:
+			if problem := check(response); problem != "" {
+				problems = append(problems, name+": "+problem)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		result.Success = false
+		result.Status = StatusFailed
+		result.ErrorCategory = ErrorCategoryOutput
+		result.ErrorMessage = "assertion failed: " + strings.Join(problems, "; ")
+	}
+}
+
+// outputJSON parses the JSON a sample printed: all of its output if it is
+// one document, else the last line that is
+func outputJSON(stdout string) (interface{}, error) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(stdout), &document); err == nil {
+		return document, nil
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if err := json.Unmarshal([]byte(lines[i]), &document); err == nil {
+			return document, nil
+		}
+	}
+	return nil, fmt.Errorf("the output isn't JSON")
+}
+
+// responseFields returns every value of a key anywhere in a response
+func responseFields(value interface{}, key string) []interface{} {
+	var found []interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if name == key {
+				found = append(found, field)
+			}
+			found = append(found, responseFields(field, key)...)
+		}
+	case []interface{}:
+		for _, element := range v {
+			found = append(found, responseFields(element, key)...)
+		}
+	}
+	return found
+}
+
+// responseObjects returns every object in a response holding all the keys
+func responseObjects(value interface{}, keys ...string) []map[string]interface{} {
+	var found []map[string]interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		matches := true
+		for _, key := range keys {
+			if _, ok := v[key]; !ok {
+				matches = false
+			}
+		}
+		if matches {
+			found = append(found, v)
+		}
+		for _, field := range v {
+			found = append(found, responseObjects(field, keys...)...)
+		}
+	case []interface{}:
+		for _, element := range v {
+			found = append(found, responseObjects(element, keys...)...)
+		}
+	}
+	return found
+}
+
+// assertTranscript requires a non-empty transcript
+func assertTranscript(response interface{}) string {
+	transcripts := responseFields(response, "transcript")
+	if len(transcripts) == 0 {
+		return "no transcript in the response"
+	}
+	for _, transcript := range transcripts {
+		if text, ok := transcript.(string); ok && strings.TrimSpace(text) != "" {
+			return ""
+		}
+	}
+	return "every transcript is empty"
+}
+
+// assertConfidence requires confidences, all within [0, 1]
+func assertConfidence(response interface{}) string {
+	confidences := responseFields(response, "confidence")
+	if len(confidences) == 0 {
+		return "no confidence in the response"
+	}
+	for _, confidence := range confidences {
+		value, ok := confidence.(float64)
+		if !ok {
+			return fmt.Sprintf("confidence %v isn't a number", confidence)
+		}
+		if value < 0 || value > 1 {
+			return fmt.Sprintf("confidence %v is outside [0, 1]", value)
+		}
+	}
+	return ""
+}
+
+// assertRequestID requires a non-empty request_id
+func assertRequestID(response interface{}) string {
+	for _, id := range responseFields(response, "request_id") {
+		if text, ok := id.(string); ok && text != "" {
+			return ""
+		}
+	}
+	return "no request_id in the response"
+}
+
+// assertWords requires each alternative's words to spell out its
+// transcript, ignoring case and punctuation
+func assertWords(response interface{}) string {
+	alternatives := responseObjects(response, "transcript", "words")
+	if len(alternatives) == 0 {
+		return "no transcript with words in the response"
+	}
+	for _, alternative := range alternatives {
+		transcript, _ := alternative["transcript"].(string)
+		words, _ := alternative["words"].([]interface{})
+
+		expected := transcriptTokens(transcript)
+		var actual []string
+		for _, entry := range words {
+			word, _ := entry.(map[string]interface{})
+			// Smart formatting shows the punctuated form in the transcript
+			text, ok := word["punctuated_word"].(string)
+			if !ok {
+				text, _ = word["word"].(string)
+			}
+			actual = append(actual, transcriptTokens(text)...)
+		}
+
+		for i := 0; i < len(expected) || i < len(actual); i++ {
+			switch {
+			case i >= len(actual):
+				return fmt.Sprintf("the words end before the transcript's %q", expected[i])
+			case i >= len(expected):
+				return fmt.Sprintf("word %q isn't in the transcript", actual[i])
+			case actual[i] != expected[i]:
+				return fmt.Sprintf("word %d is %q, but the transcript has %q", i+1, actual[i], expected[i])
+			}
+		}
+	}
+	return ""
+}
+
+// transcriptTokens splits text into lowercase words without punctuation
+func transcriptTokens(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	})
+}
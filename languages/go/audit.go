@@ -0,0 +1,125 @@
+package main
+
+// Execution audit log
+// When the executor misbehaves on a CI host, or a security review asks what
+// docs code actually ran there, the report isn't enough: it shows samples,
+// not the processes behind them. `run --audit-log audit.jsonl` writes one
+// JSON line for every command the executor spawns, whether git, the go
+// toolchain, goimports, or a sample's binary, as it finishes: its arguments,
+// working directory, the environment it was given on top of the executor's
+// own, how long it took, and how it exited.
+//
+// Values of variables whose names look secret are redacted, since the log is
+// meant to be shared. The environment inherited from the host isn't logged.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one spawned command
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Sample is the ID of the sample the command ran for, when it ran for one
+	Sample  string   `json:"sample,omitempty"`
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Dir     string   `json:"dir,omitempty"`
+	// Env is the variables set on top of the executor's environment
+	Env      []string `json:"env,omitempty"`
+	Duration float64  `json:"duration_seconds"`
+	// ExitCode is -1 when the command didn't start or was killed by a signal
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// AuditLog appends entries to a JSON-lines file. A nil log records nothing.
+type AuditLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	err     error
+}
+
+// openAuditLog creates or truncates the audit log at path
+func openAuditLog(path string) (*AuditLog, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	return &AuditLog{file: file, encoder: encoder}, nil
+}
+
+// record logs a command that has finished, with the error it returned
+func (a *AuditLog) record(cmd *exec.Cmd, sample string, started time.Time, err error) {
+	if a == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:     started.UTC(),
+		Sample:   sample,
+		Command:  cmd.Path,
+		Args:     cmd.Args[1:],
+		Dir:      cmd.Dir,
+		Env:      auditEnv(cmd.Env),
+		Duration: time.Since(started).Seconds(),
+		ExitCode: -1,
+	}
+	if cmd.ProcessState != nil {
+		entry.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.encoder.Encode(entry); err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// Close closes the log, returning the first error writing it
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.file.Close(); a.err == nil {
+		a.err = err
+	}
+	return a.err
+}
+
+// closeAuditLog closes a log as a command returns, making a failure to
+// write it the command's error unless the command already failed
+func closeAuditLog(log *AuditLog, err *error) {
+	if closeErr := log.Close(); closeErr != nil && *err == nil {
+		*err = fmt.Errorf("writing the audit log: %v", closeErr)
+	}
+}
+
+// auditEnv returns the variables in env that differ from the executor's
+// environment, with secret values masked as in run plans
+func auditEnv(env []string) []string {
+	inherited := make(map[string]bool)
+	for _, variable := range os.Environ() {
+		inherited[variable] = true
+	}
+
+	var added []string
+	for _, variable := range env {
+		if !inherited[variable] {
+			added = append(added, variable)
+		}
+	}
+	return maskSecrets(added)
+}
@@ -0,0 +1,83 @@
+package main
+
+// API base URL overrides
+// Pointing samples at a mock server or a staging environment shouldn't mean
+// editing docs. When a base URL is configured, it is injected three ways,
+// since samples reach the API differently: through the SDK's host
+// environment variables, through a Host field in the client options, and
+// through API URL literals in the code.
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// defaultAPIHosts are the hosts whose URL literals are rewritten
+var defaultAPIHosts = []string{"api.deepgram.com", "agent.deepgram.com"}
+
+// defaultHostEnv are the environment variables the SDK reads its host from
+var defaultHostEnv = []string{"DEEPGRAM_HOST"}
+
+// hostFieldRegex matches a string-valued Host field in an options literal
+var hostFieldRegex = regexp.MustCompile(`(\bHost:\s*)"[^"]*"`)
+
+// apiConfig returns the framework's api section
+func (e *GoExecutor) apiConfig() map[string]interface{} {
+	return configSection(e.FrameworkConfig, "api")
+}
+
+// baseURLEnv returns the host environment variables pointing at BaseURL
+func (e *GoExecutor) baseURLEnv() []string {
+	if e.BaseURL == "" {
+		return nil
+	}
+
+	names := configStrings(e.apiConfig(), "host_env")
+	if len(names) == 0 {
+		names = defaultHostEnv
+	}
+
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		env = append(env, name+"="+e.BaseURL)
+	}
+	return env
+}
+
+// rewriteBaseURL points a sample's Host option and API URL literals at BaseURL
+func (e *GoExecutor) rewriteBaseURL(code string) string {
+	if e.BaseURL == "" {
+		return code
+	}
+	base, err := url.Parse(e.BaseURL)
+	if err != nil || base.Host == "" {
+		return code
+	}
+
+	code = hostFieldRegex.ReplaceAllString(code, `${1}"`+e.BaseURL+`"`)
+
+	hosts := configStrings(e.apiConfig(), "hosts")
+	if len(hosts) == 0 {
+		hosts = defaultAPIHosts
+	}
+
+	// WebSocket URLs keep their scheme family: an http base becomes ws://
+	socketScheme := "wss"
+	if base.Scheme == "http" || base.Scheme == "ws" {
+		socketScheme = "ws"
+	}
+	httpScheme := "https"
+	if base.Scheme == "http" || base.Scheme == "ws" {
+		httpScheme = "http"
+	}
+	prefix := strings.TrimSuffix(base.Host+base.Path, "/")
+
+	for _, host := range hosts {
+		code = strings.ReplaceAll(code, "https://"+host, httpScheme+"://"+prefix)
+		code = strings.ReplaceAll(code, "http://"+host, httpScheme+"://"+prefix)
+		code = strings.ReplaceAll(code, "wss://"+host, socketScheme+"://"+prefix)
+		code = strings.ReplaceAll(code, "ws://"+host, socketScheme+"://"+prefix)
+	}
+	return code
+}
@@ -0,0 +1,157 @@
+package main
+
+// Extraction index cache
+// Parsing and classifying thousands of pages dominates startup on a warm
+// machine, yet most pages don't change between runs. The index stores each
+// page's extracted samples keyed by a hash of its content, so unchanged
+// pages skip extraction. The hash also covers the language config and the
+// dgtest binary itself, since either can change what extraction produces.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// extractionIndex maps page paths to their cached extraction results
+type extractionIndex struct {
+	Pages map[string]cachedPage `json:"pages"`
+
+	path        string
+	fingerprint string
+	used        map[string]bool
+	dirty       bool
+}
+
+// cachedPage is one page's samples and the hash they were extracted under
+type cachedPage struct {
+	Hash    string       `json:"hash"`
+	Samples []CodeSample `json:"samples"`
+}
+
+// extractionCachePath returns where the index for a docs root lives, or ""
+// when caching is disabled via documentation.cache.enabled
+func (e *GoExecutor) extractionCachePath(documentationPath string) string {
+	cache := configSection(configSection(e.FrameworkConfig, "documentation"), "cache")
+	if !configBool(cache, "enabled", true) {
+		return ""
+	}
+	if path := configString(cache, "path", ""); path != "" {
+		return path
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	root, err := filepath.Abs(documentationPath)
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "dgtest", "extract", hashString(root)[:16]+".json")
+}
+
+// loadExtractionIndex reads the index for a docs root. A missing or
+// unreadable index starts empty, since it only ever saves work.
+func (e *GoExecutor) loadExtractionIndex(documentationPath string) *extractionIndex {
+	index := &extractionIndex{
+		Pages:       make(map[string]cachedPage),
+		path:        e.extractionCachePath(documentationPath),
+		fingerprint: e.extractionFingerprint(),
+		used:        make(map[string]bool),
+	}
+	if index.path == "" {
+		return index
+	}
+
+	content, err := os.ReadFile(index.path)
+	if err == nil && json.Unmarshal(content, index) == nil && index.Pages != nil {
+		return index
+	}
+	index.Pages = make(map[string]cachedPage)
+	return index
+}
+
+// extractionFingerprint covers everything besides page content that
+// affects extraction
+func (e *GoExecutor) extractionFingerprint() string {
+	hash := sha256.New()
+	config, _ := json.Marshal(e.LanguageConfig)
+	hash.Write(config)
+	if executable, err := os.Executable(); err == nil {
+		if info, err := os.Stat(executable); err == nil {
+			hash.Write([]byte(info.ModTime().String()))
+			hash.Write([]byte(executable))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// samples returns the cached samples for a page if its content is unchanged
+func (index *extractionIndex) samples(path string, content []byte) ([]CodeSample, string, bool) {
+	key := index.pageHash(content)
+	index.used[path] = true
+
+	page, ok := index.Pages[path]
+	if !ok || page.Hash != key {
+		return nil, key, false
+	}
+	return page.Samples, key, true
+}
+
+// store records a page's freshly extracted samples
+func (index *extractionIndex) store(path, key string, samples []CodeSample) {
+	index.Pages[path] = cachedPage{Hash: key, Samples: samples}
+	index.dirty = true
+}
+
+func (index *extractionIndex) pageHash(content []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(index.fingerprint))
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// save writes the index back, dropping pages this walk no longer visits
+func (index *extractionIndex) save() error {
+	for path := range index.Pages {
+		if !index.used[path] {
+			delete(index.Pages, path)
+			index.dirty = true
+		}
+	}
+	if index.path == "" || !index.dirty {
+		return nil
+	}
+
+	content, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(index.path), 0755); err != nil {
+		return err
+	}
+
+	// Write then rename so concurrent runs never read a partial index
+	temp, err := os.CreateTemp(filepath.Dir(index.path), ".extract-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return os.Rename(temp.Name(), index.path)
+}
+
+func hashString(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
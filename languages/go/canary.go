@@ -0,0 +1,434 @@
+package main
+
+// Mock drift canary
+// The mock is only worth trusting while it answers like production. A
+// scheduled `dgtest canary` runs a small curated set of samples, canary.samples
+// in the framework config (by ID or page:line), against both the live API and
+// the mock, and compares what each run printed: the shape of the JSON
+// responses (every field path and its type, ignoring values) and whether the
+// sample passed. Fields production returns that the mock lacks, fields only
+// the mock returns, changed types, and samples that pass on one side only are
+// drift. The command fails when anything drifted, and --file-issue opens or
+// updates a GitHub issue about it (issues.repo, with canary.labels or
+// issues.labels).
+//
+//	canary:
+//	  samples: ["stt-prerecorded-1a2b3c4d", "fern/pages/docs/tts.mdx:42"]
+//	  ignore_paths: ["metadata.extra.**"]
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of canary drift
+const (
+	DriftMissing = "missing_from_mock"
+	DriftExtra   = "only_in_mock"
+	DriftType    = "type"
+	DriftStatus  = "status"
+)
+
+// canaryIssueMarker identifies the issue the canary files
+const canaryIssueMarker = "<!-- dgtest-canary -->"
+
+// uuidKeyRegex matches object keys that are identifiers rather than field
+// names, such as the model UUIDs keying metadata.model_info
+var uuidKeyRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ShapeDrift is one difference between the live and mock runs of a sample
+type ShapeDrift struct {
+	Kind string `json:"kind"`
+	// Path is the field's path, e.g. results.channels[].alternatives[].words,
+	// empty for status drift
+	Path string `json:"path,omitempty"`
+	Live string `json:"live"`
+	Mock string `json:"mock"`
+}
+
+// CanaryResult compares one sample's live and mock runs
+type CanaryResult struct {
+	Sample     CodeSample `json:"sample"`
+	LiveStatus string     `json:"live_status"`
+	MockStatus string     `json:"mock_status"`
+	// Compared is whether both runs printed JSON whose shapes were compared
+	Compared bool         `json:"compared"`
+	Drift    []ShapeDrift `json:"drift"`
+}
+
+// CanaryReport is the outcome of a canary run
+type CanaryReport struct {
+	Samples []CanaryResult `json:"samples"`
+	Drifted int            `json:"drifted"`
+}
+
+// responseShape is every path in a JSON document with the types found there
+type responseShape struct {
+	// types maps a path to its non-null types, sorted and joined with "|",
+	// or "" when it was only ever null
+	types map[string]string
+	// emptyArrays are the array paths that never held an element
+	emptyArrays map[string]bool
+}
+
+// shapeOf walks a JSON document into its shape
+func shapeOf(document interface{}) responseShape {
+	found := make(map[string]map[string]bool)
+	elements := make(map[string]int)
+	var walk func(path string, value interface{})
+	walk = func(path string, value interface{}) {
+		if found[path] == nil {
+			found[path] = make(map[string]bool)
+		}
+		switch v := value.(type) {
+		case nil:
+		case map[string]interface{}:
+			found[path]["object"] = true
+			for key, field := range v {
+				if uuidKeyRegex.MatchString(key) {
+					key = "*"
+				}
+				if path != "" {
+					key = path + "." + key
+				}
+				walk(key, field)
+			}
+		case []interface{}:
+			found[path]["array"] = true
+			elements[path] += len(v)
+			for _, element := range v {
+				walk(path+"[]", element)
+			}
+		case string:
+			found[path]["string"] = true
+		case float64:
+			found[path]["number"] = true
+		case bool:
+			found[path]["boolean"] = true
+		}
+	}
+	walk("", document)
+
+	shape := responseShape{types: make(map[string]string), emptyArrays: make(map[string]bool)}
+	for path, types := range found {
+		shape.types[path] = strings.Join(sortedKeys(types), "|")
+	}
+	for path, count := range elements {
+		if count == 0 {
+			shape.emptyArrays[path] = true
+		}
+	}
+	return shape
+}
+
+// unknown reports whether a shape can't say what's at path because an
+// ancestor was only ever null or an empty array
+func (s responseShape) unknown(path string) bool {
+	for path != "" {
+		if strings.HasSuffix(path, "[]") {
+			path = strings.TrimSuffix(path, "[]")
+			if s.emptyArrays[path] {
+				return true
+			}
+		} else if i := strings.LastIndexAny(path, ".]"); i >= 0 {
+			path = path[:i+1]
+			path = strings.TrimSuffix(path, ".")
+		} else {
+			path = ""
+		}
+		if types, ok := s.types[path]; ok && types == "" && path != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// shapeDrift lists how the mock's shape differs from the live one, leaving
+// out paths matching an ignore pattern
+func shapeDrift(live, mock responseShape, ignore []string) []ShapeDrift {
+	paths := make(map[string]bool)
+	for path := range live.types {
+		paths[path] = true
+	}
+	for path := range mock.types {
+		paths[path] = true
+	}
+
+	var drift []ShapeDrift
+	for _, path := range sortedKeys(paths) {
+		if path == "" || ignoredPath(path, ignore) {
+			continue
+		}
+		liveType, inLive := live.types[path]
+		mockType, inMock := mock.types[path]
+		switch {
+		case !inMock && !mock.unknown(path):
+			drift = append(drift, ShapeDrift{Kind: DriftMissing, Path: path, Live: liveType})
+		case !inLive && !live.unknown(path):
+			drift = append(drift, ShapeDrift{Kind: DriftExtra, Path: path, Mock: mockType})
+		case inLive && inMock && liveType != "" && mockType != "" && liveType != mockType:
+			drift = append(drift, ShapeDrift{Kind: DriftType, Path: path, Live: liveType, Mock: mockType})
+		}
+	}
+	return drift
+}
+
+// ignoredPath reports whether a path matches a canary.ignore_paths pattern,
+// whose elements are separated by dots and may be * or **
+func ignoredPath(path string, ignore []string) bool {
+	name := strings.ReplaceAll(path, ".", "/")
+	for _, pattern := range ignore {
+		if matchGlob(strings.ReplaceAll(pattern, ".", "/"), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareCanary compares a sample's live and mock results
+func compareCanary(live, mock TestResult, ignore []string) CanaryResult {
+	result := CanaryResult{Sample: live.Sample, LiveStatus: live.Status, MockStatus: mock.Status, Drift: []ShapeDrift{}}
+	if live.Status == StatusSkipped || mock.Status == StatusSkipped {
+		return result
+	}
+	if (live.Status == StatusPassed) != (mock.Status == StatusPassed) {
+		result.Drift = append(result.Drift, ShapeDrift{Kind: DriftStatus, Live: live.Status, Mock: mock.Status})
+	}
+
+	liveJSON, liveErr := outputJSON(live.Stdout)
+	mockJSON, mockErr := outputJSON(mock.Stdout)
+	if liveErr == nil && mockErr == nil {
+		result.Compared = true
+		result.Drift = append(result.Drift, shapeDrift(shapeOf(liveJSON), shapeOf(mockJSON), ignore)...)
+	}
+	return result
+}
+
+// canarySamples picks the samples named, or those in canary.samples
+func (e *GoExecutor) canarySamples(samples []CodeSample, names []string) ([]CodeSample, error) {
+	if len(names) == 0 {
+		names = configStrings(configSection(e.FrameworkConfig, "canary"), "samples")
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no canary samples are configured (canary.samples)")
+	}
+
+	var selected []CodeSample
+	for _, name := range names {
+		found := false
+		for _, sample := range samples {
+			if (Quarantine{Sample: name}).covers(sample) {
+				selected = append(selected, sample)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("canary sample %q isn't in the docs", name)
+		}
+	}
+	return selected, nil
+}
+
+// RunCanary runs samples live and on the mock and compares the runs
+func (e *GoExecutor) RunCanary(ctx context.Context, samples []CodeSample) (CanaryReport, error) {
+	e.Live = true
+	liveResults, _ := e.RunSamples(ctx, samples)
+	if err := ctx.Err(); err != nil {
+		return CanaryReport{}, err
+	}
+	// The mock half runs as an offline run would, with the test key
+	e.Live = false
+	mock, err := e.runOnMock(ctx, samples, nil, nil)
+	e.Live = true
+	if err != nil {
+		return CanaryReport{}, err
+	}
+
+	mockByID := make(map[string]TestResult)
+	for _, result := range mock {
+		mockByID[result.Sample.ID] = result
+	}
+	ignore := configStrings(configSection(e.FrameworkConfig, "canary"), "ignore_paths")
+	report := CanaryReport{Samples: []CanaryResult{}}
+	for _, result := range liveResults {
+		mockResult, ok := mockByID[result.Sample.ID]
+		if !ok {
+			return CanaryReport{}, fmt.Errorf("the mock run has no result for canary sample %s:%d", result.Sample.Page, result.Sample.LineNumber)
+		}
+		compared := compareCanary(result, mockResult, ignore)
+		if len(compared.Drift) > 0 {
+			report.Drifted++
+		}
+		report.Samples = append(report.Samples, compared)
+	}
+	return report, nil
+}
+
+// describe renders one drift for people
+func (d ShapeDrift) describe() string {
+	switch d.Kind {
+	case DriftStatus:
+		return fmt.Sprintf("%s live, %s on the mock", d.Live, d.Mock)
+	case DriftMissing:
+		return fmt.Sprintf("missing from the mock: %s (%s)", d.Path, shapeTypeName(d.Live))
+	case DriftExtra:
+		return fmt.Sprintf("only in the mock: %s (%s)", d.Path, shapeTypeName(d.Mock))
+	default:
+		return fmt.Sprintf("%s is %s live but %s on the mock", d.Path, d.Live, d.Mock)
+	}
+}
+
+// shapeTypeName names a path's types, which are empty for null-only paths
+func shapeTypeName(types string) string {
+	if types == "" {
+		return "null"
+	}
+	return types
+}
+
+// writeCanaryReport renders a canary run as plain text, JSON, or Markdown
+func writeCanaryReport(w io.Writer, format string, report CanaryReport) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatPlain:
+		for _, result := range report.Samples {
+			location := fmt.Sprintf("%s:%d", result.Sample.Page, result.Sample.LineNumber)
+			switch {
+			case len(result.Drift) > 0:
+				fmt.Fprintf(w, "❌ %s drifted (live %s, mock %s)\n", location, result.LiveStatus, result.MockStatus)
+				for _, drift := range result.Drift {
+					fmt.Fprintf(w, "   %s\n", drift.describe())
+				}
+			case !result.Compared:
+				fmt.Fprintf(w, "⚠️  %s not compared (live %s, mock %s, JSON output from both needed)\n", location, result.LiveStatus, result.MockStatus)
+			default:
+				fmt.Fprintf(w, "✅ %s matches\n", location)
+			}
+		}
+		fmt.Fprintf(w, "📊 %d of %d canary samples drifted between the live API and the mock\n", report.Drifted, len(report.Samples))
+		return nil
+	case FormatMarkdown:
+		fmt.Fprint(w, canaryMarkdown(report))
+		return nil
+	default:
+		return fmt.Errorf("unknown canary format: %s", format)
+	}
+}
+
+// canaryMarkdown summarizes a canary run in Markdown, for reports and issues
+func canaryMarkdown(report CanaryReport) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "## Mock drift canary\n\n%d of %d canary samples drifted between the live API and the mock.\n", report.Drifted, len(report.Samples))
+	if len(report.Samples) == 0 {
+		return out.String()
+	}
+	out.WriteString("\n| Sample | Live | Mock | Drift |\n|--------|------|------|-------|\n")
+	for _, result := range report.Samples {
+		drift := make([]string, 0, len(result.Drift))
+		for _, d := range result.Drift {
+			drift = append(drift, d.describe())
+		}
+		summary := strings.Join(drift, "<br>")
+		if summary == "" && !result.Compared {
+			summary = "not compared"
+		} else if summary == "" {
+			summary = "none"
+		}
+		fmt.Fprintf(&out, "| `%s:%d` | %s | %s | %s |\n", result.Sample.Page, result.Sample.LineNumber,
+			result.LiveStatus, result.MockStatus, strings.ReplaceAll(summary, "|", "\\|"))
+	}
+	return out.String()
+}
+
+// fileCanaryIssue opens an issue about the drift in a canary run, or
+// updates the one still open, returning its URL
+func (e *GoExecutor) fileCanaryIssue(ctx context.Context, report CanaryReport) (string, error) {
+	filer, _, err := e.newIssueFiler()
+	if err != nil {
+		return "", err
+	}
+	if labels := configStrings(configSection(e.FrameworkConfig, "canary"), "labels"); len(labels) > 0 {
+		filer.labels = labels
+	}
+	open, err := filer.listOpenIssues(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	body := canaryIssueMarker + "\n" + canaryMarkdown(report) +
+		"\nUpdate the mock in `languages/go/mock.go` to answer like production, or list intentional differences under `canary.ignore_paths`. " +
+		"This issue is updated by each canary run that finds drift; close it once the mock matches.\n"
+	var issue githubIssue
+	for _, existing := range open {
+		if strings.Contains(existing.Body, canaryIssueMarker) {
+			err = filer.client.do(ctx, http.MethodPatch, fmt.Sprintf("/issues/%d", existing.Number), map[string]interface{}{"body": body}, &issue)
+			return issue.HTMLURL, err
+		}
+	}
+	request := map[string]interface{}{"title": "The mock API has drifted from the live API", "body": body}
+	if len(filer.labels) > 0 {
+		request["labels"] = filer.labels
+	}
+	err = filer.client.do(ctx, http.MethodPost, "/issues", request, &issue)
+	return issue.HTMLURL, err
+}
+
+// canaryCommand runs the canary samples live and on the mock, failing when
+// the mock has drifted from production
+func canaryCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("canary", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain, json, or markdown")
+	fileIssue := flags.Bool("file-issue", false, "Open or update a GitHub issue in issues.repo when the mock has drifted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+	executor.Live = true
+	if err := executor.checkEnvironmentKey(); err != nil {
+		return err
+	}
+	if executor.Environment == nil && os.Getenv("DEEPGRAM_API_KEY") == "" {
+		return fmt.Errorf("the canary runs samples live, which needs DEEPGRAM_API_KEY")
+	}
+	selected, err := executor.canarySamples(samples, flags.Args())
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].ID < selected[j].ID })
+
+	report, err := executor.RunCanary(ctx, selected)
+	if err != nil {
+		return err
+	}
+	if err := writeCanaryReport(os.Stdout, *format, report); err != nil {
+		return err
+	}
+	if report.Drifted == 0 {
+		return nil
+	}
+	if *fileIssue {
+		url, err := executor.fileCanaryIssue(ctx, report)
+		if err != nil {
+			return fmt.Errorf("filing the canary issue: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "🐤 Mock drift reported in %s\n", url)
+	}
+	return fmt.Errorf("the mock has drifted from the live API for %d canary samples", report.Drifted)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestShapeOf(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(`{
+		"metadata": {"request_id": "r", "models": {"6f1e0c1a-9b2d-4c3e-8f4a-5b6c7d8e9f00": {"name": "nova"}}},
+		"results": {"channels": [{"alternatives": [{"confidence": 0.9, "words": []}]}]},
+		"warnings": null,
+		"mixed": ["a", 1, null]
+	}`), &document); err != nil {
+		t.Fatal(err)
+	}
+	shape := shapeOf(document)
+
+	want := map[string]string{
+		"":                                  "object",
+		"metadata":                          "object",
+		"metadata.request_id":               "string",
+		"metadata.models":                   "object",
+		"metadata.models.*":                 "object",
+		"metadata.models.*.name":            "string",
+		"results":                           "object",
+		"results.channels":                  "array",
+		"results.channels[]":                "object",
+		"results.channels[].alternatives":   "array",
+		"results.channels[].alternatives[]": "object",
+		"results.channels[].alternatives[].confidence": "number",
+		"results.channels[].alternatives[].words":      "array",
+		"warnings": "",
+		"mixed":    "array",
+		"mixed[]":  "number|string",
+	}
+	if !reflect.DeepEqual(shape.types, want) {
+		t.Errorf("types = %v, want %v", shape.types, want)
+	}
+	if !reflect.DeepEqual(shape.emptyArrays, map[string]bool{"results.channels[].alternatives[].words": true}) {
+		t.Errorf("emptyArrays = %v", shape.emptyArrays)
+	}
+}
+
+func TestShapeDrift(t *testing.T) {
+	shape := func(document string) responseShape {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(document), &parsed); err != nil {
+			t.Fatal(err)
+		}
+		return shapeOf(parsed)
+	}
+	tests := []struct {
+		name       string
+		live, mock string
+		ignore     []string
+		want       []ShapeDrift
+	}{
+		{
+			name: "same shape",
+			live: `{"id": "a", "n": 1}`,
+			mock: `{"id": "b", "n": 2}`,
+		},
+		{
+			name: "missing and extra",
+			live: `{"id": "a", "live_only": true}`,
+			mock: `{"id": "b", "mock_only": 1}`,
+			want: []ShapeDrift{
+				{Kind: DriftMissing, Path: "live_only", Live: "boolean"},
+				{Kind: DriftExtra, Path: "mock_only", Mock: "number"},
+			},
+		},
+		{
+			name: "type change",
+			live: `{"duration": 1.5}`,
+			mock: `{"duration": "1.5"}`,
+			want: []ShapeDrift{{Kind: DriftType, Path: "duration", Live: "number", Mock: "string"}},
+		},
+		{
+			name: "null matches any type",
+			live: `{"summary": {"text": "hi"}}`,
+			mock: `{"summary": null}`,
+		},
+		{
+			name: "empty array hides element fields",
+			live: `{"words": [{"word": "hi", "start": 0}]}`,
+			mock: `{"words": []}`,
+		},
+		{
+			name: "UUID keys compare as one",
+			live: `{"models": {"6f1e0c1a-9b2d-4c3e-8f4a-5b6c7d8e9f00": {"name": "nova"}}}`,
+			mock: `{"models": {"00000000-0000-0000-0000-000000000000": {"name": "mock"}}}`,
+		},
+		{
+			name:   "ignored paths",
+			live:   `{"metadata": {"sha256": "x", "extra": {"a": 1}}, "id": "a"}`,
+			mock:   `{"metadata": {}, "id": 1}`,
+			ignore: []string{"metadata.**", "id"},
+		},
+		{
+			name:   "ignore with a single-element wildcard",
+			live:   `{"metadata": {"sha256": "x", "extra": {"a": 1}}}`,
+			mock:   `{"metadata": {}}`,
+			ignore: []string{"metadata.*"},
+			want:   []ShapeDrift{{Kind: DriftMissing, Path: "metadata.extra.a", Live: "number"}},
+		},
+	}
+	for _, test := range tests {
+		got := shapeDrift(shape(test.live), shape(test.mock), test.ignore)
+		if len(got) == 0 && len(test.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: shapeDrift = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+// Captured values
+// Management walkthroughs pass identifiers from one sample to the next: the
+// project created in one step is listed in the next. A sample's fence can
+// capture values from its stdout, and a sample depending on it can have them
+// substituted for placeholders in its code:
+//
+//	```go step=create-project capture.project_id="project_id: (\S+)"
+//	```go depends=create-project substitute.YOUR_PROJECT_ID=project_id
+//
+// A capture is a regular expression, whose first group (or whole match) is
+// the value, or a JSON path such as $.projects[0].project_id applied to the
+// JSON the sample prints.
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metadata key prefixes for a sample's captures and substitutions
+const (
+	metadataCapturePrefix    = "capture."
+	metadataSubstitutePrefix = "substitute."
+)
+
+var (
+	// fenceAttributeRegex matches key=value fence attributes, with values
+	// optionally quoted so they can hold spaces
+	fenceAttributeRegex  = regexp.MustCompile(`\b((?:capture|substitute)\.[\w-]+)=("(?:[^"\\]|\\.)*"|'[^']*'|\S+)`)
+	jsonPathElementRegex = regexp.MustCompile(`^\.?([\w-]+)|^\[(\d+)\]`)
+)
+
+// sampleCaptures records the captures and substitutions a sample's fence declares
+func sampleCaptures(sample *CodeSample, fenceMeta string) {
+	for _, match := range fenceAttributeRegex.FindAllStringSubmatch(fenceMeta, -1) {
+		// Quoted values keep their backslashes, which regular expressions
+		// need, except before a quote
+		value := match[2]
+		switch {
+		case strings.HasPrefix(value, `"`):
+			value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+		case strings.HasPrefix(value, "'"):
+			value = value[1 : len(value)-1]
+		}
+		sample.Metadata[match[1]] = value
+	}
+}
+
+// prefixedMetadata returns a sample's metadata under a prefix, keyed without it
+func prefixedMetadata(sample CodeSample, prefix string) map[string]string {
+	var values map[string]string
+	for key, value := range sample.Metadata {
+		if name := strings.TrimPrefix(key, prefix); name != key {
+			if values == nil {
+				values = make(map[string]string)
+			}
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// captureValues extracts a passing sample's declared captures from its output
+func captureValues(sample CodeSample, stdout string) (map[string]string, error) {
+	captures := prefixedMetadata(sample, metadataCapturePrefix)
+	if len(captures) == 0 {
+		return nil, nil
+	}
+
+	values := make(map[string]string, len(captures))
+	for _, name := range sortedStringKeys(captures) {
+		expression := captures[name]
+		var value string
+		var err error
+		if strings.HasPrefix(expression, "$") {
+			value, err = captureJSON(stdout, expression)
+		} else {
+			value, err = captureRegex(stdout, expression)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("capture %s: %v", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// captureRegex returns the first group of the pattern's first match, or the
+// whole match when the pattern has no groups
+func captureRegex(stdout, pattern string) (string, error) {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	match := compiled.FindStringSubmatch(stdout)
+	switch {
+	case match == nil:
+		return "", fmt.Errorf("%s doesn't match the output", pattern)
+	case len(match) > 1:
+		return match[1], nil
+	default:
+		return match[0], nil
+	}
+}
+
+// captureJSON applies a JSON path to the sample's output: all of it if it
+// is one JSON document, else the last line that is
+func captureJSON(stdout, path string) (string, error) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(stdout), &document); err != nil {
+		document = nil
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		for i := len(lines) - 1; i >= 0 && document == nil; i-- {
+			json.Unmarshal([]byte(lines[i]), &document)
+		}
+		if document == nil {
+			return "", fmt.Errorf("the output isn't JSON")
+		}
+	}
+
+	value := document
+	rest := strings.TrimPrefix(path, "$")
+	for rest != "" {
+		match := jsonPathElementRegex.FindStringSubmatch(rest)
+		if match == nil {
+			return "", fmt.Errorf("can't parse JSON path %s at %q", path, rest)
+		}
+		rest = rest[len(match[0]):]
+
+		if match[1] != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s: %q isn't in an object", path, match[1])
+			}
+			if value, ok = object[match[1]]; !ok {
+				return "", fmt.Errorf("%s: no %q in the output", path, match[1])
+			}
+			continue
+		}
+		index, _ := strconv.Atoi(match[2])
+		array, ok := value.([]interface{})
+		if !ok || index >= len(array) {
+			return "", fmt.Errorf("%s: no element %d in the output", path, index)
+		}
+		value = array[index]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", fmt.Errorf("%s is null in the output", path)
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// substituteCaptures replaces a sample's placeholders with the values
+// captured by the samples it depends on, transitively
+func (g executionGraph) substituteCaptures(samples []CodeSample, results []TestResult, i int) (CodeSample, error) {
+	sample := samples[i]
+	substitutions := prefixedMetadata(sample, metadataSubstitutePrefix)
+	if len(substitutions) == 0 {
+		return sample, nil
+	}
+
+	captured := make(map[string]string)
+	seen := make(map[int]bool)
+	var collect func(int)
+	collect = func(j int) {
+		for _, dependency := range g.dependencies[j] {
+			if seen[dependency] {
+				continue
+			}
+			seen[dependency] = true
+			collect(dependency)
+			for name, value := range results[dependency].Captured {
+				captured[name] = value
+			}
+		}
+	}
+	collect(i)
+
+	// Longer placeholders first, so one that contains another is replaced whole
+	placeholders := sortedStringKeys(substitutions)
+	sort.SliceStable(placeholders, func(a, b int) bool { return len(placeholders[a]) > len(placeholders[b]) })
+
+	var replacements []string
+	for _, placeholder := range placeholders {
+		name := substitutions[placeholder]
+		value, ok := captured[name]
+		if !ok {
+			return sample, fmt.Errorf("substitutes %s with %s, which no step it depends on captures", placeholder, name)
+		}
+		replacements = append(replacements, placeholder, value)
+	}
+	sample.Code = strings.NewReplacer(replacements...).Replace(sample.Code)
+	return sample, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaptureRegex(t *testing.T) {
+	tests := []struct {
+		name, stdout, pattern, want string
+		fails                       bool
+	}{
+		{"group", "created\nproject_id: p-123\n", `project_id: (\S+)`, "p-123", false},
+		{"whole match", "key k_abc ok", `k_\w+`, "k_abc", false},
+		{"first of several", "id=1 id=2", `id=(\d)`, "1", false},
+		{"no match", "nothing", `id=(\d)`, "", true},
+		{"invalid", "x", `(`, "", true},
+	}
+	for _, test := range tests {
+		got, err := captureRegex(test.stdout, test.pattern)
+		if (err != nil) != test.fails || got != test.want {
+			t.Errorf("%s: captureRegex = %q, %v, want %q (error %t)", test.name, got, err, test.want, test.fails)
+		}
+	}
+}
+
+func TestCaptureJSON(t *testing.T) {
+	document := `{"projects":[{"project_id":"p-1","name":"first","members":3,"active":true,"owner":null,"tags":["a"]}]}`
+	tests := []struct {
+		name, stdout, path, want string
+		fails                    bool
+	}{
+		{"string", document, "$.projects[0].project_id", "p-1", false},
+		{"number", document, "$.projects[0].members", "3", false},
+		{"boolean", document, "$.projects[0].active", "true", false},
+		{"array", document, "$.projects[0].tags", `["a"]`, false},
+		{"bracketed first element", `[{"id":"x"}]`, "$[0].id", "x", false},
+		{"last JSON line", "starting\n" + document + "\n", "$.projects[0].name", "first", false},
+		{"pretty document", "{\n  \"id\": \"x\"\n}\n", "$.id", "x", false},
+		{"null", document, "$.projects[0].owner", "", true},
+		{"missing key", document, "$.projects[0].nope", "", true},
+		{"index out of range", document, "$.projects[1]", "", true},
+		{"not an object", document, "$.projects.name", "", true},
+		{"not JSON", "plain text\n", "$.id", "", true},
+		{"bad path", document, "$.projects[x]", "", true},
+	}
+	for _, test := range tests {
+		got, err := captureJSON(test.stdout, test.path)
+		if (err != nil) != test.fails || got != test.want {
+			t.Errorf("%s: captureJSON = %q, %v, want %q (error %t)", test.name, got, err, test.want, test.fails)
+		}
+	}
+}
+
+func TestSubstituteCaptures(t *testing.T) {
+	samples := []CodeSample{
+		{Code: "create()"},
+		{Code: "key()"},
+		{Code: `list("YOUR_PROJECT_ID", "YOUR_PROJECT_ID_2", "YOUR_KEY")`, Metadata: map[string]string{
+			"substitute.YOUR_PROJECT_ID":   "project_id",
+			"substitute.YOUR_PROJECT_ID_2": "other_id",
+			"substitute.YOUR_KEY":          "key_id",
+		}},
+		{Code: `get("YOUR_SCOPE")`, Metadata: map[string]string{"substitute.YOUR_SCOPE": "scope"}},
+		{Code: "plain()"},
+	}
+	results := []TestResult{
+		{Captured: map[string]string{"project_id": "p-1", "other_id": "p-2"}},
+		{Captured: map[string]string{"key_id": "k-1"}},
+		{},
+		{},
+		{},
+	}
+	// Sample 2 depends on 1, which depends on 0, so it sees captures transitively
+	graph := executionGraph{dependencies: map[int][]int{1: {0}, 2: {1}, 3: {0}}}
+
+	tests := []struct {
+		index int
+		want  string
+		fails bool
+	}{
+		{2, `list("p-1", "p-2", "k-1")`, false},
+		{3, "", true},
+		{4, "plain()", false},
+	}
+	for _, test := range tests {
+		sample, err := graph.substituteCaptures(samples, results, test.index)
+		if test.fails {
+			if err == nil || !strings.Contains(err.Error(), "scope") {
+				t.Errorf("sample %d: error = %v, want one naming the missing capture", test.index, err)
+			}
+			continue
+		}
+		if err != nil || sample.Code != test.want {
+			t.Errorf("sample %d: substituteCaptures = %q, %v, want %q", test.index, sample.Code, err, test.want)
+		}
+	}
+	if samples[2].Code != `list("YOUR_PROJECT_ID", "YOUR_PROJECT_ID_2", "YOUR_KEY")` {
+		t.Errorf("substituteCaptures changed the sample it was given: %q", samples[2].Code)
+	}
+}
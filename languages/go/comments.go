@@ -0,0 +1,255 @@
+package main
+
+// Stale inline comments
+// A comment saying "uses the enhanced model" above Model: "nova-3" misleads
+// readers as much as broken code, and nothing fails when it drifts. Comments
+// are checked heuristically against the code they sit in: models,
+// parameters, and endpoints a comment names should appear in the code (or,
+// for endpoints, belong to the product the sample uses).
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RuleStaleComments is the validation rule for comments that don't match the code
+const RuleStaleComments = "stale_comments"
+
+// defaultCommentModels are model and tier names looked for in comments,
+// overridable by comment_check.models in the language config
+var defaultCommentModels = []string{
+	"nova-3", "nova-2", "nova", "enhanced", "base", "whisper",
+	"aura-2", "aura",
+}
+
+// proseModels are model names that are also everyday words ("a custom base
+// URL"), so a comment only names them as a model when they're quoted or
+// followed by "model" or "tier"
+var proseModels = map[string]bool{"base": true, "enhanced": true}
+
+// defaultCommentParameters are request parameters looked for in comments,
+// overridable by comment_check.parameters in the language config
+var defaultCommentParameters = []string{
+	"smart_format", "punctuate", "diarize", "utterances", "paragraphs",
+	"interim_results", "endpointing", "utterance_end_ms", "vad_events",
+	"detect_language", "profanity_filter", "keywords", "keyterm", "redact",
+	"numerals", "summarize", "topics", "intents", "sentiment",
+	"sample_rate", "encoding", "channels", "multichannel", "filler_words",
+}
+
+// productEndpoints are the API paths each product's samples reach
+var productEndpoints = map[string][]string{
+	ProductSTTPrerecorded:   {"/v1/listen"},
+	ProductSTTLive:          {"/v1/listen"},
+	ProductTTS:              {"/v1/speak"},
+	ProductVoiceAgent:       {"/v1/agent"},
+	ProductTextIntelligence: {"/v1/read"},
+	ProductManagement:       {"/v1/projects", "/v1/keys", "/v1/usage", "/v1/members", "/v1/balances"},
+}
+
+var (
+	endpointRegex  = regexp.MustCompile(`/v\d+/[a-z]+`)
+	backtickRegex  = regexp.MustCompile("`([^`]+)`")
+	codeTokenRegex = regexp.MustCompile(`[A-Za-z][A-Za-z0-9]*(?:_[A-Za-z0-9]+)+|[a-z]+(?:[A-Z][a-z0-9]+)+|[A-Z][a-z0-9]+(?:[A-Z][a-z0-9]+)+`)
+	wordRegex      = regexp.MustCompile(`[A-Za-z0-9_]+`)
+)
+
+// staleCommentsProblem describes the comments in a sample that name
+// models, parameters, or endpoints its code doesn't use
+func (e *GoExecutor) staleCommentsProblem(sample CodeSample) (problem string, checked bool) {
+	_, file, err := parseSample(sample.Code)
+	if err != nil || len(file.Comments) == 0 {
+		return "", false
+	}
+
+	config := configSection(e.LanguageConfig, "comment_check")
+	models := configStrings(config, "models")
+	if models == nil {
+		models = defaultCommentModels
+	}
+	parameters := configStrings(config, "parameters")
+	if parameters == nil {
+		parameters = defaultCommentParameters
+	}
+
+	var problems []string
+	code := codeVocabulary(file)
+	codeModels := mentionedModels(strings.Join(code.literals, "\n"), models)
+
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			text := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(comment.Text, "//"), "/*"), "*/"))
+			if text == "" || strings.HasPrefix(text, "go:") {
+				continue
+			}
+			line := commentLine(sample, comment.Text)
+
+			// A model in the comment must be (a prefix of) one the code sets
+			if len(codeModels) > 0 {
+				for _, model := range commentModels(text, models) {
+					if !prefixOfAny(model, codeModels) {
+						problems = append(problems, fmt.Sprintf("line %d: comment mentions %s, but the code uses %s", line, model, strings.Join(codeModels, ", ")))
+					}
+				}
+			}
+
+			for _, parameter := range mentionedParameters(text, parameters) {
+				if !code.mentions(parameter) {
+					problems = append(problems, fmt.Sprintf("line %d: comment mentions %s, which the code doesn't set", line, parameter))
+				}
+			}
+
+			for _, endpoint := range endpointRegex.FindAllString(text, -1) {
+				if !code.reaches(endpoint, sample.Product) {
+					problems = append(problems, fmt.Sprintf("line %d: comment mentions %s, which the code doesn't call", line, endpoint))
+				}
+			}
+		}
+	}
+	return strings.Join(problems, "; "), true
+}
+
+// sampleVocabulary is what a sample's code names
+type sampleVocabulary struct {
+	// words are identifiers and words in string literals, lowercased with
+	// underscores removed so smart_format and SmartFormat match
+	words    map[string]bool
+	literals []string
+}
+
+// codeVocabulary collects the identifiers and string literals of a file
+func codeVocabulary(file *ast.File) sampleVocabulary {
+	vocabulary := sampleVocabulary{words: make(map[string]bool)}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.Ident:
+			vocabulary.words[normalizeWord(node.Name)] = true
+		case *ast.BasicLit:
+			if node.Kind != token.STRING {
+				return true
+			}
+			value, err := strconv.Unquote(node.Value)
+			if err != nil {
+				return true
+			}
+			vocabulary.literals = append(vocabulary.literals, value)
+			for _, word := range wordRegex.FindAllString(value, -1) {
+				vocabulary.words[normalizeWord(word)] = true
+			}
+		}
+		return true
+	})
+	return vocabulary
+}
+
+// mentions reports whether the code names a parameter in either case style
+func (v sampleVocabulary) mentions(parameter string) bool {
+	return v.words[normalizeWord(parameter)]
+}
+
+// reaches reports whether the code calls an endpoint, through a URL in a
+// literal or through the SDK client of a product using it
+func (v sampleVocabulary) reaches(endpoint, product string) bool {
+	for _, literal := range v.literals {
+		if strings.Contains(literal, endpoint) {
+			return true
+		}
+	}
+	for _, path := range productEndpoints[product] {
+		if strings.HasPrefix(path, endpoint) || strings.HasPrefix(endpoint, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionedModels returns the models named in text, preferring the longest
+// name where one contains another, e.g. nova-3 over nova
+func mentionedModels(text string, models []string) []string {
+	lower := strings.ToLower(text)
+	sorted := append([]string{}, models...)
+	sort.SliceStable(sorted, func(a, b int) bool { return len(sorted[a]) > len(sorted[b]) })
+
+	var found []string
+	for _, model := range sorted {
+		pattern := regexp.MustCompile(`(^|[^\w-])` + regexp.QuoteMeta(strings.ToLower(model)) + `($|[^\w-]|-[a-z])`)
+		if pattern.MatchString(lower) && !prefixOfAny(model, found) {
+			found = append(found, model)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// commentModels returns the models a comment names, leaving out prose
+// models used as ordinary words
+func commentModels(text string, models []string) []string {
+	lower := strings.ToLower(text)
+	var found []string
+	for _, model := range mentionedModels(text, models) {
+		if proseModels[model] {
+			name := regexp.QuoteMeta(model)
+			asModel := regexp.MustCompile("[\"'`]" + name + "[\"'`]|\\b" + name + `\s+(model|tier)s?\b`)
+			if !asModel.MatchString(lower) {
+				continue
+			}
+		}
+		found = append(found, model)
+	}
+	return found
+}
+
+// mentionedParameters returns the parameters a comment names as code: in
+// backticks, or spelled snake_case or CamelCase, since plain words like
+// "keywords" are as likely to be prose
+func mentionedParameters(text string, parameters []string) []string {
+	known := make(map[string]string, len(parameters))
+	for _, parameter := range parameters {
+		known[normalizeWord(parameter)] = parameter
+	}
+
+	var tokens []string
+	for _, match := range backtickRegex.FindAllStringSubmatch(text, -1) {
+		tokens = append(tokens, wordRegex.FindAllString(match[1], -1)...)
+	}
+	tokens = append(tokens, codeTokenRegex.FindAllString(text, -1)...)
+
+	seen := make(map[string]bool)
+	var found []string
+	for _, token := range tokens {
+		if parameter, ok := known[normalizeWord(token)]; ok && !seen[parameter] {
+			seen[parameter] = true
+			found = append(found, parameter)
+		}
+	}
+	return found
+}
+
+// prefixOfAny reports whether name is, or begins, any of the candidates
+func prefixOfAny(name string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeWord folds case and drops underscores
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.ReplaceAll(word, "_", ""))
+}
+
+// commentLine returns the page line of a comment
+func commentLine(sample CodeSample, comment string) int {
+	offset := strings.Index(sample.Code, comment)
+	if offset < 0 {
+		return sample.CodeLineNumber
+	}
+	return sample.CodeLineNumber + strings.Count(sample.Code[:offset], "\n")
+}
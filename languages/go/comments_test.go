@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStaleCommentsModels(t *testing.T) {
+	sample := func(comment string) CodeSample {
+		return CodeSample{Code: `package main
+
+import "fmt"
+
+func main() {
+	` + comment + `
+	options := map[string]string{"Model": "nova-3"}
+	fmt.Println(options)
+}
+`}
+	}
+	tests := []struct {
+		comment string
+		problem string
+	}{
+		{"// Transcribe with nova-3", ""},
+		{"// Transcribe with Nova", ""},
+		{"// Transcribe with nova-2", "comment mentions nova-2, but the code uses nova-3"},
+		// Everyday words aren't models
+		{"// Point the client at a custom base URL", ""},
+		{"// Keep the enhanced logging from the previous step", ""},
+		{"// Use the base model", "comment mentions base, but the code uses nova-3"},
+		{"// Switch to the enhanced tier for accuracy", "comment mentions enhanced, but the code uses nova-3"},
+		{"// Model: \"base\"", "comment mentions base, but the code uses nova-3"},
+	}
+	e := &GoExecutor{}
+	for _, test := range tests {
+		problem, checked := e.staleCommentsProblem(sample(test.comment))
+		if !checked {
+			t.Errorf("%q wasn't checked", test.comment)
+			continue
+		}
+		if test.problem == "" && problem != "" || !strings.Contains(problem, test.problem) {
+			t.Errorf("%q: problem = %q, want %q", test.comment, problem, test.problem)
+		}
+	}
+}
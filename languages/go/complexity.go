@@ -0,0 +1,109 @@
+package main
+
+// Sample complexity scoring
+// Long, branchy, concurrent snippets are hard for readers to follow, and a
+// "getting started" page whose first snippet is a full application loses
+// people before they make their first request.
+
+import (
+	"go/ast"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Complexity describes how much a reader has to take in to follow a sample
+type Complexity struct {
+	LOC        int `json:"loc"`
+	Imports    int `json:"imports"`
+	Branches   int `json:"branches"`
+	Goroutines int `json:"goroutines"`
+	Score      int `json:"score"`
+}
+
+// ComplexityOutlier is a sample that exceeds a configured complexity threshold
+type ComplexityOutlier struct {
+	Sample CodeSample `json:"sample"`
+	Reason string     `json:"reason"`
+}
+
+var (
+	branchRegex    = regexp.MustCompile(`\b(if|for|switch|select|case)\b`)
+	goroutineRegex = regexp.MustCompile(`\bgo\s+(func\b|[\w.]+\()`)
+)
+
+// measureComplexity scores a sample from its AST when available, or from
+// its text when the snippet doesn't parse
+func measureComplexity(code string, imports []string, file *ast.File) Complexity {
+	complexity := Complexity{
+		LOC:     countCodeLines(code),
+		Imports: len(imports),
+	}
+
+	if file != nil {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n.(type) {
+			case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt,
+				*ast.TypeSwitchStmt, *ast.SelectStmt, *ast.CaseClause, *ast.CommClause:
+				complexity.Branches++
+			case *ast.GoStmt:
+				complexity.Goroutines++
+			}
+			return true
+		})
+	} else {
+		complexity.Branches = len(branchRegex.FindAllString(code, -1))
+		complexity.Goroutines = len(goroutineRegex.FindAllString(code, -1))
+	}
+
+	// Lines dominate; each import, branch, and goroutine adds a concept the
+	// reader has to hold in their head
+	complexity.Score = complexity.LOC + 2*complexity.Imports + 3*complexity.Branches + 5*complexity.Goroutines
+	return complexity
+}
+
+// countCodeLines counts lines that are neither blank nor comment-only
+func countCodeLines(code string) int {
+	count := 0
+	for _, line := range strings.Split(code, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "//") {
+			count++
+		}
+	}
+	return count
+}
+
+// complexityOutliers flags samples scoring above complexity.max_score, and
+// the first sample on a page when it is longer than complexity.first_sample_max_loc
+func (e *GoExecutor) complexityOutliers(samples []CodeSample) []ComplexityOutlier {
+	config := configSection(e.LanguageConfig, "complexity")
+	maxScore := configInt(config, "max_score", 80)
+	firstSampleMaxLOC := configInt(config, "first_sample_max_loc", 40)
+
+	seenPages := make(map[string]bool)
+	var outliers []ComplexityOutlier
+
+	for _, sample := range samples {
+		first := !seenPages[sample.FilePath]
+		seenPages[sample.FilePath] = true
+
+		switch {
+		case first && sample.Complexity.LOC > firstSampleMaxLOC:
+			outliers = append(outliers, ComplexityOutlier{
+				Sample: sample,
+				Reason: "first sample on the page is long for an introduction",
+			})
+		case sample.Complexity.Score > maxScore:
+			outliers = append(outliers, ComplexityOutlier{
+				Sample: sample,
+				Reason: "complexity score is above the configured maximum",
+			})
+		}
+	}
+
+	sort.SliceStable(outliers, func(i, j int) bool {
+		return outliers[i].Sample.Complexity.Score > outliers[j].Sample.Complexity.Score
+	})
+	return outliers
+}
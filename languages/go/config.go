@@ -0,0 +1,123 @@
+package main
+
+// Configuration loading for the Go executor
+// The Python test runner owns the YAML files; the Go executor receives the
+// same data as JSON so it can stay dependency free.
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// executorConfig is the JSON document handed to the Go executor
+type executorConfig struct {
+	Language  map[string]interface{} `json:"language"`
+	Framework map[string]interface{} `json:"framework"`
+}
+
+// loadConfig reads the language and framework configuration from a JSON file.
+// An empty path returns defaults mirroring config/languages/go.yaml and
+// config/framework_config.yaml.
+func loadConfig(path string) (langConfig, frameworkConfig map[string]interface{}, err error) {
+	if path == "" {
+		return defaultLanguageConfig(), defaultFrameworkConfig(), nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseConfig(content)
+}
+
+// parseConfig reads the language and framework configuration from JSON,
+// over the defaults
+func parseConfig(content []byte) (langConfig, frameworkConfig map[string]interface{}, err error) {
+	config := executorConfig{
+		Language:  defaultLanguageConfig(),
+		Framework: defaultFrameworkConfig(),
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, nil, err
+	}
+	return config.Language, config.Framework, nil
+}
+
+func defaultLanguageConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"sdk": map[string]interface{}{
+			"current_version": "v2",
+			"repository_path": "../deepgram-go-sdk",
+			"source_path":     ".",
+			"module_name":     "github.com/deepgram/deepgram-go-sdk/v2",
+		},
+	}
+}
+
+func defaultFrameworkConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"execution": map[string]interface{}{
+			"timeout_seconds": float64(10),
+			"parallel_tests":  true,
+			"max_concurrent":  float64(5),
+		},
+		"api": map[string]interface{}{
+			"environments": map[string]interface{}{
+				"production": map[string]interface{}{
+					"api_key_env": "DEEPGRAM_API_KEY",
+				},
+				"staging": map[string]interface{}{
+					"base_url":    "${DEEPGRAM_STAGING_URL}",
+					"api_key_env": "DEEPGRAM_STAGING_API_KEY",
+				},
+			},
+		},
+	}
+}
+
+// configSection returns a nested config map, or an empty map if it is missing
+func configSection(config map[string]interface{}, key string) map[string]interface{} {
+	if section, ok := config[key].(map[string]interface{}); ok {
+		return section
+	}
+	return map[string]interface{}{}
+}
+
+func configString(config map[string]interface{}, key, fallback string) string {
+	if value, ok := config[key].(string); ok {
+		return value
+	}
+	return fallback
+}
+
+func configInt(config map[string]interface{}, key string, fallback int) int {
+	if value, ok := config[key].(float64); ok {
+		return int(value)
+	}
+	return fallback
+}
+
+func configFloat(config map[string]interface{}, key string, fallback float64) float64 {
+	if value, ok := config[key].(float64); ok {
+		return value
+	}
+	return fallback
+}
+
+func configBool(config map[string]interface{}, key string, fallback bool) bool {
+	if value, ok := config[key].(bool); ok {
+		return value
+	}
+	return fallback
+}
+
+// configStrings reads a list of strings, skipping any non-string entries
+func configStrings(config map[string]interface{}, key string) []string {
+	values, _ := config[key].([]interface{})
+	var result []string
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
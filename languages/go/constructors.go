@@ -0,0 +1,227 @@
+package main
+
+// Client construction by SDK version
+// Each SDK major version has its own client entry points (New and
+// NewWithDefaults in v1/v2, NewREST and the NewWSUsing* constructors in v3)
+// and its own option types. Samples copied between versions mix them, e.g.
+// a v3 constructor handed v2 interfaces.ClientOptions, which only fails
+// when the sample is built. The validator names the offending call.
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// RuleClientConstruction is the validation rule for SDK client construction
+const RuleClientConstruction = "client_construction"
+
+// defaultClientConstructors are each SDK major version's non-streaming
+// client entry points, overridable by client_constructors in the language
+// config. Live constructors come from streaming_patterns.
+var defaultClientConstructors = map[string][]string{
+	"v1": {"New", "NewWithDefaults", "NewForDemo"},
+	"v2": {"New", "NewWithDefaults", "NewForDemo"},
+	"v3": {"New", "NewWithDefaults", "NewREST", "NewRESTWithDefaults"},
+}
+
+// sdkMajorVersion returns the SDK major version an import path belongs to,
+// "v1" for the unversioned module path
+func sdkMajorVersion(importPath string) string {
+	rest := strings.TrimPrefix(strings.TrimPrefix(importPath, sdkModulePrefix), "/")
+	element, _, _ := strings.Cut(rest, "/")
+	if isMajorVersionSuffix(element) {
+		return element
+	}
+	return "v1"
+}
+
+// clientConstructorsFor returns every client constructor of an SDK major version
+func (e *GoExecutor) clientConstructorsFor(version string) ([]string, bool) {
+	constructors, ok := defaultClientConstructors[version]
+	if configured := configStrings(configSection(e.LanguageConfig, "client_constructors"), version); len(configured) > 0 {
+		constructors, ok = configured, true
+	}
+	if patterns, known := e.streamingPatternsFor(version); known {
+		constructors = append(append(append([]string{}, constructors...), patterns.Callback...), patterns.Channel...)
+		ok = true
+	}
+	return constructors, ok
+}
+
+// isClientConstructorName reports whether a New* function of an SDK client
+// package constructs a client: it's an entry point of some SDK version or
+// names a client. Option helpers like NewSettingsConfigurationOptions don't.
+func (e *GoExecutor) isClientConstructorName(name string) bool {
+	if !strings.HasPrefix(name, "New") || strings.HasSuffix(name, "Options") {
+		return false
+	}
+	if strings.Contains(name, "Client") {
+		return true
+	}
+	versions := make(map[string]bool)
+	for version := range defaultClientConstructors {
+		versions[version] = true
+	}
+	for version := range defaultStreamingPatterns {
+		versions[version] = true
+	}
+	for _, section := range []string{"client_constructors", "streaming_patterns"} {
+		for version := range configSection(e.LanguageConfig, section) {
+			versions[version] = true
+		}
+	}
+	for version := range versions {
+		if constructors, _ := e.clientConstructorsFor(version); containsString(constructors, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientConstructionProblem explains the first client constructor call that
+// isn't an entry point of its SDK version or is given option types from
+// another version. checked is false for samples that construct no client.
+func (e *GoExecutor) clientConstructionProblem(sample CodeSample) (problem string, checked bool) {
+	_, file, err := parseSample(sample.Code)
+	if err != nil {
+		return "", false
+	}
+
+	aliases := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		if !strings.HasPrefix(importPath, sdkModulePrefix) {
+			continue
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else if isMajorVersionSuffix(name) {
+			name = path.Base(path.Dir(importPath))
+		}
+		aliases[name] = importPath
+	}
+
+	// sdkType returns the SDK package and type of a T{...}, &T{...}, or new(T) expression
+	sdkType := func(expr ast.Expr) (importPath, typeName string) {
+		if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			expr = unary.X
+		}
+		switch node := expr.(type) {
+		case *ast.CompositeLit:
+			expr = node.Type
+		case *ast.CallExpr:
+			if ident, ok := node.Fun.(*ast.Ident); ok && ident.Name == "new" && len(node.Args) == 1 {
+				expr = node.Args[0]
+			}
+		case *ast.StarExpr:
+			expr = node.X
+		}
+		selector, ok := expr.(*ast.SelectorExpr)
+		if !ok {
+			return "", ""
+		}
+		ident, ok := selector.X.(*ast.Ident)
+		if !ok || ident.Obj != nil {
+			return "", ""
+		}
+		return aliases[ident.Name], ident.Name + "." + selector.Sel.Name
+	}
+
+	// Option values held in variables, e.g. cOptions := &interfaces.ClientOptions{}
+	type sdkValue struct{ importPath, typeName string }
+	variables := make(map[string]sdkValue)
+	record := func(name *ast.Ident, expr ast.Expr) {
+		if importPath, typeName := sdkType(expr); importPath != "" {
+			variables[name.Name] = sdkValue{importPath, typeName}
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && i < len(node.Rhs) && len(node.Lhs) == len(node.Rhs) {
+					record(ident, node.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range node.Names {
+				switch {
+				case i < len(node.Values):
+					record(name, node.Values[i])
+				case node.Type != nil:
+					record(name, node.Type)
+				}
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if problem != "" {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := selector.X.(*ast.Ident)
+		if !ok || ident.Obj != nil {
+			return true
+		}
+		importPath := aliases[ident.Name]
+		name := selector.Sel.Name
+		if !strings.Contains(importPath, "/pkg/client") || !e.isClientConstructorName(name) {
+			return true
+		}
+
+		version := sdkMajorVersion(importPath)
+		constructors, known := e.clientConstructorsFor(version)
+		if !known {
+			return true
+		}
+		checked = true
+
+		if !containsString(constructors, name) {
+			problem = callSource(call) + ": " + name + " isn't a client constructor in SDK " + version + "; use one of " + strings.Join(constructors, ", ")
+			return false
+		}
+		for _, arg := range call.Args {
+			argPath, typeName := sdkType(arg)
+			if unary, ok := arg.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+				arg = unary.X
+			}
+			if argIdent, ok := arg.(*ast.Ident); ok {
+				value := variables[argIdent.Name]
+				argPath, typeName = value.importPath, value.typeName
+			}
+			if argPath == "" {
+				continue
+			}
+			if argVersion := sdkMajorVersion(argPath); argVersion != version {
+				problem = callSource(call) + ": SDK " + version + " constructor " + name + " is given " + typeName + " from SDK " + argVersion
+				return false
+			}
+		}
+		return true
+	})
+	return problem, checked
+}
+
+// callSource renders a call expression as it would appear in gofmt'd code
+func callSource(call *ast.CallExpr) string {
+	var source bytes.Buffer
+	if err := format.Node(&source, token.NewFileSet(), call); err != nil {
+		return "call"
+	}
+	return source.String()
+}
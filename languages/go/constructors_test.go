@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientConstructionProblem(t *testing.T) {
+	tests := []struct {
+		name, code string
+		checked    bool
+		problem    string
+	}{
+		{
+			name: "v3 voice agent",
+			code: `package main
+
+import (
+	"context"
+
+	client "github.com/deepgram/deepgram-go-sdk/v3/pkg/client/agent"
+	interfaces "github.com/deepgram/deepgram-go-sdk/v3/pkg/client/interfaces"
+)
+
+func main() {
+	ctx := context.Background()
+	cOptions := &interfaces.ClientOptions{EnableKeepAlive: true}
+	tOptions := client.NewSettingsConfigurationOptions()
+	tOptions.Agent.Think.Provider["type"] = "open_ai"
+	dgClient, err := client.NewWSUsingChan(ctx, "", cOptions, tOptions, nil)
+	_, _ = dgClient, err
+}
+`,
+			checked: true,
+		},
+		{
+			name: "option helpers alone",
+			code: `package main
+
+import client "github.com/deepgram/deepgram-go-sdk/v3/pkg/client/agent"
+
+func main() {
+	_ = client.NewSettingsConfigurationOptions()
+}
+`,
+		},
+		{
+			name: "v3 REST client",
+			code: `package main
+
+import (
+	interfaces "github.com/deepgram/deepgram-go-sdk/v3/pkg/client/interfaces"
+	client "github.com/deepgram/deepgram-go-sdk/v3/pkg/client/listen"
+)
+
+func main() {
+	c := client.NewREST("", &interfaces.ClientOptions{})
+	_ = c
+}
+`,
+			checked: true,
+		},
+		{
+			name: "v2 options given to a v3 constructor",
+			code: `package main
+
+import (
+	interfaces "github.com/deepgram/deepgram-go-sdk/pkg/client/interfaces"
+	client "github.com/deepgram/deepgram-go-sdk/v3/pkg/client/listen"
+)
+
+func main() {
+	cOptions := &interfaces.ClientOptions{}
+	c := client.NewREST("", cOptions)
+	_ = c
+}
+`,
+			checked: true,
+			problem: "SDK v3 constructor NewREST is given interfaces.ClientOptions from SDK v1",
+		},
+		{
+			name: "v3 constructor in v1",
+			code: `package main
+
+import client "github.com/deepgram/deepgram-go-sdk/pkg/client/prerecorded"
+
+func main() {
+	c := client.NewREST("", nil)
+	_ = c
+}
+`,
+			checked: true,
+			problem: "NewREST isn't a client constructor in SDK v1",
+		},
+		{
+			name: "no client",
+			code: "package main\n\nfunc main() {}\n",
+		},
+	}
+	e := &GoExecutor{}
+	for _, test := range tests {
+		problem, checked := e.clientConstructionProblem(CodeSample{Code: test.code})
+		if checked != test.checked {
+			t.Errorf("%s: checked = %t, want %t", test.name, checked, test.checked)
+		}
+		if test.problem == "" && problem != "" || !strings.Contains(problem, test.problem) {
+			t.Errorf("%s: problem = %q, want %q", test.name, problem, test.problem)
+		}
+	}
+}
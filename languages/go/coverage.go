@@ -0,0 +1,170 @@
+package main
+
+// SDK coverage from docs samples
+// With a local SDK checkout, samples can be built with coverage
+// instrumentation for the SDK's packages. Every sample writes its counters
+// to one GOCOVERDIR, so the merged profile shows which parts of the SDK the
+// documentation actually exercises.
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SDKCoverage is how much of the SDK the samples of a run exercised
+type SDKCoverage struct {
+	Module    string             `json:"module"`
+	Percent   float64            `json:"percent"`
+	Packages  []PackageCoverage  `json:"packages"`
+	Functions []FunctionCoverage `json:"functions"`
+}
+
+// PackageCoverage is the statement coverage of one SDK package. Packages no
+// sample imports aren't instrumented and are reported at zero.
+type PackageCoverage struct {
+	Package  string  `json:"package"`
+	Percent  float64 `json:"percent"`
+	Imported bool    `json:"imported"`
+}
+
+// FunctionCoverage is the statement coverage of one SDK function
+type FunctionCoverage struct {
+	Position string  `json:"position"`
+	Function string  `json:"function"`
+	Percent  float64 `json:"percent"`
+}
+
+// coverBuildFlags returns the build flags that instrument the SDK's
+// packages. The main package has to be instrumented as well, or the binary
+// never writes its counters.
+func coverBuildFlags(modulePath string) []string {
+	return []string{"-cover", "-coverpkg=" + modulePath + "/...,command-line-arguments"}
+}
+
+// SDKCoverage reads the merged profile written to CoverDir during a run
+func (e *GoExecutor) SDKCoverage() (*SDKCoverage, error) {
+	modulePath, sdkDir, ok := e.localSDKModule()
+	if !ok {
+		return nil, fmt.Errorf("SDK coverage needs a local SDK checkout with a go.mod at %s", e.SDKPath)
+	}
+
+	coverage := &SDKCoverage{Module: modulePath}
+	inModule := func(pkg string) bool {
+		return pkg == modulePath || strings.HasPrefix(pkg, modulePath+"/")
+	}
+
+	percents, err := e.covdata("percent")
+	if err != nil {
+		return nil, err
+	}
+	measured := make(map[string]float64)
+	for _, line := range percents {
+		// <package> coverage: 50.0% of statements
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[1] == "coverage:" && inModule(fields[0]) {
+			measured[fields[0]] = parsePercent(fields[2])
+		}
+	}
+
+	// Every non-test package in the SDK, so unimported ones show up at zero
+	list := exec.Command("go", "list", "./...")
+	list.Dir = sdkDir
+	list.Env = e.goToolEnv()
+	started := time.Now()
+	output, err := list.Output()
+	e.Audit.record(list, "", started, err)
+	if err != nil {
+		return nil, fmt.Errorf("go list in %s: %v", sdkDir, err)
+	}
+	for _, pkg := range strings.Fields(string(output)) {
+		percent, imported := measured[pkg]
+		coverage.Packages = append(coverage.Packages, PackageCoverage{Package: pkg, Percent: percent, Imported: imported})
+	}
+	sort.Slice(coverage.Packages, func(i, j int) bool {
+		return coverage.Packages[i].Package < coverage.Packages[j].Package
+	})
+
+	functions, err := e.covdata("func")
+	if err != nil {
+		return nil, err
+	}
+	var covered, total int
+	for _, line := range functions {
+		// <file>:<line>: <function> 100.0%
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !inModule(fields[0]) {
+			continue
+		}
+		function := FunctionCoverage{
+			Position: strings.TrimSuffix(fields[0], ":"),
+			Function: fields[1],
+			Percent:  parsePercent(fields[len(fields)-1]),
+		}
+		coverage.Functions = append(coverage.Functions, function)
+		total++
+		if function.Percent > 0 {
+			covered++
+		}
+	}
+	if total > 0 {
+		coverage.Percent = 100 * float64(covered) / float64(total)
+	}
+
+	return coverage, nil
+}
+
+// resetCoverDir creates dir, removing profiles left by an earlier run so
+// they aren't merged into this one
+func resetCoverDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, pattern := range []string{"covmeta.*", "covcounters.*"} {
+		stale, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, path := range stale {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// covdata runs a `go tool covdata` report over CoverDir and returns its lines
+func (e *GoExecutor) covdata(mode string) ([]string, error) {
+	cmd := exec.Command("go", "tool", "covdata", mode, "-i", e.CoverDir)
+	cmd.Env = e.goToolEnv()
+	started := time.Now()
+	output, err := cmd.Output()
+	e.Audit.record(cmd, "", started, err)
+	if err != nil {
+		return nil, fmt.Errorf("go tool covdata %s: %v", mode, err)
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n"), nil
+}
+
+// parsePercent parses "66.7%"
+func parsePercent(text string) float64 {
+	percent, _ := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64)
+	return percent
+}
+
+// uncoveredFunctions returns the SDK functions no sample reached
+func (c *SDKCoverage) uncoveredFunctions() []FunctionCoverage {
+	var uncovered []FunctionCoverage
+	for _, function := range c.Functions {
+		if function.Percent == 0 {
+			uncovered = append(uncovered, function)
+		}
+	}
+	return uncovered
+}
@@ -0,0 +1,109 @@
+package main
+
+// Interactive debugging of a single sample
+// Reconstructing the exact workspace, environment, and commands the executor
+// used is tedious, so `debug` prepares them and hands the terminal over.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// debugCommand prepares a sample's workspace, prints how the executor would
+// run it, and opens a shell (or dlv) inside the workspace
+func debugCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	live := flags.Bool("live", false, "Use DEEPGRAM_API_KEY from the environment instead of a test key")
+	dlv := flags.Bool("dlv", false, "Start the sample under dlv instead of opening a shell")
+	shellName := flags.String("shell", "", "Shell to print commands for and open: sh, bash, zsh, pwsh, powershell, or cmd (overrides execution.shell)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: debug [--dlv] [--shell name] page.mdx:line")
+	}
+
+	executor, err := docs.executor()
+	if err != nil {
+		return err
+	}
+	shell, err := executor.commandShell(*shellName)
+	if err != nil {
+		return err
+	}
+	executor.Live = *live
+	if err := executor.checkEnvironmentKey(); err != nil {
+		return err
+	}
+
+	sample, err := sampleAtTarget(executor, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var usage ResourceUsage
+	dir, err := executor.prepareWorkspace(ctx, sample, &usage)
+	if err != nil {
+		return err
+	}
+	env := executor.sampleEnv()
+
+	fmt.Printf("📄 %s:%d-%d\n", sample.FilePath, sample.LineNumber, sample.EndLineNumber)
+	fmt.Printf("📁 Workspace: %s\n", dir)
+	fmt.Printf("🔧 Environment:\n")
+	for _, variable := range env {
+		fmt.Printf("   %s\n", variable)
+	}
+	if len(env) == 0 {
+		fmt.Printf("   (inherited unchanged)\n")
+	}
+	fmt.Printf("▶️  The executor runs:\n")
+	fmt.Printf("   cd %s\n", dir)
+	fmt.Printf("   %s\n", strings.Join(executor.buildCommandArgs(), " "))
+	for _, line := range shell.runBinary(sampleBinary, env) {
+		fmt.Printf("   %s\n", line)
+	}
+	fmt.Printf("   (time limit %s)\n\n", executor.samplePolicy(sample).Timeout)
+
+	var cmd *exec.Cmd
+	if *dlv {
+		path, err := exec.LookPath("dlv")
+		if err != nil {
+			return fmt.Errorf("dlv not found; install with: go install github.com/go-delve/delve/cmd/dlv@latest")
+		}
+		cmd = exec.Command(path, "debug", "main.go")
+	} else {
+		fmt.Printf("🐚 Starting %s in the workspace; exit to return\n", shell.Path)
+		cmd = exec.Command(shell.Path)
+	}
+
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+
+	fmt.Printf("\n📁 Workspace left in place: %s\n", dir)
+	return err
+}
+
+// interactiveShell returns the user's shell
+func interactiveShell() string {
+	if runtime.GOOS == "windows" {
+		if comspec := os.Getenv("COMSPEC"); comspec != "" {
+			return comspec
+		}
+		return "cmd.exe"
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
@@ -0,0 +1,255 @@
+package main
+
+// Sample dependencies
+// Tutorial pages build up state across samples: one creates a project, the
+// next uses it. A sample's fence can name it as a step and list the steps
+// it depends on, e.g. ```go step=use-project depends=create-project. Samples
+// on a page joined by dependencies run as one chain, in dependency order on
+// a single worker, and a sample whose dependency didn't pass is skipped.
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Metadata keys set from a sample's fence
+const (
+	metadataStep      = "step"
+	metadataDependsOn = "depends_on"
+)
+
+var (
+	fenceStepRegex    = regexp.MustCompile(`\bstep=["']?([\w.-]+)`)
+	fenceDependsRegex = regexp.MustCompile(`\bdepends=["']?([\w.,-]+)`)
+)
+
+// sampleDependencies records the step a sample's fence names and the steps
+// it depends on
+func sampleDependencies(sample *CodeSample, fenceMeta string) {
+	if match := fenceStepRegex.FindStringSubmatch(fenceMeta); match != nil {
+		sample.Metadata[metadataStep] = match[1]
+	}
+	if match := fenceDependsRegex.FindStringSubmatch(fenceMeta); match != nil {
+		sample.Metadata[metadataDependsOn] = strings.Trim(match[1], ",")
+	}
+}
+
+// dependsOn returns the steps a sample depends on
+func dependsOn(sample CodeSample) []string {
+	var steps []string
+	for _, step := range strings.Split(sample.Metadata[metadataDependsOn], ",") {
+		if step = strings.TrimSpace(step); step != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// executionGraph orders samples so dependencies run first
+type executionGraph struct {
+	// units are the index lists run one after another by a single worker:
+	// a lone sample, or a page's chain of dependent samples in order
+	units [][]int
+	// dependencies maps a sample to the samples it depends on
+	dependencies map[int][]int
+	// problems explains samples whose dependencies can't be satisfied
+	problems map[int]string
+}
+
+// dependencyGraph resolves every sample's dependencies among the samples
+// of its page
+func dependencyGraph(samples []CodeSample) executionGraph {
+	graph := executionGraph{
+		dependencies: make(map[int][]int),
+		problems:     make(map[int]string),
+	}
+
+	// Group indexes by page, keeping input order
+	var pages []string
+	byPage := make(map[string][]int)
+	for i, sample := range samples {
+		if _, ok := byPage[sample.FilePath]; !ok {
+			pages = append(pages, sample.FilePath)
+		}
+		byPage[sample.FilePath] = append(byPage[sample.FilePath], i)
+	}
+
+	// component maps each sample to the first sample of its chain
+	component := make(map[int]int)
+	var find func(i int) int
+	find = func(i int) int {
+		if parent, ok := component[i]; ok && parent != i {
+			component[i] = find(parent)
+			return component[i]
+		}
+		return i
+	}
+	union := func(a, b int) {
+		a, b = find(a), find(b)
+		if a < b {
+			component[b] = a
+		} else if b < a {
+			component[a] = b
+		}
+	}
+
+	for _, page := range pages {
+		steps := make(map[string]int)
+		for _, i := range byPage[page] {
+			if step := samples[i].Metadata[metadataStep]; step != "" {
+				if other, ok := steps[step]; ok {
+					graph.problems[i] = fmt.Sprintf("step %q is also named by the sample at line %d", step, samples[other].LineNumber)
+					continue
+				}
+				steps[step] = i
+			}
+		}
+		for _, i := range byPage[page] {
+			for _, step := range dependsOn(samples[i]) {
+				dependency, ok := steps[step]
+				if !ok {
+					graph.problems[i] = fmt.Sprintf("depends on step %q, which no sample on the page names", step)
+					continue
+				}
+				graph.dependencies[i] = append(graph.dependencies[i], dependency)
+				union(i, dependency)
+			}
+		}
+	}
+
+	// Collect each chain in input order, then order it by its dependencies
+	members := make(map[int][]int)
+	var roots []int
+	for i := range samples {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+	for _, root := range roots {
+		graph.units = append(graph.units, graph.order(samples, members[root]))
+	}
+	return graph
+}
+
+// order sorts a chain so every sample follows its dependencies, keeping
+// page order otherwise. Samples in a cycle are kept, in page order, at the
+// end of the chain and get a problem.
+func (g executionGraph) order(samples []CodeSample, chain []int) []int {
+	if len(chain) == 1 {
+		return chain
+	}
+
+	done := make(map[int]bool)
+	var ordered []int
+	for len(ordered) < len(chain) {
+		progressed := false
+		for _, i := range chain {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, dependency := range g.dependencies[i] {
+				if !done[dependency] {
+					ready = false
+				}
+			}
+			if ready {
+				done[i] = true
+				ordered = append(ordered, i)
+				progressed = true
+			}
+		}
+		if progressed {
+			continue
+		}
+
+		var cycle []string
+		for _, i := range chain {
+			if !done[i] {
+				cycle = append(cycle, stepName(samples[i]))
+			}
+		}
+		sort.Strings(cycle)
+		for _, i := range chain {
+			if !done[i] {
+				g.problems[i] = "dependency cycle through steps " + strings.Join(cycle, ", ")
+				done[i] = true
+				ordered = append(ordered, i)
+			}
+		}
+	}
+	return ordered
+}
+
+// dependencyFailure explains why a sample can't run because of its
+// dependencies' results, or returns ""
+func (g executionGraph) dependencyFailure(samples []CodeSample, results []TestResult, i int) string {
+	for _, dependency := range g.dependencies[i] {
+		if status := results[dependency].Status; status != StatusPassed {
+			return fmt.Sprintf("depends on step %q, which %s", stepName(samples[dependency]), dependencyOutcome(status))
+		}
+	}
+	return ""
+}
+
+// dependencyOutcome describes a dependency's status in a skip reason
+func dependencyOutcome(status string) string {
+	switch status {
+	case StatusSkipped:
+		return "was skipped"
+	case StatusTimeout:
+		return "timed out"
+	default:
+		return "failed"
+	}
+}
+
+// stepName names a sample in dependency messages
+func stepName(sample CodeSample) string {
+	if step := sample.Metadata[metadataStep]; step != "" {
+		return step
+	}
+	return fmt.Sprintf("line %d", sample.LineNumber)
+}
+
+// includeDependencies returns the selected samples together with
+// everything they depend on, transitively, in the order of all
+func includeDependencies(selected, all []CodeSample) []CodeSample {
+	// Steps are named per page
+	steps := make(map[string]CodeSample)
+	for _, sample := range all {
+		if step := sample.Metadata[metadataStep]; step != "" {
+			steps[sample.FilePath+"\x00"+step] = sample
+		}
+	}
+
+	included := make(map[string]bool)
+	var include func(CodeSample)
+	include = func(sample CodeSample) {
+		location := fmt.Sprintf("%s:%d", sample.FilePath, sample.LineNumber)
+		if included[location] {
+			return
+		}
+		included[location] = true
+		for _, step := range dependsOn(sample) {
+			if dependency, ok := steps[sample.FilePath+"\x00"+step]; ok {
+				include(dependency)
+			}
+		}
+	}
+	for _, sample := range selected {
+		include(sample)
+	}
+
+	var samples []CodeSample
+	for _, sample := range all {
+		if included[fmt.Sprintf("%s:%d", sample.FilePath, sample.LineNumber)] {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
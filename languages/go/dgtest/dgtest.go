@@ -0,0 +1,109 @@
+// Package dgtest runs documentation samples as subtests of a Go test, so
+// docs verification gets `go test` filtering (-run), verbose output (-v),
+// and parallelism (-parallel) without a separate CI step:
+//
+//	func TestDocsSamples(t *testing.T) {
+//		dgtest.RunSamples(t, dgtest.Config{DocsPath: "../../fern"})
+//	}
+//
+// The package is its own module, so a docs repo can require it on its own:
+//
+//	go get github.com/deepgram-devs/docs-sample-testing/languages/go/dgtest@main
+//
+// Samples are discovered and executed by the dgtest binary, which must be
+// built from languages/go (see the README) and on PATH or set in Config.
+package dgtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// Config selects the samples to run and how to run them
+type Config struct {
+	// Binary is the dgtest executable; defaults to "dgtest" on PATH
+	Binary string
+	// DocsPath is the documentation directory containing fern/pages; leave
+	// it empty to use the roots in the config's documentation.roots
+	DocsPath string
+	// ConfigPath is an optional JSON file with language and framework configuration
+	ConfigPath string
+	// Products limits the run to these products, e.g. "stt-live"
+	Products []string
+	// Live runs network-bound samples against the live API
+	Live bool
+}
+
+// sample mirrors the JSON printed by `dgtest list`
+type sample struct {
+	Name    string `json:"name"`
+	Target  string `json:"target"`
+	Product string `json:"product"`
+	Skip    string `json:"skip"`
+}
+
+// RunSamples runs every sample under config.DocsPath as a parallel subtest
+// named after its page and line, e.g. TestDocsSamples/speech_to_text_live_L42
+func RunSamples(t *testing.T, config Config) {
+	t.Helper()
+
+	output, err := config.command("list", "--format", "json").Output()
+	if err != nil {
+		t.Fatalf("dgtest list: %v%s", err, exitOutput(err))
+	}
+
+	var samples []sample
+	if err := json.Unmarshal(output, &samples); err != nil {
+		t.Fatalf("dgtest list: %v", err)
+	}
+
+	for _, s := range samples {
+		s := s
+		t.Run(s.Name, func(t *testing.T) {
+			if s.Skip != "" {
+				t.Skip(s.Skip)
+			}
+			t.Parallel()
+
+			output, err := config.command("run", "--keep-workspace=false", s.Target).CombinedOutput()
+			t.Log(strings.TrimSpace(string(output)))
+			if err != nil {
+				t.Fatalf("%s: %v", s.Target, err)
+			}
+		})
+	}
+}
+
+// command builds a dgtest invocation with the config's shared flags
+func (c Config) command(subcommand string, args ...string) *exec.Cmd {
+	binary := c.Binary
+	if binary == "" {
+		binary = "dgtest"
+	}
+
+	flags := []string{subcommand}
+	if c.DocsPath != "" {
+		flags = append(flags, "--docs-path", c.DocsPath)
+	}
+	if c.ConfigPath != "" {
+		flags = append(flags, "--config", c.ConfigPath)
+	}
+	if c.Live {
+		flags = append(flags, "--live")
+	}
+	if len(c.Products) > 0 && subcommand == "list" {
+		flags = append(flags, "--product", strings.Join(c.Products, ","))
+	}
+	return exec.Command(binary, append(flags, args...)...)
+}
+
+// exitOutput returns a failed command's stderr for error messages
+func exitOutput(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+		return "\n" + string(bytes.TrimSpace(exitErr.Stderr))
+	}
+	return ""
+}
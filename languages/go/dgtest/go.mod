@@ -0,0 +1,3 @@
+module github.com/deepgram-devs/docs-sample-testing/languages/go/dgtest
+
+go 1.18
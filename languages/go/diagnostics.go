@@ -0,0 +1,231 @@
+package main
+
+// Editor diagnostics over JSON-over-stdio
+// An editor extension sends the text of an open MDX document and gets back
+// LSP-shaped diagnostics for its Go samples, so broken snippets can be
+// underlined while writers type. Only static checks run here; nothing is
+// executed.
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Diagnostic severities, matching the LSP DiagnosticSeverity values
+const (
+	SeverityError   = 1
+	SeverityWarning = 2
+)
+
+// Position is a zero-based line and character offset, as in LSP
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range spans two positions in a document
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic is a problem found in a document
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Message  string `json:"message"`
+	Source   string `json:"source"`
+	Code     string `json:"code,omitempty"`
+}
+
+// diagnosticsRequest is one line of input to the diagnostics server
+type diagnosticsRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"params"`
+}
+
+// diagnosticsResponse is one line of output from the diagnostics server
+type diagnosticsResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// DocumentDiagnostics checks the Go samples in an MDX document's text
+func (e *GoExecutor) DocumentDiagnostics(ctx context.Context, path, text string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for _, sample := range e.extractGoSamplesFromContent(path, text) {
+		diagnostics = append(diagnostics, sampleDiagnostics(sample)...)
+
+		validation := e.ValidateSample(ctx, sample)
+		problems := e.validationProblems(sample, validation)
+		rules := make([]string, 0, len(validation))
+		for rule, passed := range validation {
+			if !passed {
+				rules = append(rules, rule)
+			}
+		}
+		sort.Strings(rules)
+
+		for _, rule := range rules {
+			message := fmt.Sprintf("Sample fails validation rule %s", rule)
+			if problem := problems[rule]; problem != "" {
+				message += ": " + problem
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    fenceRange(sample),
+				Severity: SeverityWarning,
+				Message:  message,
+				Source:   "dgtest",
+				Code:     rule,
+			})
+		}
+	}
+
+	return diagnostics
+}
+
+// sampleDiagnostics reports syntax errors in a sample at their position in the page
+func sampleDiagnostics(sample CodeSample) []Diagnostic {
+	_, _, err := parseSample(sample.Code)
+	if err == nil {
+		return nil
+	}
+
+	errors, ok := err.(scanner.ErrorList)
+	if !ok {
+		return []Diagnostic{{
+			Range:    fenceRange(sample),
+			Severity: SeverityError,
+			Message:  err.Error(),
+			Source:   "dgtest",
+		}}
+	}
+
+	// parseSample reports errors against the code with a package clause
+	// prepended when the sample had none
+	prefixLines := 0
+	if !strings.HasPrefix(sample.Code, "package") {
+		prefixLines = 2
+	}
+
+	var diagnostics []Diagnostic
+	for _, syntaxError := range errors {
+		line := sample.CodeLineNumber - 1 + syntaxError.Pos.Line - 1 - prefixLines
+		if line < sample.CodeLineNumber-1 {
+			line = sample.CodeLineNumber - 1
+		}
+		character := syntaxError.Pos.Column - 1
+		if character < 0 {
+			character = 0
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Range: Range{
+				Start: Position{Line: line, Character: character},
+				End:   Position{Line: line, Character: character + 1},
+			},
+			Severity: SeverityError,
+			Message:  syntaxError.Msg,
+			Source:   "dgtest",
+			Code:     "syntax",
+		})
+	}
+	return diagnostics
+}
+
+// fenceRange covers the opening fence line of a sample's code block
+func fenceRange(sample CodeSample) Range {
+	line := sample.LineNumber - 1
+	return Range{
+		Start: Position{Line: line, Character: 0},
+		End:   Position{Line: line, Character: len("```go")},
+	}
+}
+
+// serveDiagnostics answers newline-delimited JSON requests until the input
+// closes or a "shutdown" request arrives
+func (e *GoExecutor) serveDiagnostics(ctx context.Context, r io.Reader, w io.Writer) error {
+	input := bufio.NewScanner(r)
+	input.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	encoder := json.NewEncoder(w)
+
+	for input.Scan() {
+		line := strings.TrimSpace(input.Text())
+		if line == "" {
+			continue
+		}
+
+		var request diagnosticsRequest
+		if err := json.Unmarshal([]byte(line), &request); err != nil {
+			if err := encoder.Encode(diagnosticsResponse{Error: "invalid request: " + err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		response := diagnosticsResponse{ID: request.ID}
+		switch request.Method {
+		case "diagnostics":
+			response.Result = map[string]interface{}{
+				"uri":         request.Params.URI,
+				"diagnostics": e.DocumentDiagnostics(ctx, request.Params.URI, request.Params.Text),
+			}
+		case "shutdown":
+			return encoder.Encode(response)
+		default:
+			response.Error = "unknown method: " + request.Method
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	}
+
+	return input.Err()
+}
+
+// diagnosticsCommand serves diagnostics over stdio, or prints the
+// diagnostics for the pages given as arguments
+func diagnosticsCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("diagnostics", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, err := docs.executor()
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return executor.serveDiagnostics(ctx, os.Stdin, os.Stdout)
+	}
+
+	results := make(map[string][]Diagnostic)
+	for _, path := range flags.Args() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		results[path] = executor.DocumentDiagnostics(ctx, path, string(content))
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
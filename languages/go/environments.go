@@ -0,0 +1,153 @@
+package main
+
+// API environment targets
+// The same suite validates docs against staging before an API release
+// reaches production. Each entry in api.environments names a target with
+// its own base URL, the environment variable holding its API key for live
+// runs, extra variables for samples, and feature flags that turn products
+// off where they aren't deployed yet. A run selects one with --env.
+// Values may name environment variables, e.g. "${DEEPGRAM_STAGING_URL}",
+// which are expanded when the environment is selected.
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// apiEnvironment is a named API target
+type apiEnvironment struct {
+	Name      string
+	BaseURL   string
+	APIKeyEnv string
+	// Features maps products to whether the environment serves them;
+	// products it doesn't list are assumed available
+	Features map[string]bool
+	Env      map[string]string
+}
+
+// defaultAPIKeyEnv holds the API key samples read
+const defaultAPIKeyEnv = "DEEPGRAM_API_KEY"
+
+// apiEnvironment looks up a named environment, falling back to
+// api.default_environment when name is empty. No name and no default
+// means no environment.
+func (e *GoExecutor) apiEnvironment(name string) (*apiEnvironment, error) {
+	if name == "" {
+		name = configString(e.apiConfig(), "default_environment", "")
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	environments := configSection(e.apiConfig(), "environments")
+	section, ok := environments[name].(map[string]interface{})
+	if !ok {
+		known := make([]string, 0, len(environments))
+		for configured := range environments {
+			known = append(known, configured)
+		}
+		sort.Strings(known)
+		return nil, fmt.Errorf("unknown API environment %q (configured: %s)", name, strings.Join(known, ", "))
+	}
+
+	baseURL, err := expandEnvironmentValue(name, "base_url", configString(section, "base_url", ""))
+	if err != nil {
+		return nil, err
+	}
+	if baseURL != "" {
+		if parsed, err := url.Parse(baseURL); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("API environment %s: base_url %q isn't an absolute URL", name, baseURL)
+		}
+	}
+
+	environment := &apiEnvironment{
+		Name:      name,
+		BaseURL:   baseURL,
+		APIKeyEnv: configString(section, "api_key_env", defaultAPIKeyEnv),
+		Features:  make(map[string]bool),
+		Env:       make(map[string]string),
+	}
+	for feature := range configSection(section, "feature_flags") {
+		environment.Features[feature] = configBool(configSection(section, "feature_flags"), feature, true)
+	}
+	for key, value := range configSection(section, "env") {
+		if text, ok := value.(string); ok {
+			if environment.Env[key], err = expandEnvironmentValue(name, "env."+key, text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return environment, nil
+}
+
+// expandEnvironmentValue expands the variables in an environment's setting,
+// failing when a setting that names variables expands to nothing
+func expandEnvironmentValue(environment, key, value string) (string, error) {
+	expanded := os.ExpandEnv(value)
+	if value != "" && strings.TrimSpace(expanded) == "" {
+		return "", fmt.Errorf("API environment %s: %s %q is empty; set the variables it names", environment, key, value)
+	}
+	return expanded, nil
+}
+
+// useEnvironment points the executor at an environment
+func (e *GoExecutor) useEnvironment(environment *apiEnvironment) {
+	e.Environment = environment
+	if environment != nil && environment.BaseURL != "" {
+		e.BaseURL = environment.BaseURL
+	}
+}
+
+// environmentEnv returns the variables a sample needs for the selected
+// environment: its extra variables and, on live runs, its API key under
+// the name samples read
+func (e *GoExecutor) environmentEnv() []string {
+	if e.Environment == nil {
+		return nil
+	}
+
+	var env []string
+	for _, key := range sortedStringKeys(e.Environment.Env) {
+		env = append(env, key+"="+e.Environment.Env[key])
+	}
+	if e.Live && e.Environment.APIKeyEnv != defaultAPIKeyEnv {
+		env = append(env, defaultAPIKeyEnv+"="+os.Getenv(e.Environment.APIKeyEnv))
+	}
+	return env
+}
+
+// checkEnvironmentKey fails a live run early when the selected
+// environment's API key isn't set
+func (e *GoExecutor) checkEnvironmentKey() error {
+	if e.Environment == nil || !e.Live {
+		return nil
+	}
+	if os.Getenv(e.Environment.APIKeyEnv) == "" {
+		return fmt.Errorf("--live against %s needs %s", e.Environment.Name, e.Environment.APIKeyEnv)
+	}
+	return nil
+}
+
+// disabledInEnvironment reports why a sample's product isn't served by the
+// selected environment, or "" when it is
+func (e *GoExecutor) disabledInEnvironment(sample CodeSample) string {
+	if e.Environment == nil {
+		return ""
+	}
+	if enabled, ok := e.Environment.Features[sampleProduct(sample)]; ok && !enabled {
+		return fmt.Sprintf("%s is disabled in the %s environment", sampleProduct(sample), e.Environment.Name)
+	}
+	return ""
+}
+
+func sortedStringKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
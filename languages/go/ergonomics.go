@@ -0,0 +1,243 @@
+package main
+
+// Copy-paste ergonomics
+// A sample can run and still be hard to use: lines too wide for the code
+// block scroll sideways, long samples are hard to scan, and placeholders
+// like "YOUR_PROJECT_ID" or an elided "..." mean the code doesn't work as
+// pasted. `dgtest ergonomics` measures every sample's rendered width, its
+// length in lines and Go tokens, and its placeholders, and flags samples
+// beyond the thresholds under ergonomics in the language config.
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Ergonomics measures how easily a sample is read and pasted
+type Ergonomics struct {
+	// Width is the widest line in columns, with tabs expanded
+	Width  int `json:"width"`
+	Lines  int `json:"lines"`
+	Tokens int `json:"tokens"`
+	// Placeholders are the values a reader must replace before the code runs
+	Placeholders []string `json:"placeholders,omitempty"`
+}
+
+// ErgonomicsEntry is one sample's measurements and the thresholds it exceeds
+type ErgonomicsEntry struct {
+	Sample     CodeSample `json:"sample"`
+	Ergonomics Ergonomics `json:"ergonomics"`
+	Problems   []string   `json:"problems,omitempty"`
+}
+
+// ErgonomicsReport measures every sample
+type ErgonomicsReport struct {
+	Samples []ErgonomicsEntry `json:"samples"`
+	Flagged int               `json:"flagged"`
+}
+
+// ergonomicsThresholds are the limits samples are flagged beyond
+type ergonomicsThresholds struct {
+	tabWidth        int
+	maxWidth        int
+	maxLines        int
+	maxTokens       int
+	maxPlaceholders int
+	placeholders    []*regexp.Regexp
+}
+
+// defaultPlaceholderPatterns match values readers must fill in: SCREAMING
+// "YOUR_..." names, <angle-bracketed> values in strings, and lines eliding
+// code with "..."
+var defaultPlaceholderPatterns = []string{
+	`\bYOUR_[A-Z0-9_]+\b`,
+	`"<[^"<>]+>"`,
+	`(?m)^\s*(?://\s*)?(?:\.\.\.|…)\s*$`,
+}
+
+// ergonomicsThresholds reads ergonomics from the language config
+func (e *GoExecutor) ergonomicsThresholds() (ergonomicsThresholds, error) {
+	config := configSection(e.LanguageConfig, "ergonomics")
+	thresholds := ergonomicsThresholds{
+		tabWidth:        configInt(config, "tab_width", 4),
+		maxWidth:        configInt(config, "max_width", 100),
+		maxLines:        configInt(config, "max_lines", 60),
+		maxTokens:       configInt(config, "max_tokens", 600),
+		maxPlaceholders: configInt(config, "max_placeholders", 1),
+	}
+
+	patterns := configStrings(config, "placeholder_patterns")
+	if len(patterns) == 0 {
+		patterns = defaultPlaceholderPatterns
+	}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return thresholds, fmt.Errorf("ergonomics.placeholder_patterns: %v", err)
+		}
+		thresholds.placeholders = append(thresholds.placeholders, compiled)
+	}
+	return thresholds, nil
+}
+
+// measureErgonomics measures a sample's code as it is shown
+func measureErgonomics(code string, thresholds ergonomicsThresholds) Ergonomics {
+	lines := strings.Split(code, "\n")
+	ergonomics := Ergonomics{Lines: len(lines)}
+	for _, line := range lines {
+		if width := renderedWidth(line, thresholds.tabWidth); width > ergonomics.Width {
+			ergonomics.Width = width
+		}
+	}
+
+	// Tokens as the Go scanner sees them, without the semicolons it inserts
+	var s scanner.Scanner
+	file := token.NewFileSet().AddFile("main.go", -1, len(code))
+	s.Init(file, []byte(code), func(token.Position, string) {}, 0)
+	for {
+		_, tok, literal := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.SEMICOLON || literal == ";" {
+			ergonomics.Tokens++
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, pattern := range thresholds.placeholders {
+		for _, match := range pattern.FindAllString(code, -1) {
+			match = strings.TrimSpace(match)
+			if !seen[match] {
+				seen[match] = true
+				ergonomics.Placeholders = append(ergonomics.Placeholders, match)
+			}
+		}
+	}
+	return ergonomics
+}
+
+// renderedWidth counts a line's columns with tabs expanded to tab stops
+func renderedWidth(line string, tabWidth int) int {
+	width := 0
+	for _, r := range line {
+		if r == '\t' && tabWidth > 0 {
+			width += tabWidth - width%tabWidth
+			continue
+		}
+		width++
+	}
+	return width
+}
+
+// problems lists the thresholds a sample's measurements exceed
+func (t ergonomicsThresholds) problems(ergonomics Ergonomics) []string {
+	var problems []string
+	if ergonomics.Width > t.maxWidth {
+		problems = append(problems, fmt.Sprintf("%d columns wide (max %d)", ergonomics.Width, t.maxWidth))
+	}
+	if ergonomics.Lines > t.maxLines {
+		problems = append(problems, fmt.Sprintf("%d lines (max %d)", ergonomics.Lines, t.maxLines))
+	}
+	if ergonomics.Tokens > t.maxTokens {
+		problems = append(problems, fmt.Sprintf("%d tokens (max %d)", ergonomics.Tokens, t.maxTokens))
+	}
+	if len(ergonomics.Placeholders) > t.maxPlaceholders {
+		problems = append(problems, fmt.Sprintf("%d placeholders to replace (max %d): %s",
+			len(ergonomics.Placeholders), t.maxPlaceholders, strings.Join(ergonomics.Placeholders, ", ")))
+	}
+	return problems
+}
+
+// ErgonomicsReport measures every sample, flagged samples first
+func (e *GoExecutor) ErgonomicsReport(samples []CodeSample) (ErgonomicsReport, error) {
+	thresholds, err := e.ergonomicsThresholds()
+	if err != nil {
+		return ErgonomicsReport{}, err
+	}
+
+	report := ErgonomicsReport{Samples: []ErgonomicsEntry{}}
+	for _, sample := range samples {
+		entry := ErgonomicsEntry{Sample: sample, Ergonomics: measureErgonomics(sample.Code, thresholds)}
+		entry.Problems = thresholds.problems(entry.Ergonomics)
+		if len(entry.Problems) > 0 {
+			report.Flagged++
+		}
+		report.Samples = append(report.Samples, entry)
+	}
+	sort.SliceStable(report.Samples, func(i, j int) bool {
+		return len(report.Samples[i].Problems) > len(report.Samples[j].Problems)
+	})
+	return report, nil
+}
+
+// writeErgonomicsReport renders the report as plain text, JSON, or Markdown;
+// text formats list only flagged samples
+func writeErgonomicsReport(w io.Writer, format string, report ErgonomicsReport) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatPlain:
+		for _, entry := range report.Samples {
+			if len(entry.Problems) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "⚠️  %s:%d %s\n", entry.Sample.FilePath, entry.Sample.LineNumber, strings.Join(entry.Problems, "; "))
+		}
+		if report.Flagged == 0 {
+			fmt.Fprintf(w, "✅ No ergonomics problems in %d samples\n", len(report.Samples))
+		} else {
+			fmt.Fprintf(w, "📐 %d of %d samples exceed ergonomics thresholds\n", report.Flagged, len(report.Samples))
+		}
+		return nil
+	case FormatMarkdown:
+		fmt.Fprintf(w, "## Sample ergonomics\n\n%d of %d samples exceed ergonomics thresholds.\n", report.Flagged, len(report.Samples))
+		if report.Flagged == 0 {
+			return nil
+		}
+		fmt.Fprintf(w, "\n| Sample | Width | Lines | Tokens | Problems |\n|--------|-------|-------|--------|----------|\n")
+		for _, entry := range report.Samples {
+			if len(entry.Problems) == 0 {
+				continue
+			}
+			ergonomics := entry.Ergonomics
+			fmt.Fprintf(w, "| `%s:%d` | %d | %d | %d | %s |\n", entry.Sample.Page, entry.Sample.LineNumber,
+				ergonomics.Width, ergonomics.Lines, ergonomics.Tokens, strings.ReplaceAll(strings.Join(entry.Problems, "; "), "|", "\\|"))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown ergonomics format: %s", format)
+	}
+}
+
+// ergonomicsCommand reports every sample's width, length, and placeholders,
+// flagging those beyond the ergonomics thresholds
+func ergonomicsCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("ergonomics", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain, json, or markdown")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+	report, err := executor.ErgonomicsReport(samples)
+	if err != nil {
+		return err
+	}
+	return writeErgonomicsReport(os.Stdout, *format, report)
+}
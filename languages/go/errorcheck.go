@@ -0,0 +1,391 @@
+package main
+
+// Documented error messages
+// Troubleshooting pages show a sample followed by the error it prints, and
+// those strings drift as the SDK's error handling changes. A sample whose
+// next code block is plain-text output mentioning an error is run against
+// the mock with the fault that produces it, and the documented lines are
+// looked for in what the sample actually printed, with "..." in the docs
+// matching anything.
+//
+// The fault is inferred from the documented text (a 429 means rate_limit,
+// a 401 means unauthorized, and so on) or named on the sample's fence:
+//
+//	```go fault=rate_limit
+//
+// Samples documenting a 403 or an expired key are instead run once under
+// every key tier the mock simulates (or named with tier=member): tiers that
+// reject the sample the documented way must make it print the documented
+// error, and the others must not.
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrorCheck is the outcome of checking one sample's documented error
+type ErrorCheck struct {
+	Sample   CodeSample `json:"sample"`
+	Fault    string     `json:"fault,omitempty"`
+	Tier     string     `json:"tier,omitempty"`
+	Expected string     `json:"expected"`
+	Actual   string     `json:"actual"`
+	// Missing lists documented lines the sample didn't print
+	Missing []string `json:"missing,omitempty"`
+	// Absent is set when the tier accepts the sample or rejects it
+	// differently, so the documented error shouldn't be printed
+	Absent bool `json:"absent,omitempty"`
+	// Unexpected is set when the sample printed its documented error anyway
+	Unexpected bool `json:"unexpected,omitempty"`
+	// Problem explains why the check couldn't run, e.g. a build failure
+	Problem string `json:"problem,omitempty"`
+}
+
+// Matched reports whether the sample printed every documented line, or
+// for an Absent check, didn't print them all
+func (c ErrorCheck) Matched() bool {
+	return c.Problem == "" && len(c.Missing) == 0 && !c.Unexpected
+}
+
+// Condition names what the mock did to the sample: the fault it injected
+// or the key tier it simulated
+func (c ErrorCheck) Condition() string {
+	if c.Tier != "" {
+		return "tier " + c.Tier
+	}
+	return c.Fault
+}
+
+// Metadata keys set on samples that document an error
+const (
+	metadataExpectedError = "expected_error"
+	metadataFault         = "fault"
+	metadataTier          = "tier"
+)
+
+// maxLeadInLines is how much prose may separate a sample from its output block
+const maxLeadInLines = 3
+
+var (
+	// outputBlockRegex matches a plain-text code block at the start of a text
+	outputBlockRegex = regexp.MustCompile("^```(text|plaintext|console|log|output)?[ \\t]*[^\\n]*\\n((?s).*?)```")
+	fenceFaultRegex  = regexp.MustCompile(`\bfault=["']?(\w+)`)
+	fenceTierRegex   = regexp.MustCompile(`\btier=["']?(\w+)`)
+	whitespaceRegex  = regexp.MustCompile(`\s+`)
+)
+
+// faultSignals infers the fault that produces a documented error, checked in order
+var faultSignals = []struct {
+	fault   string
+	signals []string
+}{
+	{FaultRateLimit, []string{"429", "too many requests", "rate limit"}},
+	{FaultUnauthorized, []string{"401", "invalid_auth", "invalid credentials", "unauthorized"}},
+	{FaultServerError, []string{"500", "502", "503", "internal server error", "bad gateway", "service unavailable"}},
+	{FaultMalformedJSON, []string{"invalid character", "unexpected end of json", "cannot unmarshal"}},
+	{FaultDisconnect, []string{"unexpected eof", "connection reset", "broken pipe"}},
+}
+
+// tierSignals infers the status a documented key-permission error comes
+// with; these are checked before faultSignals, as expired keys get a 401 too
+var tierSignals = []struct {
+	status  int
+	signals []string
+}{
+	{http.StatusUnauthorized, []string{"expired"}},
+	{http.StatusForbidden, []string{"403", "forbidden", "insufficient permissions", "missing scope"}},
+}
+
+// documentedError records the error a sample's page says it prints, from
+// the plain-text block following it, and the fault or tier its fence names
+func documentedError(sample *CodeSample, fenceMeta, following string) {
+	if match := fenceFaultRegex.FindStringSubmatch(fenceMeta); match != nil {
+		sample.Metadata[metadataFault] = match[1]
+	}
+	if match := fenceTierRegex.FindStringSubmatch(fenceMeta); match != nil {
+		sample.Metadata[metadataTier] = match[1]
+	}
+
+	match := outputBlockRegex.FindStringSubmatch(outputBlockStart(following))
+	if match == nil {
+		return
+	}
+	output := strings.TrimSpace(match[2])
+	if !strings.Contains(strings.ToLower(output), "error") && sample.Metadata[metadataFault] == "" && sample.Metadata[metadataTier] == "" {
+		return
+	}
+	sample.Metadata[metadataExpectedError] = output
+}
+
+// outputBlockStart skips the blank lines and short lead-in prose, such as
+// "the sample prints:", between a sample and the block that follows it.
+// Headings, components, and other code blocks end the search.
+func outputBlockStart(following string) string {
+	prose := 0
+	for following != "" {
+		line := following
+		if end := strings.IndexByte(following, '\n'); end >= 0 {
+			line = following[:end]
+		}
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			return strings.TrimLeft(following, " \t")
+		case strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "<"):
+			return ""
+		case trimmed != "":
+			prose++
+			if prose > maxLeadInLines {
+				return ""
+			}
+		}
+
+		if len(line) == len(following) {
+			break
+		}
+		following = following[len(line)+1:]
+	}
+	return ""
+}
+
+// inferFault picks the mock fault that should make a sample print its
+// documented error
+func inferFault(sample CodeSample) string {
+	if fault := sample.Metadata[metadataFault]; fault != "" {
+		return fault
+	}
+	expected := strings.ToLower(sample.Metadata[metadataExpectedError])
+	for _, candidate := range faultSignals {
+		for _, signal := range candidate.signals {
+			if strings.Contains(expected, signal) {
+				return candidate.fault
+			}
+		}
+	}
+	return ""
+}
+
+// documentedAuthStatus returns the status of the key-permission error a
+// sample documents, from the tier on its fence or the documented text, or
+// 0 when it documents some other error
+func documentedAuthStatus(sample CodeSample, tiers map[string]KeyTier) (int, error) {
+	if name := sample.Metadata[metadataTier]; name != "" {
+		tier, ok := tiers[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown tier %q on the fence (expected %s)", name, strings.Join(tierNames(tiers), ", "))
+		}
+		status := tier.status(sample.RequiredScopes)
+		if status == http.StatusOK {
+			return 0, fmt.Errorf("tier %s on the fence has every scope the sample needs (%s)", name, strings.Join(sample.RequiredScopes, ", "))
+		}
+		return status, nil
+	}
+	if sample.Metadata[metadataFault] != "" {
+		return 0, nil
+	}
+
+	expected := strings.ToLower(sample.Metadata[metadataExpectedError])
+	for _, candidate := range tierSignals {
+		for _, signal := range candidate.signals {
+			if strings.Contains(expected, signal) {
+				return candidate.status, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// CheckDocumentedErrors runs every sample that documents an error against
+// the mock with the matching fault and compares what it printed
+func (e *GoExecutor) CheckDocumentedErrors(ctx context.Context, samples []CodeSample) ([]ErrorCheck, error) {
+	rules, err := e.redactionRules()
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []ErrorCheck
+	tiers := e.keyTiers()
+	byFault := make(map[string][]CodeSample)
+	var authSamples []CodeSample
+	// authStatus is the documented status of each auth sample, by page:line
+	authStatus := make(map[string]int)
+	for _, sample := range samples {
+		expected, ok := sample.Metadata[metadataExpectedError]
+		if !ok {
+			continue
+		}
+		status, err := documentedAuthStatus(sample, tiers)
+		if err != nil {
+			checks = append(checks, ErrorCheck{Sample: sample, Tier: sample.Metadata[metadataTier], Expected: expected, Problem: err.Error()})
+			continue
+		}
+		if status != 0 {
+			if !rejectedByAnyTier(tiers, sample.RequiredScopes, status) {
+				checks = append(checks, ErrorCheck{Sample: sample, Expected: expected,
+					Problem: fmt.Sprintf("no key tier answers this sample with a %d; add one to mocking.tiers", status)})
+				continue
+			}
+			authSamples = append(authSamples, sample)
+			authStatus[fmt.Sprintf("%s:%d", sample.FilePath, sample.LineNumber)] = status
+			continue
+		}
+		fault := inferFault(sample)
+		if fault == "" {
+			checks = append(checks, ErrorCheck{Sample: sample, Expected: expected,
+				Problem: "can't tell which fault produces this error; name it on the fence, e.g. ```go fault=rate_limit"})
+			continue
+		}
+		if !faultKinds[fault] {
+			checks = append(checks, ErrorCheck{Sample: sample, Fault: fault, Expected: expected,
+				Problem: fmt.Sprintf("unknown fault %q on the fence (expected %s)", fault, strings.Join(sortedKeys(faultKinds), ", "))})
+			continue
+		}
+		byFault[fault] = append(byFault[fault], sample)
+	}
+
+	for _, fault := range sortedKeys(faultSet(byFault)) {
+		faults, err := NewMockFaults(fault, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		faults.Status = documentedStatus(byFault[fault])
+		results, err := e.runOnMock(ctx, byFault[fault], faults, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			checks = append(checks, compareDocumentedError(result, fault, rules))
+		}
+	}
+
+	if len(authSamples) == 0 {
+		return checks, nil
+	}
+	for _, name := range tierNames(tiers) {
+		tier := tiers[name]
+		results, err := e.runOnMock(ctx, authSamples, nil, &tier)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			check := compareDocumentedError(result, "", rules)
+			check.Tier = name
+			if check.Problem == "" && tier.status(result.Sample.RequiredScopes) != authStatus[fmt.Sprintf("%s:%d", result.Sample.FilePath, result.Sample.LineNumber)] {
+				check.Absent = true
+				check.Unexpected = len(check.Missing) == 0
+				check.Missing = nil
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// rejectedByAnyTier reports whether some tier answers a sample needing
+// scopes with status
+func rejectedByAnyTier(tiers map[string]KeyTier, scopes []string, status int) bool {
+	for _, tier := range tiers {
+		if tier.status(scopes) == status {
+			return true
+		}
+	}
+	return false
+}
+
+// runOnMock runs samples against a mock server injecting faults and
+// simulating a key tier, restoring the executor's target afterwards
+func (e *GoExecutor) runOnMock(ctx context.Context, samples []CodeSample, faults *MockFaults, tier *KeyTier) ([]TestResult, error) {
+	agent, err := e.mockAgent()
+	if err != nil {
+		return nil, err
+	}
+	mock, err := StartMockServer("127.0.0.1:0", e.mockTLSEnabled(), faults, tier, agent)
+	if err != nil {
+		return nil, fmt.Errorf("starting mock server: %v", err)
+	}
+	previousMock, previousURL := e.Mock, e.BaseURL
+	e.Mock, e.BaseURL = mock, mock.URL()
+
+	results, _ := e.RunSamples(ctx, samples)
+	mock.Close()
+	e.Mock, e.BaseURL = previousMock, previousURL
+	return results, nil
+}
+
+// compareDocumentedError looks for each documented line in the sample's
+// output, ignoring redacted values and whitespace differences
+func compareDocumentedError(result TestResult, fault string, rules []redactionRule) ErrorCheck {
+	check := ErrorCheck{
+		Sample:   result.Sample,
+		Fault:    fault,
+		Expected: result.Sample.Metadata[metadataExpectedError],
+		Actual:   strings.TrimSpace(strings.TrimSpace(result.Stdout) + "\n" + strings.TrimSpace(result.Stderr)),
+	}
+
+	switch result.ErrorCategory {
+	case ErrorCategorySetup, ErrorCategoryDependency, ErrorCategoryBuild:
+		check.Problem = fmt.Sprintf("sample didn't run (%s): %s", result.ErrorCategory, result.ErrorMessage)
+		return check
+	}
+	if result.Status == StatusSkipped {
+		check.Problem = "sample was skipped: " + result.ErrorMessage
+		return check
+	}
+
+	actual := normalizeOutput(redact(check.Actual, rules))
+	for _, line := range strings.Split(check.Expected, "\n") {
+		if normalized := normalizeOutput(redact(line, rules)); normalized != "" && !containsElided(actual, normalized) {
+			check.Missing = append(check.Missing, strings.TrimSpace(line))
+		}
+	}
+	return check
+}
+
+// documentedStatus returns the 5xx status the samples' pages show, so a
+// page documenting a 502 gets a 502; mixed or missing statuses return 0
+func documentedStatus(samples []CodeSample) int {
+	status := 0
+	for _, sample := range samples {
+		for _, candidate := range serverErrorStatuses {
+			if !strings.Contains(sample.Metadata[metadataExpectedError], strconv.Itoa(candidate)) {
+				continue
+			}
+			if status != 0 && status != candidate {
+				return 0
+			}
+			status = candidate
+		}
+	}
+	return status
+}
+
+// containsElided reports whether text contains line, where "..." in line
+// stands for anything, as docs often elide IDs and long payloads
+func containsElided(text, line string) bool {
+	for _, part := range strings.Split(strings.ReplaceAll(line, "…", "..."), "...") {
+		part = strings.TrimSpace(part)
+		i := strings.Index(text, part)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(part):]
+	}
+	return true
+}
+
+func normalizeOutput(text string) string {
+	return strings.TrimSpace(whitespaceRegex.ReplaceAllString(text, " "))
+}
+
+func faultSet(byFault map[string][]CodeSample) map[string]bool {
+	set := make(map[string]bool, len(byFault))
+	for fault := range byFault {
+		set[fault] = true
+	}
+	return set
+}
@@ -4,12 +4,13 @@ package main
 // Example implementation showing how Go SDK testing would integrate
 
 import (
+	"context"
 	"fmt"
-	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"time"
 )
@@ -19,107 +20,253 @@ type GoExecutor struct {
 	LanguageConfig  map[string]interface{}
 	FrameworkConfig map[string]interface{}
 	SDKPath         string
+	// Live allows network-bound samples to run against the real API using
+	// DEEPGRAM_API_KEY from the environment; otherwise they are skipped
+	Live bool
+	// KeepWorkspace leaves each sample's temp directory in place for inspection
+	KeepWorkspace bool
+	// CoverDir, when set, collects SDK coverage counters from every sample
+	CoverDir string
+	// BaseURL, when set, points samples at another API host such as a mock
+	// server or staging
+	BaseURL string
+	// Environment, when set, is the named API target samples run against
+	Environment *apiEnvironment
+	// Mock, when running, answers network-bound samples instead of the live API
+	Mock *MockServer
+	// GoldenDir holds expected outputs; UpdateGolden rewrites them from this run
+	GoldenDir    string
+	UpdateGolden bool
+	// SDKRelease, when set, builds samples against this SDK release instead
+	// of the local checkout
+	SDKRelease string
+	// Audit, when set, logs every command the executor spawns
+	Audit *AuditLog
+
+	// transformers are every code transformer in pipeline order, built
+	// from execution.transformers once
+	transformers []codeTransformer
+	sharedModule *moduleFiles
+	workspaces   *workspacePool
 }
 
 // CodeSample represents a Go code sample extracted from documentation
 type CodeSample struct {
-	FilePath          string            `json:"file_path"`
+	// ID identifies the sample across runs even as its line number shifts
+	ID       string `json:"id"`
+	FilePath string `json:"file_path"`
+	// Page is FilePath relative to its docs root, prefixed with the root's
+	// name when the run has several
+	Page              string            `json:"page"`
 	LineNumber        int               `json:"line_number"`
+	EndLineNumber     int               `json:"end_line_number"`
+	CodeLineNumber    int               `json:"code_line_number"`
 	Code              string            `json:"code"`
 	Language          string            `json:"language"`
 	SampleType        string            `json:"sample_type"`
+	Product           string            `json:"product"`
+	Feature           string            `json:"feature,omitempty"`
 	Imports           []string          `json:"imports"`
 	RequiresAPIKey    bool              `json:"requires_api_key"`
 	RequiresAudioFile bool              `json:"requires_audio_file"`
+	RequiresNetwork   bool              `json:"requires_network"`
+	RequiredScopes    []string          `json:"required_scopes"`
+	Complexity        Complexity        `json:"complexity"`
 	Metadata          map[string]string `json:"metadata"`
 }
 
+// Sample statuses reported in TestResult.Status
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusTimeout = "timeout"
+	StatusSkipped = "skipped"
+)
+
+// Error categories reported in TestResult.ErrorCategory for failed samples
+const (
+	ErrorCategorySetup      = "setup"
+	ErrorCategoryDependency = "dependency"
+	ErrorCategoryBuild      = "build"
+	ErrorCategoryRuntime    = "runtime"
+	ErrorCategoryOutput     = "output"
+)
+
 // TestResult represents the result of testing a Go sample
 type TestResult struct {
-	Sample            CodeSample      `json:"sample"`
-	Success           bool            `json:"success"`
-	ExecutionTime     float64         `json:"execution_time"`
+	Sample        CodeSample `json:"sample"`
+	Success       bool       `json:"success"`
+	Status        string     `json:"status"`
+	ErrorCategory string     `json:"error_category,omitempty"`
+	ExecutionTime float64    `json:"execution_time"`
+	TimeoutLimit  float64    `json:"timeout_limit,omitempty"`
+	Attempts      int        `json:"attempts,omitempty"`
+	BuildTime     float64    `json:"build_time"`
+	BinarySize    int64      `json:"binary_size"`
+	GoldenDiff    string     `json:"golden_diff,omitempty"`
+	// GoldenMissing is set when --golden found no golden file for the sample
+	GoldenMissing     bool            `json:"golden_missing,omitempty"`
 	Stdout            string          `json:"stdout"`
 	Stderr            string          `json:"stderr"`
 	ErrorMessage      string          `json:"error_message"`
 	ValidationResults map[string]bool `json:"validation_results"`
+	// ValidationProblems explains failed rules that can say what's wrong
+	ValidationProblems map[string]string `json:"validation_problems,omitempty"`
+	// Captured holds the values the sample's fence captures from its output
+	Captured map[string]string `json:"captured,omitempty"`
+	// Quarantine is set when a failure of this sample doesn't fail the run
+	Quarantine    *Quarantine   `json:"quarantine,omitempty"`
+	ResourceUsage ResourceUsage `json:"resource_usage"`
+	WorkDir       string        `json:"work_dir,omitempty"`
 }
 
-// NewGoExecutor creates a new Go executor
-func NewGoExecutor(langConfig, frameworkConfig map[string]interface{}) *GoExecutor {
+// NewGoExecutor creates a new Go executor, checking its code transformers
+func NewGoExecutor(langConfig, frameworkConfig map[string]interface{}) (*GoExecutor, error) {
 	sdkConfig := langConfig["sdk"].(map[string]interface{})
 	repoPath := sdkConfig["repository_path"].(string)
 	sourcePath := sdkConfig["source_path"].(string)
 
-	return &GoExecutor{
+	executor := &GoExecutor{
 		LanguageConfig:  langConfig,
 		FrameworkConfig: frameworkConfig,
 		SDKPath:         filepath.Join(repoPath, sourcePath),
+		BaseURL:         configString(configSection(frameworkConfig, "api"), "base_url", ""),
+	}
+	transformers, err := executor.codeTransformers()
+	if err != nil {
+		return nil, err
 	}
+	executor.transformers = transformers
+	return executor, nil
 }
 
-// ExtractSamples finds and extracts Go code samples from documentation
-func (e *GoExecutor) ExtractSamples(documentationPath string) ([]CodeSample, error) {
-	var samples []CodeSample
+// ExtractSamples finds and extracts Go code samples from documentation,
+// stopping early if ctx is cancelled
+func (e *GoExecutor) ExtractSamples(ctx context.Context, documentationPath string) ([]CodeSample, error) {
+	roots, err := e.docsRoots(documentationPath)
+	if err != nil {
+		return nil, err
+	}
+	return e.extractRoot(ctx, roots[0])
+}
 
-	pagesPath := filepath.Join(documentationPath, "fern", "pages")
+// extractRoot walks a docs root's pages and extracts their samples
+func (e *GoExecutor) extractRoot(ctx context.Context, root docsRoot) ([]CodeSample, error) {
+	var samples []CodeSample
 
-	err := filepath.WalkDir(pagesPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+	index := e.loadExtractionIndex(root.Path)
+	err := walkPages(ctx, root, func(path string, content []byte) error {
+		fileSamples, key, cached := index.samples(path, content)
+		if !cached {
+			fileSamples = e.extractGoSamplesFromContent(path, string(content))
+			index.store(path, key, fileSamples)
 		}
-
-		if !strings.HasSuffix(path, ".mdx") {
-			return nil
+		page := make([]CodeSample, len(fileSamples))
+		for i, sample := range fileSamples {
+			sample.Page = root.pageName(path)
+			page[i] = sample
 		}
+		assignSampleIDs(page)
+		samples = append(samples, page...)
+		return nil
+	})
+	if err != nil {
+		return samples, err
+	}
 
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
+	// The cache only ever saves work, so failing to write it isn't an error
+	index.save()
+	return samples, nil
+}
 
-		fileSamples := e.extractGoSamplesFromContent(path, string(content))
-		samples = append(samples, fileSamples...)
+// ExtractSamplesFromFile extracts the Go code samples from a single MDX page
+func (e *GoExecutor) ExtractSamplesFromFile(path string) ([]CodeSample, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	samples := e.extractGoSamplesFromContent(path, string(content))
+	for i := range samples {
+		samples[i].Page = pagePath(path)
+	}
+	assignSampleIDs(samples)
+	return samples, nil
+}
 
-		return nil
-	})
+// extractRootPage extracts one page of a root, naming its samples as a
+// walk of the root does
+func (e *GoExecutor) extractRootPage(root docsRoot, path string) ([]CodeSample, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	samples := e.extractGoSamplesFromContent(path, string(content))
+	for i := range samples {
+		samples[i].Page = root.pageName(path)
+	}
+	assignSampleIDs(samples)
+	return samples, nil
+}
+
+// SampleAt returns the sample whose code block contains the given line of a page
+func (e *GoExecutor) SampleAt(path string, line int) (CodeSample, error) {
+	samples, err := e.ExtractSamplesFromFile(path)
+	if err != nil {
+		return CodeSample{}, err
+	}
 
-	return samples, err
+	for _, sample := range samples {
+		if line >= sample.LineNumber && line <= sample.EndLineNumber {
+			return sample, nil
+		}
+	}
+	return CodeSample{}, fmt.Errorf("no Go sample contains %s:%d", path, line)
 }
 
 func (e *GoExecutor) extractGoSamplesFromContent(filePath, content string) []CodeSample {
 	var samples []CodeSample
 
+	// Pages checked out on Windows may have CRLF line endings
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
 	// Regex to find Go code blocks
-	codeBlockRegex := regexp.MustCompile("```go[^\n]*\n(.*?)```")
-	matches := codeBlockRegex.FindAllStringSubmatch(content, -1)
+	codeBlockRegex := regexp.MustCompile("(?s)```go([^\n]*)\n(.*?)```")
+	matches := codeBlockRegex.FindAllStringSubmatchIndex(content, -1)
 
 	for _, match := range matches {
-		if len(match) < 2 {
+		if len(match) < 6 {
 			continue
 		}
 
-		code := strings.TrimSpace(match[1])
+		rawCode := content[match[4]:match[5]]
+		code := strings.TrimSpace(rawCode)
 
 		// Skip if too short or not Go SDK related
 		if len(code) < 30 || !strings.Contains(code, "deepgram") {
 			continue
 		}
 
-		// Calculate line number (simplified)
-		lineNumber := strings.Count(content[:strings.Index(content, match[0])], "\n") + 1
+		// Lines of the opening and closing fences
+		lineNumber := strings.Count(content[:match[0]], "\n") + 1
+		endLineNumber := lineNumber + strings.Count(content[match[0]:match[1]], "\n")
+		codeLineNumber := lineNumber + 1 + strings.Count(rawCode[:strings.Index(rawCode, code)], "\n")
 
 		sample := CodeSample{
-			FilePath:          filePath,
-			LineNumber:        lineNumber,
-			Code:              code,
-			Language:          "go",
-			SampleType:        e.determineSampleType(code),
-			Imports:           e.extractImports(code),
-			RequiresAPIKey:    e.requiresAPIKey(code),
-			RequiresAudioFile: e.requiresAudioFile(code),
-			Metadata:          make(map[string]string),
+			FilePath:       filePath,
+			LineNumber:     lineNumber,
+			EndLineNumber:  endLineNumber,
+			CodeLineNumber: codeLineNumber,
+			Code:           code,
+			Language:       "go",
+			SampleType:     e.determineSampleType(code),
+			Metadata:       make(map[string]string),
 		}
+		e.classifySample(&sample)
+		documentedError(&sample, content[match[2]:match[3]], content[match[1]:])
+		sampleDependencies(&sample, content[match[2]:match[3]])
+		sampleCaptures(&sample, content[match[2]:match[3]])
+		sampleTargetOS(&sample, content[match[2]:match[3]])
+		sampleAssertions(&sample, content[match[2]:match[3]])
 
 		samples = append(samples, sample)
 	}
@@ -127,6 +274,40 @@ func (e *GoExecutor) extractGoSamplesFromContent(filePath, content string) []Cod
 	return samples
 }
 
+// classifySample fills in the sample's imports and runtime requirements from
+// its AST, falling back to text heuristics for snippets that don't parse
+func (e *GoExecutor) classifySample(sample *CodeSample) {
+	analysis := analyzeSample(sample.Code)
+	if !analysis.Parsed {
+		sample.Imports = e.extractImports(sample.Code)
+		sample.RequiresAPIKey = e.requiresAPIKey(sample.Code)
+		sample.RequiresAudioFile = e.requiresAudioFile(sample.Code)
+		sample.RequiresNetwork = sample.RequiresAPIKey
+		sample.Product, sample.Feature = classifyProduct(sample.Imports, nil)
+		sample.RequiredScopes = e.requiredScopes(sample.Product, nil, nil)
+		sample.Complexity = measureComplexity(sample.Code, sample.Imports, nil)
+		sample.Metadata["classification"] = "heuristic"
+		return
+	}
+
+	sample.Imports = analysis.Imports
+	sample.RequiresAPIKey = analysis.RequiresAPIKey()
+	sample.RequiresAudioFile = analysis.RequiresAudioFile()
+	sample.RequiresNetwork = analysis.NetworkBound()
+	sample.Product, sample.Feature = classifyProduct(analysis.Imports, analysis.ClientConstructors)
+	if sample.RequiresNetwork {
+		sample.RequiredScopes = e.requiredScopes(sample.Product, analysis.MethodCalls, analysis.URLs)
+	}
+	sample.Complexity = measureComplexity(sample.Code, analysis.Imports, analysis.File)
+	sample.Metadata["classification"] = "ast"
+	if len(analysis.ClientConstructors) > 0 {
+		sample.Metadata["client_constructors"] = strings.Join(analysis.ClientConstructors, ",")
+	}
+	if len(analysis.NetworkCalls) > 0 {
+		sample.Metadata["network_calls"] = strings.Join(analysis.NetworkCalls, ",")
+	}
+}
+
 func (e *GoExecutor) determineSampleType(code string) string {
 	if strings.Contains(code, "goroutine") || strings.Contains(code, "go func") {
 		return "concurrent"
@@ -137,6 +318,8 @@ func (e *GoExecutor) determineSampleType(code string) string {
 	return "simple"
 }
 
+var importPathRegex = regexp.MustCompile(`"([^"]+)"`)
+
 func (e *GoExecutor) extractImports(code string) []string {
 	importRegex := regexp.MustCompile(`import\s+(?:\(\s*((?:[^\)]+\n?)+)\s*\)|"([^"]+)")`)
 	matches := importRegex.FindAllStringSubmatch(code, -1)
@@ -146,6 +329,9 @@ func (e *GoExecutor) extractImports(code string) []string {
 		if len(match) > 2 && match[2] != "" {
 			imports = append(imports, match[2])
 		}
+		for _, quoted := range importPathRegex.FindAllStringSubmatch(match[1], -1) {
+			imports = append(imports, quoted[1])
+		}
 	}
 
 	return imports
@@ -171,8 +357,9 @@ func (e *GoExecutor) requiresAudioFile(code string) bool {
 	return false
 }
 
-// ValidateSample checks Go sample against current SDK patterns
-func (e *GoExecutor) ValidateSample(sample CodeSample) map[string]bool {
+// ValidateSample checks Go sample against current SDK patterns. Validation
+// is static, so ctx only keeps the signature in line with the other stages.
+func (e *GoExecutor) ValidateSample(ctx context.Context, sample CodeSample) map[string]bool {
 	results := make(map[string]bool)
 
 	// Example validation: check for v2 import paths
@@ -189,98 +376,415 @@ func (e *GoExecutor) ValidateSample(sample CodeSample) map[string]bool {
 		results["no_old_client"] = true
 	}
 
+	// Live handler wiring must match the targeted SDK version
+	if problem, checked := e.streamingWiringProblem(sample); checked {
+		results[RuleStreamingWiring] = problem == ""
+	}
+
+	// Clients must be built through their SDK version's constructors and options
+	if problem, checked := e.clientConstructionProblem(sample); checked {
+		results[RuleClientConstruction] = problem == ""
+	}
+
+	// Comments must not describe models, parameters, or endpoints the code doesn't use
+	if problem, checked := e.staleCommentsProblem(sample); checked {
+		results[RuleStaleComments] = problem == ""
+	}
+
 	return results
 }
 
-// ExecuteSample runs a Go code sample and returns the result
-func (e *GoExecutor) ExecuteSample(sample CodeSample) TestResult {
+// validationProblems explains the failed rules in validation that have
+// more to say than their name
+func (e *GoExecutor) validationProblems(sample CodeSample, validation map[string]bool) map[string]string {
+	var problems map[string]string
+	explain := func(rule, problem string) {
+		if passed, ok := validation[rule]; ok && !passed && problem != "" {
+			if problems == nil {
+				problems = make(map[string]string)
+			}
+			problems[rule] = problem
+		}
+	}
+
+	if problem, _ := e.streamingWiringProblem(sample); problem != "" {
+		explain(RuleStreamingWiring, problem)
+	}
+	if problem, _ := e.clientConstructionProblem(sample); problem != "" {
+		explain(RuleClientConstruction, problem)
+	}
+	if problem, _ := e.staleCommentsProblem(sample); problem != "" {
+		explain(RuleStaleComments, problem)
+	}
+	return problems
+}
+
+// ExecuteSample runs a Go code sample and returns the result. Cancelling
+// ctx stops the sample's tools and process; the sample is then reported as
+// skipped rather than failed.
+func (e *GoExecutor) ExecuteSample(ctx context.Context, sample CodeSample) TestResult {
 	startTime := time.Now()
 
-	// Create temporary directory for test
-	tempDir, err := os.MkdirTemp("", "go-test-*")
+	var usage ResourceUsage
+	tempDir, release, err := e.workspace(ctx, sample, &usage)
+	defer release()
+	if err := ctx.Err(); err != nil {
+		return cancelledResult(sample, err)
+	}
 	if err != nil {
-		return TestResult{
-			Sample:       sample,
-			Success:      false,
-			ErrorMessage: err.Error(),
+		return setupFailure(sample, err)
+	}
+
+	policy := e.samplePolicy(sample)
+
+	// Build separately from running so build time and binary size can be
+	// tracked, and so retries don't rebuild
+	build := e.buildWorkspace(ctx, sample, tempDir, &usage)
+	var result TestResult
+	if build.failure != nil {
+		result = *build.failure
+		result.Sample = sample
+		result.ValidationResults = e.ValidateSample(ctx, sample)
+		result.ValidationProblems = e.validationProblems(sample, result.ValidationResults)
+		result.ResourceUsage = usage
+	} else {
+		result = e.runWorkspace(ctx, sample, tempDir, policy.Timeout, &usage)
+		result.Attempts = 1
+		for attempt := 1; attempt <= policy.Retries && retryable(result); attempt++ {
+			select {
+			case <-ctx.Done():
+			case <-time.After(policy.retryDelay(attempt)):
+				result = e.runWorkspace(ctx, sample, tempDir, policy.Timeout, &usage)
+				result.Attempts = attempt + 1
+			}
 		}
 	}
-	defer os.RemoveAll(tempDir)
 
-	// Create test Go file
-	testFile := filepath.Join(tempDir, "main.go")
-	testCode := e.prepareCodeForExecution(sample)
+	if result.Status == StatusPassed {
+		checkAssertions(&result)
+	}
+	if result.Status == StatusPassed {
+		e.checkAgentConversation(&result)
+	}
+	if result.Status == StatusPassed && e.GoldenDir != "" {
+		if err := e.checkGolden(&result); err != nil {
+			result.Success = false
+			result.Status = StatusFailed
+			result.ErrorCategory = ErrorCategoryOutput
+			result.ErrorMessage = fmt.Sprintf("checking golden output: %v", err)
+		}
+	}
 
-	err = os.WriteFile(testFile, []byte(testCode), 0644)
-	if err != nil {
-		return TestResult{
-			Sample:       sample,
-			Success:      false,
-			ErrorMessage: err.Error(),
+	result.BuildTime = build.duration.Seconds()
+	result.BinarySize = build.size
+	result.ExecutionTime = time.Since(startTime).Seconds()
+	if e.KeepWorkspace {
+		result.WorkDir = tempDir
+	}
+	return result
+}
+
+// workspaceBuild is the outcome of compiling a workspace
+type workspaceBuild struct {
+	duration time.Duration
+	size     int64
+	// failure is the result to report when the build failed or timed out
+	failure *TestResult
+}
+
+// buildWorkspace compiles a prepared workspace into sampleBinary
+func (e *GoExecutor) buildWorkspace(parent context.Context, sample CodeSample, dir string, usage *ResourceUsage) workspaceBuild {
+	started := time.Now()
+	timeout := e.buildTimeout()
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	args := e.buildCommandArgs()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = e.goToolEnv()
+	killProcessGroupOnCancel(cmd)
+
+	output, err := cmd.CombinedOutput()
+	e.Audit.record(cmd, sample.ID, started, err)
+	usage.record(cmd.ProcessState)
+	build := workspaceBuild{duration: time.Since(started)}
+
+	switch {
+	case parent.Err() != nil:
+		cancelled := cancelledResult(CodeSample{}, parent.Err())
+		build.failure = &cancelled
+	case ctx.Err() == context.DeadlineExceeded:
+		build.failure = &TestResult{
+			Status:       StatusTimeout,
+			TimeoutLimit: timeout.Seconds(),
+			Stdout:       string(output),
+			Stderr:       fmt.Sprintf("build timed out after %.1fs (limit %s)", build.duration.Seconds(), timeout),
+		}
+	case err != nil:
+		build.failure = &TestResult{
+			Status:        StatusFailed,
+			ErrorCategory: categorizeBuildError(string(output)),
+			Stdout:        string(output),
+			Stderr:        err.Error(),
+		}
+	default:
+		if info, err := os.Stat(filepath.Join(dir, sampleBinary)); err == nil {
+			build.size = info.Size()
 		}
 	}
+	return build
+}
 
-	// Initialize Go module
-	cmd := exec.Command("go", "mod", "init", "test")
-	cmd.Dir = tempDir
-	cmd.Run() // Ignore errors for this example
+// runWorkspace makes one attempt at running a built workspace within timeout
+func (e *GoExecutor) runWorkspace(parent context.Context, sample CodeSample, dir string, timeout time.Duration, usage *ResourceUsage) TestResult {
+	startTime := time.Now()
 
-	// Try to run the code
-	cmd = exec.Command("go", "run", "main.go")
-	cmd.Dir = tempDir
-	cmd.Env = append(os.Environ(), "DEEPGRAM_API_KEY=test_key")
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, filepath.Join(dir, sampleBinary))
+	cmd.Dir = dir
+	cmd.Env = append(e.goToolEnv(), e.sampleEnv()...)
+	if key := e.agentAPIKey(sample); key != "" {
+		cmd.Env = append(cmd.Env, "DEEPGRAM_API_KEY="+key)
+	}
+	killProcessGroupOnCancel(cmd)
 
 	output, err := cmd.CombinedOutput()
+	e.Audit.record(cmd, sample.ID, startTime, err)
 	executionTime := time.Since(startTime).Seconds()
+	usage.record(cmd.ProcessState)
+	if parent.Err() != nil {
+		result := cancelledResult(sample, parent.Err())
+		result.ExecutionTime = executionTime
+		result.ResourceUsage = *usage
+		return result
+	}
 
 	success := err == nil
+	status := StatusPassed
+	errorCategory := ""
 	stderr := ""
 	stdout := string(output)
 
-	if err != nil {
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		success = false
+		status = StatusTimeout
+		stderr = fmt.Sprintf("timed out after %.1fs (limit %s)", executionTime, timeout)
+	case err != nil:
+		status = StatusFailed
+		errorCategory = ErrorCategoryRuntime
 		stderr = err.Error()
 	}
 
+	validation := e.ValidateSample(parent, sample)
 	return TestResult{
-		Sample:            sample,
-		Success:           success,
-		ExecutionTime:     executionTime,
-		Stdout:            stdout,
-		Stderr:            stderr,
-		ValidationResults: e.ValidateSample(sample),
+		Sample:             sample,
+		Success:            success,
+		Status:             status,
+		ErrorCategory:      errorCategory,
+		ExecutionTime:      executionTime,
+		TimeoutLimit:       timeout.Seconds(),
+		Stdout:             stdout,
+		Stderr:             stderr,
+		ValidationResults:  validation,
+		ValidationProblems: e.validationProblems(sample, validation),
+		ResourceUsage:      *usage,
 	}
 }
 
-func (e *GoExecutor) prepareCodeForExecution(sample CodeSample) string {
-	code := sample.Code
+// sampleBinary is the executable a workspace is built into
+var sampleBinary = "sample" + exeSuffix()
+
+// exeSuffix returns the executable file extension for the host OS
+func exeSuffix() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
 
-	// Add package declaration if missing
-	if !strings.HasPrefix(code, "package") {
-		code = "package main\n\n" + code
+// buildCommandArgs returns the command that compiles a prepared workspace
+func (e *GoExecutor) buildCommandArgs() []string {
+	args := []string{"go", "build", "-o", sampleBinary}
+	if e.CoverDir != "" {
+		if modulePath, _, ok := e.localSDKModule(); ok {
+			args = append(args, coverBuildFlags(modulePath)...)
+		}
 	}
+	return append(args, "main.go")
+}
 
-	// Replace placeholder API keys
-	code = strings.ReplaceAll(code, `"YOUR_API_KEY"`, `"test_key"`)
+// buildTimeout bounds compiling a sample, separately from running it
+func (e *GoExecutor) buildTimeout() time.Duration {
+	execution := configSection(e.LanguageConfig, "execution")
+	return time.Duration(configInt(execution, "build_timeout_seconds", 120)) * time.Second
+}
 
-	// Add basic error handling for network calls
-	if strings.Contains(code, "http://") || strings.Contains(code, "https://") {
-		code = "// Note: This would make real network calls\n" + code
+// workspace prepares a sample's workspace, from the warm pool when one is
+// running, and returns a func that cleans it up once the sample is done
+func (e *GoExecutor) workspace(ctx context.Context, sample CodeSample, usage *ResourceUsage) (string, func(), error) {
+	if e.workspaces != nil {
+		dir, err := e.workspaces.acquire(ctx)
+		if err != nil {
+			return "", func() {}, err
+		}
+		return dir, func() { e.workspaces.release(dir) }, e.warmWorkspace(ctx, dir, sample, usage)
 	}
 
-	return code
+	dir, err := e.prepareWorkspace(ctx, sample, usage)
+	if dir == "" || e.KeepWorkspace {
+		return dir, func() {}, err
+	}
+	return dir, func() { removeWorkspace(dir) }, err
 }
 
-// CLI interface for integration with Python test runner
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Go executor ready")
+// prepareWorkspace creates a temp module containing the prepared sample as
+// main.go. The directory is returned even on error so callers can clean up.
+func (e *GoExecutor) prepareWorkspace(ctx context.Context, sample CodeSample, usage *ResourceUsage) (string, error) {
+	// Create temporary directory for test
+	tempDir, err := os.MkdirTemp("", "go-test-*")
+	if err != nil {
+		return "", err
+	}
+
+	// Create test Go file
+	testFile := filepath.Join(tempDir, "main.go")
+	testCode := e.prepareCodeForExecution(sample)
+
+	err = os.WriteFile(testFile, []byte(testCode), 0644)
+	if err != nil {
+		return tempDir, err
+	}
+	if err := e.writeAudioFixtures(tempDir, sample); err != nil {
+		return tempDir, err
+	}
+
+	// Initialize Go module
+	cmd := exec.CommandContext(ctx, "go", "mod", "init", "test")
+	cmd.Dir = tempDir
+	cmd.Env = e.goToolEnv()
+	started := time.Now()
+	err = cmd.Run() // Ignore errors for this example
+	e.Audit.record(cmd, sample.ID, started, err)
+	usage.record(cmd.ProcessState)
+
+	// Add missing imports and drop unused ones left behind by preparation
+	e.fixImports(ctx, tempDir, sample.ID, usage)
+
+	e.resolveDependencies(ctx, tempDir, sample.ID, sample.Imports, usage)
+
+	return tempDir, nil
+}
+
+// sampleEnv returns the variables added to the environment samples run in
+func (e *GoExecutor) sampleEnv() []string {
+	var env []string
+	if !e.Live {
+		env = append(env, "DEEPGRAM_API_KEY=test_key")
+	}
+	if e.CoverDir != "" {
+		env = append(env, "GOCOVERDIR="+e.CoverDir)
+	}
+	if e.Mock != nil {
+		env = append(env, e.Mock.Env()...)
+	}
+	env = append(env, e.environmentEnv()...)
+	return append(env, e.baseURLEnv()...)
+}
+
+// sampleTimeout returns the global execution time limit, which sample
+// policies may override
+func (e *GoExecutor) sampleTimeout() time.Duration {
+	execution := configSection(e.FrameworkConfig, "execution")
+	return time.Duration(configInt(execution, "timeout_seconds", 10)) * time.Second
+}
+
+// fixImports runs goimports over the prepared main.go so fragments that
+// leave out standard-library imports, or that picked up unused ones during
+// preparation, don't fail to compile for reasons unrelated to the docs.
+// It is a no-op when execution.goimports is false or goimports isn't installed.
+// The run is audited under sampleID.
+func (e *GoExecutor) fixImports(ctx context.Context, dir, sampleID string, usage *ResourceUsage) {
+	execution := configSection(e.LanguageConfig, "execution")
+	if !configBool(execution, "goimports", true) {
+		return
+	}
+
+	goimports, err := exec.LookPath(configString(execution, "goimports_path", "goimports"))
+	if err != nil {
 		return
 	}
 
-	command := os.Args[1]
-	fmt.Printf("Go executor: %s\n", command)
+	ctx, cancel := context.WithTimeout(ctx, e.sampleTimeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, goimports, "-w", "main.go")
+	cmd.Dir = dir
+	cmd.Env = e.goToolEnv()
+	started := time.Now()
+	err = cmd.Run() // Syntax errors are left for the build step to report
+	e.Audit.record(cmd, sampleID, started, err)
+	usage.record(cmd.ProcessState)
+}
+
+// skipReason explains why a sample can't run in this mode, or returns ""
+func (e *GoExecutor) skipReason(sample CodeSample) string {
+	if reason := platformSkipReason(sample); reason != "" {
+		return reason
+	}
+	if sample.RequiresNetwork && !e.Live && e.Mock == nil {
+		return "requires network access to a live service; run with --live or --mock"
+	}
+	if sample.RequiresNetwork && e.Mock == nil {
+		return e.disabledInEnvironment(sample)
+	}
+	return ""
+}
+
+// setupFailure builds the result for a sample that never reached `go run`
+func setupFailure(sample CodeSample, err error) TestResult {
+	return TestResult{
+		Sample:        sample,
+		Success:       false,
+		Status:        StatusFailed,
+		ErrorCategory: ErrorCategorySetup,
+		ErrorMessage:  err.Error(),
+	}
+}
+
+// cancelledResult reports a sample whose run was cancelled by the caller
+func cancelledResult(sample CodeSample, err error) TestResult {
+	return TestResult{
+		Sample:       sample,
+		Status:       StatusSkipped,
+		ErrorMessage: "run cancelled: " + err.Error(),
+	}
+}
+
+var (
+	buildErrorRegex      = regexp.MustCompile(`(?m)^(# command-line-arguments|\.?[/\\]?main\.go:\d+:\d+: )`)
+	dependencyErrorRegex = regexp.MustCompile(`no required module provides package|cannot find module|missing go\.sum entry|errors parsing go\.mod`)
+)
+
+// categorizeBuildError classifies a failed `go build` by inspecting its
+// output, since module resolution and compile errors share an exit code
+func categorizeBuildError(output string) string {
+	if dependencyErrorRegex.MatchString(output) {
+		return ErrorCategoryDependency
+	}
+	return ErrorCategoryBuild
+}
 
-	// This would handle JSON communication with Python test runner
-	// For example, reading config and samples from stdin,
-	// returning results as JSON to stdout
+// prepareCodeForExecution runs a sample's code through its transformer
+// pipeline
+func (e *GoExecutor) prepareCodeForExecution(sample CodeSample) string {
+	code := sample.Code
+	for _, transformer := range e.samplePipeline(sample) {
+		code = transformer.Apply(sample, code)
+	}
+	return code
 }
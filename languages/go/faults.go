@@ -0,0 +1,146 @@
+package main
+
+// Mock fault injection
+// Docs show samples that retry on rate limits or report server errors, but
+// the happy-path mock never exercises that code. With faults enabled, the
+// mock answers some requests with 429s, 5xxs, 401s, malformed JSON, or a
+// connection dropped mid-response, so a sample that claims to handle errors
+// has to survive them.
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Faults the mock can inject
+const (
+	FaultRateLimit     = "rate_limit"
+	FaultServerError   = "server_error"
+	FaultMalformedJSON = "malformed_json"
+	FaultDisconnect    = "disconnect"
+	FaultUnauthorized  = "unauthorized"
+)
+
+var faultKinds = map[string]bool{
+	FaultRateLimit:     true,
+	FaultServerError:   true,
+	FaultMalformedJSON: true,
+	FaultDisconnect:    true,
+	FaultUnauthorized:  true,
+}
+
+// serverErrorStatuses are rotated through by FaultServerError
+var serverErrorStatuses = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}
+
+// MockFaults decides which mock requests fail and how
+type MockFaults struct {
+	Kinds []string
+	// Rate is the fraction of requests that get a fault
+	Rate float64
+	// Status, when set, is the status FaultServerError answers with instead
+	// of rotating through 500, 502, and 503
+	Status int
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+// NewMockFaults validates a comma-separated list of fault kinds, or "all".
+// Faults are drawn from a generator seeded with seed so runs repeat.
+func NewMockFaults(kinds string, rate float64, seed int64) (*MockFaults, error) {
+	faults := &MockFaults{Rate: rate, random: rand.New(rand.NewSource(seed))}
+	for _, kind := range strings.Split(kinds, ",") {
+		kind = strings.TrimSpace(kind)
+		switch {
+		case kind == "":
+		case kind == "all":
+			for known := range faultKinds {
+				faults.Kinds = append(faults.Kinds, known)
+			}
+		case faultKinds[kind]:
+			faults.Kinds = append(faults.Kinds, kind)
+		default:
+			return nil, fmt.Errorf("unknown mock fault %q (expected %s)", kind, strings.Join(sortedKeys(faultKinds), ", "))
+		}
+	}
+	if len(faults.Kinds) == 0 {
+		return nil, nil
+	}
+	if rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("fault rate must be in (0, 1], got %g", rate)
+	}
+	sort.Strings(faults.Kinds)
+	return faults, nil
+}
+
+// pick returns the fault for the next request, or "" to answer normally
+func (f *MockFaults) pick() (string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.random.Float64() >= f.Rate {
+		return "", 0
+	}
+	status := f.Status
+	if status == 0 {
+		status = serverErrorStatuses[f.random.Intn(len(serverErrorStatuses))]
+	}
+	return f.Kinds[f.random.Intn(len(f.Kinds))], status
+}
+
+// wrap injects faults in front of the mock's handler
+func (f *MockFaults) wrap(next http.Handler) http.Handler {
+	if f == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault, status := f.pick()
+		switch fault {
+		case FaultRateLimit:
+			w.Header().Set("Retry-After", "1")
+			writeMockError(w, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "Too many requests. Please try again later.")
+
+		case FaultServerError:
+			writeMockError(w, status, "INTERNAL_SERVER_ERROR", "The mock server injected a "+strconv.Itoa(status)+" response.")
+
+		case FaultUnauthorized:
+			writeMockError(w, http.StatusUnauthorized, "INVALID_AUTH", "Invalid credentials.")
+
+		case FaultMalformedJSON:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"metadata": {"request_id": "00000000-0000-0000-0000-000000000000", "dur`))
+
+		case FaultDisconnect:
+			// Promise a full body, send part of it, then drop the connection
+			body := []byte(`{"metadata": {"request_id": "00000000-0000-0000-0000-000000000000"}, "results": {`)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", strconv.Itoa(4*len(body)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(body)
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			panic(http.ErrAbortHandler)
+
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// mockFaults builds the fault injector from mocking.faults, with kinds
+// overriding the configured ones when given
+func (e *GoExecutor) mockFaults(kinds string) (*MockFaults, error) {
+	config := configSection(configSection(e.FrameworkConfig, "mocking"), "faults")
+	if kinds == "" {
+		kinds = strings.Join(configStrings(config, "kinds"), ",")
+	}
+	return NewMockFaults(kinds, configFloat(config, "rate", 1), int64(configInt(config, "seed", 1)))
+}
@@ -0,0 +1,227 @@
+package main
+
+// Freshness stamping
+// A green run says the samples work today; stamping pages with when their
+// samples were last verified turns that into a record, so pages that keep
+// failing or that nobody has run in a while can be found later. Stamps go in
+// a sidecar manifest, page frontmatter, or both.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// frontmatterKey is the frontmatter field holding a page's verification date
+const frontmatterKey = "last_verified"
+
+// FreshnessManifest records when each page's samples last all passed
+type FreshnessManifest struct {
+	Pages map[string]PageFreshness `json:"pages"`
+}
+
+// PageFreshness is the verification record for one page
+type PageFreshness struct {
+	LastVerified time.Time `json:"last_verified"`
+	Samples      int       `json:"samples"`
+}
+
+// StalePage is a page whose samples haven't passed recently enough
+type StalePage struct {
+	Page         string     `json:"page"`
+	LastVerified *time.Time `json:"last_verified,omitempty"`
+	AgeDays      int        `json:"age_days,omitempty"`
+}
+
+// verifiedPages returns the pages, keyed by file path, whose every sample
+// ran in this run and passed, with their sample counts
+func verifiedPages(samples []CodeSample, results []TestResult) map[string]int {
+	passed := make(map[string]bool)
+	for _, result := range results {
+		if result.Status == StatusPassed {
+			passed[fmt.Sprintf("%s:%d", result.Sample.FilePath, result.Sample.LineNumber)] = true
+		}
+	}
+
+	counts := make(map[string]int)
+	failed := make(map[string]bool)
+	for _, sample := range samples {
+		counts[sample.FilePath]++
+		if !passed[fmt.Sprintf("%s:%d", sample.FilePath, sample.LineNumber)] {
+			failed[sample.FilePath] = true
+		}
+	}
+
+	for page := range failed {
+		delete(counts, page)
+	}
+	return counts
+}
+
+// pageNames maps each sample's file to its page name in the manifest
+func pageNames(samples []CodeSample) map[string]string {
+	names := make(map[string]string)
+	for _, sample := range samples {
+		names[sample.FilePath] = sample.Page
+	}
+	return names
+}
+
+// loadFreshnessManifest reads a manifest, returning an empty one if the file doesn't exist
+func loadFreshnessManifest(path string) (FreshnessManifest, error) {
+	manifest := FreshnessManifest{Pages: make(map[string]PageFreshness)}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return manifest, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	if manifest.Pages == nil {
+		manifest.Pages = make(map[string]PageFreshness)
+	}
+	return manifest, nil
+}
+
+// stampManifest records the verified pages in the manifest at path
+func stampManifest(path string, samples []CodeSample, verified map[string]int, now time.Time) error {
+	manifest, err := loadFreshnessManifest(path)
+	if err != nil {
+		return err
+	}
+
+	names := pageNames(samples)
+	for page, count := range verified {
+		manifest.Pages[names[page]] = PageFreshness{LastVerified: now.UTC().Truncate(time.Second), Samples: count}
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// stampFrontmatter sets last_verified in each verified page's frontmatter,
+// adding frontmatter to pages without it
+func stampFrontmatter(verified map[string]int, now time.Time) error {
+	for page := range verified {
+		content, err := os.ReadFile(page)
+		if err != nil {
+			return err
+		}
+		stamped := setFrontmatterField(string(content), frontmatterKey, now.UTC().Format("2006-01-02"))
+		if err := os.WriteFile(page, []byte(stamped), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFrontmatterField sets a top-level key in a page's YAML frontmatter,
+// keeping the page's line endings
+func setFrontmatterField(content, key, value string) string {
+	if newline := pageNewline(content); newline != "\n" {
+		content = strings.ReplaceAll(content, newline, "\n")
+		return strings.ReplaceAll(setFrontmatterField(content, key, value), "\n", newline)
+	}
+	line := key + ": " + value
+
+	if !strings.HasPrefix(content, "---\n") {
+		return "---\n" + line + "\n---\n\n" + content
+	}
+
+	end := strings.Index(content[4:], "\n---")
+	if end < 0 {
+		return "---\n" + line + "\n---\n\n" + content
+	}
+	end += 4
+
+	lines := strings.Split(content[4:end], "\n")
+	for i, existing := range lines {
+		if strings.HasPrefix(existing, key+":") {
+			lines[i] = line
+			return content[:4] + strings.Join(lines, "\n") + content[end:]
+		}
+	}
+	return content[:end] + "\n" + line + content[end:]
+}
+
+// frontmatterField reads a top-level key from a page's YAML frontmatter
+func frontmatterField(content, key string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return ""
+	}
+	end := strings.Index(content[4:], "\n---")
+	if end < 0 {
+		return ""
+	}
+
+	for _, line := range strings.Split(content[4:4+end], "\n") {
+		if strings.HasPrefix(line, key+":") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, key+":")), `"'`)
+		}
+	}
+	return ""
+}
+
+// pageNewline returns the line ending a page uses, judged by its first line
+func pageNewline(content string) string {
+	if i := strings.Index(content, "\n"); i > 0 && content[i-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// stalePages returns pages with samples whose last verification, from the
+// manifest or frontmatter, whichever is newer, is older than maxAge or missing
+func stalePages(samples []CodeSample, manifest FreshnessManifest, maxAge time.Duration, now time.Time) ([]StalePage, error) {
+	names := pageNames(samples)
+	pages := make(map[string]bool)
+	for _, sample := range samples {
+		pages[sample.FilePath] = true
+	}
+
+	var stale []StalePage
+	for _, page := range sortedKeys(pages) {
+		var last time.Time
+		if record, ok := manifest.Pages[names[page]]; ok {
+			last = record.LastVerified
+		}
+
+		content, err := os.ReadFile(page)
+		if err != nil {
+			return nil, err
+		}
+		if stamp := frontmatterField(string(content), frontmatterKey); stamp != "" {
+			if date, err := time.Parse("2006-01-02", stamp); err == nil && date.After(last) {
+				last = date
+			}
+		}
+
+		switch {
+		case last.IsZero():
+			stale = append(stale, StalePage{Page: page})
+		case now.Sub(last) > maxAge:
+			verified := last
+			stale = append(stale, StalePage{Page: page, LastVerified: &verified, AgeDays: int(now.Sub(last).Hours() / 24)})
+		}
+	}
+
+	// Never-verified pages first, then oldest first
+	sort.SliceStable(stale, func(i, j int) bool {
+		if (stale[i].LastVerified == nil) != (stale[j].LastVerified == nil) {
+			return stale[i].LastVerified == nil
+		}
+		return stale[i].AgeDays > stale[j].AgeDays
+	})
+	return stale, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestSetFrontmatterField(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{
+			"no frontmatter",
+			"# Live\n",
+			"---\nlast_verified: 2026-10-16\n---\n\n# Live\n",
+		},
+		{
+			"added",
+			"---\ntitle: Live\n---\n# Live\n",
+			"---\ntitle: Live\nlast_verified: 2026-10-16\n---\n# Live\n",
+		},
+		{
+			"replaced",
+			"---\nlast_verified: 2025-01-01\ntitle: Live\n---\n# Live\n",
+			"---\nlast_verified: 2026-10-16\ntitle: Live\n---\n# Live\n",
+		},
+		{
+			"unterminated",
+			"---\ntitle: Live\n",
+			"---\nlast_verified: 2026-10-16\n---\n\n---\ntitle: Live\n",
+		},
+		{
+			"crlf added",
+			"---\r\ntitle: Live\r\n---\r\n# Live\r\n",
+			"---\r\ntitle: Live\r\nlast_verified: 2026-10-16\r\n---\r\n# Live\r\n",
+		},
+		{
+			"crlf replaced",
+			"---\r\nlast_verified: 2025-01-01\r\n---\r\n# Live\r\n",
+			"---\r\nlast_verified: 2026-10-16\r\n---\r\n# Live\r\n",
+		},
+		{
+			"crlf no frontmatter",
+			"# Live\r\n",
+			"---\r\nlast_verified: 2026-10-16\r\n---\r\n\r\n# Live\r\n",
+		},
+	}
+	for _, test := range tests {
+		got := setFrontmatterField(test.content, frontmatterKey, "2026-10-16")
+		if got != test.want {
+			t.Errorf("%s: setFrontmatterField = %q, want %q", test.name, got, test.want)
+		}
+		if stamp := frontmatterField(got, frontmatterKey); stamp != "2026-10-16" {
+			t.Errorf("%s: frontmatterField after stamping = %q", test.name, stamp)
+		}
+	}
+}
+
+func TestFrontmatterField(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"plain", "---\nlast_verified: 2026-10-16\n---\n", "2026-10-16"},
+		{"quoted", "---\ntitle: x\nlast_verified: \"2026-10-16\"\n---\n", "2026-10-16"},
+		{"single quoted", "---\nlast_verified: '2026-10-16'\n---\n", "2026-10-16"},
+		{"crlf", "---\r\ntitle: x\r\nlast_verified: 2026-10-16\r\n---\r\n# Page\r\n", "2026-10-16"},
+		{"missing", "---\ntitle: x\n---\n", ""},
+		{"no frontmatter", "last_verified: 2026-10-16\n", ""},
+		{"unterminated", "---\nlast_verified: 2026-10-16\n", ""},
+		{"outside frontmatter", "---\ntitle: x\n---\nlast_verified: 2026-10-16\n", ""},
+	}
+	for _, test := range tests {
+		if got := frontmatterField(test.content, frontmatterKey); got != test.want {
+			t.Errorf("%s: frontmatterField = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
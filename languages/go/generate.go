@@ -0,0 +1,174 @@
+package main
+
+// go test harness generation
+// Teams that already run `go test ./...` in CI can verify docs samples with
+// stock tooling: each sample is written out as its own main package, and a
+// generated test file builds (or runs) each one as a subtest.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// harnessModule is the module path of a generated test package
+const harnessModule = "docsampletests"
+
+// harnessSample is one generated subtest
+type harnessSample struct {
+	Name   string
+	Dir    string
+	Page   string
+	Line   int
+	Skip   string
+	Policy executionPolicy
+}
+
+var nonIdentifierRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// GenerateTests writes a Go test package to outDir in which every sample is
+// a subtest. Samples are built; with run set they are also executed.
+func (e *GoExecutor) GenerateTests(ctx context.Context, samples []CodeSample, outDir string, run bool) error {
+	samplesDir := filepath.Join(outDir, "samples")
+	if err := os.RemoveAll(samplesDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(samplesDir, 0755); err != nil {
+		return err
+	}
+
+	var usage ResourceUsage
+	var harness []harnessSample
+	for _, sample := range samples {
+		page := sample.Page
+		name := sampleTestName(page, sample.LineNumber)
+		dir := filepath.Join(samplesDir, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(e.prepareCodeForExecution(sample)), 0644); err != nil {
+			return err
+		}
+		e.fixImports(ctx, dir, sample.ID, &usage)
+
+		harness = append(harness, harnessSample{
+			Name:   name,
+			Dir:    "samples/" + name,
+			Page:   page,
+			Line:   sample.LineNumber,
+			Skip:   e.skipReason(sample),
+			Policy: e.samplePolicy(sample),
+		})
+	}
+
+	source, err := harnessSource(harness, run)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "samples_test.go"), source, 0644); err != nil {
+		return err
+	}
+
+	// Start from a fresh go.mod so requirements match the current samples
+	os.Remove(filepath.Join(outDir, "go.mod"))
+	os.Remove(filepath.Join(outDir, "go.sum"))
+	cmd := exec.CommandContext(ctx, "go", "mod", "init", harnessModule)
+	cmd.Dir = outDir
+	cmd.Env = e.goToolEnv()
+	started := time.Now()
+	output, err := cmd.CombinedOutput()
+	e.Audit.record(cmd, "", started, err)
+	if err != nil {
+		return fmt.Errorf("go mod init: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	e.resolveDependencies(ctx, outDir, "", e.importUnion(samples), &usage)
+
+	return nil
+}
+
+// docsRelativePath returns a page's slash-separated path within the docs tree
+func docsRelativePath(docsPath, path string) string {
+	page, err := filepath.Rel(docsPath, path)
+	if err != nil {
+		page = path
+	}
+	return filepath.ToSlash(page)
+}
+
+// sampleTestName names a sample's subtest after its page and fence line,
+// e.g. speech_to_text_live_L42 for fern/pages/speech-to-text/live.mdx:42
+func sampleTestName(page string, line int) string {
+	stem := strings.TrimSuffix(strings.TrimPrefix(page, "fern/pages/"), ".mdx")
+	return fmt.Sprintf("%s_L%d", strings.Trim(nonIdentifierRegex.ReplaceAllString(stem, "_"), "_"), line)
+}
+
+// harnessSource renders the generated test file
+func harnessSource(samples []harnessSample, run bool) ([]byte, error) {
+	var source bytes.Buffer
+	source.WriteString(`// Code generated by dgtest generate-tests; DO NOT EDIT.
+
+package docsampletests
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+var samples = []struct {
+	Name    string
+	Dir     string
+	Page    string
+	Line    int
+	Skip    string
+	Timeout time.Duration
+}{
+`)
+	for _, sample := range samples {
+		fmt.Fprintf(&source, "\t{%q, %q, %q, %d, %q, %d * time.Second},\n",
+			sample.Name, sample.Dir, sample.Page, sample.Line, sample.Skip, int(sample.Policy.Timeout.Seconds()))
+	}
+	fmt.Fprintf(&source, `}
+
+// runSamples executes samples instead of only building them
+const runSamples = %t
+
+func TestSamples(t *testing.T) {
+	for _, sample := range samples {
+		sample := sample
+		t.Run(sample.Name, func(t *testing.T) {
+			if sample.Skip != "" {
+				t.Skip(sample.Skip)
+			}
+			t.Parallel()
+
+			args := []string{"build", "-o", os.DevNull, "./" + sample.Dir}
+			ctx := context.Background()
+			if runSamples {
+				args = []string{"run", "./" + sample.Dir}
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, sample.Timeout)
+				defer cancel()
+			}
+
+			cmd := exec.CommandContext(ctx, "go", args...)
+			cmd.Env = append(os.Environ(), "DEEPGRAM_API_KEY=test_key")
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%%s:%%d: %%v\n%%s", sample.Page, sample.Line, err, output)
+			}
+		})
+	}
+}
+`, run)
+
+	return format.Source(source.Bytes())
+}
@@ -0,0 +1,121 @@
+package main
+
+// Golden outputs
+// Samples whose output is deterministic, typically when run against the
+// mock server, can have their expected stdout checked in as a golden file.
+// Output is compared after redaction, so request IDs and temp paths don't
+// make otherwise identical runs differ.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// goldenPath returns where a sample's golden file lives, named after its
+// stable ID so the file follows the sample when lines above it change
+func (e *GoExecutor) goldenPath(sample CodeSample) string {
+	if sample.ID == "" {
+		return e.locationGoldenPath(sample)
+	}
+	return filepath.Join(e.GoldenDir, sample.ID+".golden")
+}
+
+// locationGoldenPath is where golden files named after a sample's page and
+// fence line, like generated subtests, live. They predate stable IDs, and
+// still match a sample whose code changed without moving.
+func (e *GoExecutor) locationGoldenPath(sample CodeSample) string {
+	return filepath.Join(e.GoldenDir, sampleTestName(sample.Page, sample.LineNumber)+".golden")
+}
+
+// checkGolden compares a passed sample's output with its golden file, or
+// writes the file when UpdateGolden is set. Samples without a golden file
+// aren't compared, but are marked GoldenMissing.
+func (e *GoExecutor) checkGolden(result *TestResult) error {
+	rules, err := e.redactionRules()
+	if err != nil {
+		return err
+	}
+	output := redact(result.Stdout, rules)
+	path := e.goldenPath(result.Sample)
+
+	if e.UpdateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(output), 0644); err != nil {
+			return err
+		}
+		if legacy := e.locationGoldenPath(result.Sample); legacy != path {
+			if err := os.Remove(legacy); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	golden, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		path = e.locationGoldenPath(result.Sample)
+		golden, err = os.ReadFile(path)
+	}
+	if os.IsNotExist(err) {
+		result.GoldenMissing = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if string(golden) != output {
+		result.Success = false
+		result.Status = StatusFailed
+		result.ErrorCategory = ErrorCategoryOutput
+		result.GoldenDiff = lineDiff(string(golden), output)
+		result.ErrorMessage = fmt.Sprintf("output differs from %s", path)
+	}
+	return nil
+}
+
+// lineDiff renders the lines removed from want (-) and added in got (+),
+// with unchanged lines indented, using a longest common subsequence
+func lineDiff(want, got string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return diff.String()
+}
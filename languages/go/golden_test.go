@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name, want, got, diff string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", "  a\n  b\n"},
+		{"changed", "a\nb\nc\n", "a\nB\nc\n", "  a\n- b\n+ B\n  c\n"},
+		{"added", "a\n", "a\nb\n", "  a\n+ b\n"},
+		{"removed", "a\nb\n", "b\n", "- a\n  b\n"},
+		{"trailing newline ignored", "a", "a\n", "  a\n"},
+		{"reordered", "a\nb\nc\n", "c\na\nb\n", "+ c\n  a\n  b\n- c\n"},
+	}
+	for _, test := range tests {
+		if got := lineDiff(test.want, test.got); got != test.diff {
+			t.Errorf("%s: lineDiff = %q, want %q", test.name, got, test.diff)
+		}
+	}
+}
+
+func TestGoldenPath(t *testing.T) {
+	e := &GoExecutor{GoldenDir: "golden"}
+	sample := CodeSample{ID: "stt-live-1a2b3c4d", Page: "fern/pages/stt/live.mdx", LineNumber: 42}
+	if got, want := e.goldenPath(sample), "golden/stt-live-1a2b3c4d.golden"; got != want {
+		t.Errorf("goldenPath = %q, want %q", got, want)
+	}
+	if got, want := e.locationGoldenPath(sample), "golden/stt_live_L42.golden"; got != want {
+		t.Errorf("locationGoldenPath = %q, want %q", got, want)
+	}
+	sample.ID = ""
+	if got, want := e.goldenPath(sample), "golden/stt_live_L42.golden"; got != want {
+		t.Errorf("goldenPath without an ID = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+// Page health
+// Writers want to know whether the page in front of them is safe to edit
+// or already broken, without reading CI logs. `dgtest health` answers for
+// one page: its samples and their most recent results from the run
+// history, when the page was last verified, and any open quarantines, with
+// an overall status the docs CMS can show as a badge. `--serve` answers the
+// same query over HTTP.
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page health statuses, worst first
+const (
+	HealthFailing     = "failing"
+	HealthQuarantined = "quarantined"
+	HealthStale       = "stale"
+	HealthUnknown     = "unknown"
+	HealthHealthy     = "healthy"
+)
+
+// healthBadgeColors are the badge color of each status
+var healthBadgeColors = map[string]string{
+	HealthFailing:     "red",
+	HealthQuarantined: "orange",
+	HealthStale:       "yellow",
+	HealthUnknown:     "lightgrey",
+	HealthHealthy:     "brightgreen",
+}
+
+// PageHealth answers whether a docs page is healthy
+type PageHealth struct {
+	Page   string `json:"page"`
+	Status string `json:"status"`
+	// Reason explains a status other than healthy
+	Reason       string         `json:"reason,omitempty"`
+	Samples      []SampleHealth `json:"samples"`
+	LastVerified *time.Time     `json:"last_verified,omitempty"`
+	Stale        bool           `json:"stale"`
+	Quarantines  []Quarantine   `json:"quarantines,omitempty"`
+	CheckedAt    time.Time      `json:"checked_at"`
+}
+
+// SampleHealth is one sample's most recent result
+type SampleHealth struct {
+	ID      string `json:"id"`
+	Line    int    `json:"line"`
+	Product string `json:"product"`
+	// LastStatus is the status in the most recent run that included the
+	// sample, empty if no stored run did
+	LastStatus string      `json:"last_status,omitempty"`
+	LastRun    *time.Time  `json:"last_run,omitempty"`
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+}
+
+// ShieldsBadge is the JSON a shields.io endpoint badge reads
+type ShieldsBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// healthChecker answers page health queries against stored history
+type healthChecker struct {
+	executor *GoExecutor
+	roots    []docsRoot
+	storage  Storage
+	manifest FreshnessManifest
+	maxAge   time.Duration
+	// allowPaths lets pages be given as file paths, not only as page names
+	// within the roots; it's off for HTTP queries
+	allowPaths bool
+
+	// history is cached for healthHistoryTTL, so badge views don't each
+	// read every stored run
+	mu       sync.Mutex
+	history  []HistoryRecord
+	loadedAt time.Time
+}
+
+// healthHistoryTTL is how long health queries reuse loaded history
+const healthHistoryTTL = time.Minute
+
+// pageFile finds a page given as its page name, such as fern/pages/stt.mdx,
+// within one of the docs roots, or as a path when allowPaths is set. The
+// root is nil for a path outside every root.
+func (h *healthChecker) pageFile(page string) (string, *docsRoot, error) {
+	if h.allowPaths {
+		if info, err := os.Stat(page); err == nil && !info.IsDir() {
+			for i, root := range h.roots {
+				if name, ok := rootRelative(root.Path, page); ok {
+					return filepath.Join(root.Path, name), &h.roots[i], nil
+				}
+			}
+			return page, nil, nil
+		}
+	}
+	for i, root := range h.roots {
+		name := page
+		if root.Name != "" {
+			if !strings.HasPrefix(page, root.Name+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(page, root.Name+"/")
+		}
+		// Page names never leave their root
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			continue
+		}
+		candidate := filepath.Join(root.Path, filepath.FromSlash(name))
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, &h.roots[i], nil
+		}
+	}
+	return "", nil, fmt.Errorf("no page %s in the docs roots", page)
+}
+
+// rootRelative returns a file's path within a root, if it's inside it
+func rootRelative(rootPath, file string) (string, bool) {
+	absRoot, err := filepath.Abs(rootPath)
+	if err != nil {
+		return "", false
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", false
+	}
+	name, err := filepath.Rel(absRoot, absFile)
+	if err != nil || !filepath.IsLocal(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// health answers for one page
+func (h *healthChecker) health(ctx context.Context, page string, now time.Time) (PageHealth, error) {
+	file, root, err := h.pageFile(page)
+	if err != nil {
+		return PageHealth{}, err
+	}
+	// Pages in a root are named and filtered as the run's walk does, so
+	// sample IDs and locations match the stored history
+	var samples []CodeSample
+	name := pagePath(file)
+	if root != nil {
+		included, err := root.walkIncludes(file)
+		if err != nil {
+			return PageHealth{}, err
+		}
+		if !included {
+			return PageHealth{}, fmt.Errorf("%s is excluded from the docs walk", page)
+		}
+		samples, err = h.executor.extractRootPage(*root, file)
+		name = root.pageName(file)
+	} else {
+		samples, err = h.executor.ExtractSamplesFromFile(file)
+	}
+	if err != nil {
+		return PageHealth{}, err
+	}
+	history, err := h.loadHistory(ctx, now)
+	if err != nil {
+		return PageHealth{}, err
+	}
+
+	health := PageHealth{Page: name, Samples: []SampleHealth{}, CheckedAt: now.UTC().Truncate(time.Second)}
+
+	// The newest record mentioning a sample, by ID or location, holds its last result
+	for _, sample := range samples {
+		entry := SampleHealth{ID: sample.ID, Line: sample.LineNumber, Product: sampleProduct(sample)}
+		location := fmt.Sprintf("%s:%d", sample.Page, sample.LineNumber)
+		for i := len(history) - 1; i >= 0; i-- {
+			if recorded, ok := history[i].sample(sample.ID, location); ok {
+				run := history[i].Time
+				entry.LastStatus, entry.LastRun = recorded.Status, &run
+				break
+			}
+		}
+		if quarantine := h.executor.openQuarantine(sample, now); quarantine != nil {
+			entry.Quarantine = quarantine
+			health.Quarantines = append(health.Quarantines, *quarantine)
+		}
+		health.Samples = append(health.Samples, entry)
+	}
+
+	stale, err := stalePages(samples, h.manifest, h.maxAge, now)
+	if err != nil {
+		return PageHealth{}, err
+	}
+	if len(samples) > 0 {
+		health.LastVerified = lastVerified(file, health.Page, h.manifest)
+		health.Stale = len(stale) > 0
+	}
+
+	health.Status, health.Reason = healthStatus(health)
+	return health, nil
+}
+
+// loadHistory returns the stored history, reloading it once it's older
+// than healthHistoryTTL
+func (h *healthChecker) loadHistory(ctx context.Context, now time.Time) ([]HistoryRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.history != nil && now.Sub(h.loadedAt) < healthHistoryTTL {
+		return h.history, nil
+	}
+	history, err := h.storage.LoadHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []HistoryRecord{}
+	}
+	h.history, h.loadedAt = history, now
+	return history, nil
+}
+
+// lastVerified returns when a page was last verified, from the manifest or
+// its frontmatter, whichever is newer
+func lastVerified(file, page string, manifest FreshnessManifest) *time.Time {
+	var last time.Time
+	if record, ok := manifest.Pages[page]; ok {
+		last = record.LastVerified
+	}
+	if content, err := os.ReadFile(file); err == nil {
+		if stamp := frontmatterField(string(content), frontmatterKey); stamp != "" {
+			if date, err := time.Parse("2006-01-02", stamp); err == nil && date.After(last) {
+				last = date
+			}
+		}
+	}
+	if last.IsZero() {
+		return nil
+	}
+	return &last
+}
+
+// healthStatus sums up a page: any unquarantined failure fails it, then
+// quarantines, staleness, and samples never run make it less than healthy
+func healthStatus(health PageHealth) (string, string) {
+	var failing, quarantined, unknown []string
+	for _, sample := range health.Samples {
+		location := fmt.Sprintf("line %d", sample.Line)
+		switch {
+		case sample.LastStatus == StatusFailed || sample.LastStatus == StatusTimeout:
+			if sample.Quarantine != nil {
+				quarantined = append(quarantined, location)
+			} else {
+				failing = append(failing, location)
+			}
+		case sample.LastStatus == "":
+			unknown = append(unknown, location)
+		}
+	}
+
+	switch {
+	case len(failing) > 0:
+		return HealthFailing, "samples failing at " + strings.Join(failing, ", ")
+	case len(quarantined) > 0:
+		return HealthQuarantined, "quarantined samples failing at " + strings.Join(quarantined, ", ")
+	case health.Stale:
+		return HealthStale, "samples not verified recently"
+	case len(health.Samples) == 0:
+		return HealthHealthy, "no Go samples"
+	case len(unknown) > 0:
+		return HealthUnknown, "no stored results for samples at " + strings.Join(unknown, ", ")
+	}
+	return HealthHealthy, ""
+}
+
+// badge renders a page's health for a shields.io endpoint badge
+func (health PageHealth) badge() ShieldsBadge {
+	return ShieldsBadge{SchemaVersion: 1, Label: "samples", Message: health.Status, Color: healthBadgeColors[health.Status]}
+}
+
+// serve answers GET /health?page=... with PageHealth and
+// GET /badge?page=... with a shields.io endpoint badge
+func (h *healthChecker) serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	answer := func(w http.ResponseWriter, r *http.Request, render func(PageHealth) interface{}) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			writeHealthJSON(w, http.StatusBadRequest, map[string]string{"error": "missing page parameter"})
+			return
+		}
+		health, err := h.health(r.Context(), page, time.Now())
+		if err != nil {
+			writeHealthJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeHealthJSON(w, http.StatusOK, render(health))
+	}
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		answer(w, r, func(health PageHealth) interface{} { return health })
+	})
+	mux.HandleFunc("/badge", func(w http.ResponseWriter, r *http.Request) {
+		answer(w, r, func(health PageHealth) interface{} { return health.badge() })
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func writeHealthJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	// The CMS fetches badges from the browser
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// healthCommand prints the health of the pages given as arguments, or
+// serves health queries over HTTP with --serve
+func healthCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("health", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatJSON, "Output format: json, plain, or shields (a shields.io endpoint badge)")
+	historyPath := flags.String("history", "", "Read results from this JSON-lines history file instead of the configured storage")
+	manifestPath := flags.String("manifest", "", "JSON manifest written by run --stamp")
+	maxAgeDays := flags.Int("max-age-days", 30, "Call pages last verified longer ago than this stale")
+	serve := flags.String("serve", "", "Answer GET /health?page= and /badge?page= on this address instead, e.g. 127.0.0.1:8080")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *serve == "" && flags.NArg() == 0 {
+		return fmt.Errorf("usage: health [--format json|plain|shields] page.mdx... or health --serve addr")
+	}
+
+	executor, err := docs.executor()
+	if err != nil {
+		return err
+	}
+	checker := &healthChecker{executor: executor, maxAge: time.Duration(*maxAgeDays) * 24 * time.Hour, allowPaths: true}
+	if checker.roots, err = executor.docsRoots(*docs.docsPath); err != nil {
+		return err
+	}
+	for i, root := range checker.roots {
+		if root.Git != "" {
+			if checker.roots[i].Path, err = checkoutRoot(ctx, executor.Audit, root); err != nil {
+				return fmt.Errorf("documentation root %q: %v", root.Name, err)
+			}
+		}
+	}
+	if *historyPath != "" {
+		checker.storage = diskStorage{historyFile: *historyPath}
+	} else if checker.storage, err = executor.storage(); err != nil {
+		return err
+	}
+	if *manifestPath != "" {
+		if checker.manifest, err = loadFreshnessManifest(*manifestPath); err != nil {
+			return err
+		}
+	}
+
+	if *serve != "" {
+		checker.allowPaths = false
+		fmt.Fprintf(os.Stderr, "🩺 Serving page health at http://%s/health?page=... (badges at /badge); Ctrl-C to stop\n", *serve)
+		return checker.serve(ctx, *serve)
+	}
+
+	var pages []PageHealth
+	for _, page := range flags.Args() {
+		health, err := checker.health(ctx, page, time.Now())
+		if err != nil {
+			return err
+		}
+		pages = append(pages, health)
+	}
+
+	switch *format {
+	case FormatJSON, formatShields:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		for _, health := range pages {
+			var value interface{} = health
+			if *format == formatShields {
+				value = health.badge()
+			}
+			if err := encoder.Encode(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatPlain:
+		for _, health := range pages {
+			line := fmt.Sprintf("%s %s: %s", healthIcons[health.Status], health.Page, health.Status)
+			if health.Reason != "" {
+				line += " (" + health.Reason + ")"
+			}
+			fmt.Println(line)
+			for _, quarantine := range health.Quarantines {
+				fmt.Printf("   🔒 %s quarantined: %s\n", quarantine.Sample, quarantine.Reason)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown health format: %s", *format)
+	}
+}
+
+// formatShields is the health command's badge output format
+const formatShields = "shields"
+
+var healthIcons = map[string]string{
+	HealthFailing:     "❌",
+	HealthQuarantined: "🔒",
+	HealthStale:       "⚠️ ",
+	HealthUnknown:     "❔",
+	HealthHealthy:     "✅",
+}
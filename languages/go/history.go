@@ -0,0 +1,184 @@
+package main
+
+// Run history
+// Each run can append a record of every sample's status, build time, and
+// binary size to a JSON-lines file, giving trends across runs. A snippet
+// whose binary suddenly grows has usually started importing the wrong
+// package and dragging in a large dependency tree.
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HistoryRecord is one run in the history file
+type HistoryRecord struct {
+	Time time.Time `json:"time"`
+	// Scheduled marks runs that file issues, whose consecutive failures
+	// count towards issues.after_failures
+	Scheduled bool            `json:"scheduled,omitempty"`
+	Samples   []SampleHistory `json:"samples"`
+}
+
+// SampleHistory is one sample's measurements in a run
+type SampleHistory struct {
+	ID string `json:"id"`
+	// Location is the sample's page:line in that run, used to follow a
+	// sample whose code changed, and with it its ID
+	Location      string  `json:"location,omitempty"`
+	Status        string  `json:"status"`
+	BuildTime     float64 `json:"build_time"`
+	BinarySize    int64   `json:"binary_size"`
+	ExecutionTime float64 `json:"execution_time"`
+}
+
+// BuildRegression is a sample whose binary grew sharply since it was last recorded
+type BuildRegression struct {
+	ID             string    `json:"id"`
+	Location       string    `json:"location"`
+	PreviousSize   int64     `json:"previous_size"`
+	Size           int64     `json:"size"`
+	PreviousRun    time.Time `json:"previous_run"`
+	PreviousBuild  float64   `json:"previous_build_time"`
+	BuildTime      float64   `json:"build_time"`
+	GrowthFraction float64   `json:"growth_fraction"`
+}
+
+// historyRecord summarizes a run's results for the history file
+func historyRecord(results []TestResult, now time.Time) HistoryRecord {
+	record := HistoryRecord{Time: now.UTC().Truncate(time.Second)}
+	for _, result := range results {
+		if result.Status == StatusSkipped {
+			continue
+		}
+		record.Samples = append(record.Samples, SampleHistory{
+			ID:            result.Sample.ID,
+			Location:      fmt.Sprintf("%s:%d", result.Sample.Page, result.Sample.LineNumber),
+			Status:        result.Status,
+			BuildTime:     result.BuildTime,
+			BinarySize:    result.BinarySize,
+			ExecutionTime: result.ExecutionTime,
+		})
+	}
+	return record
+}
+
+// sample finds a sample in the record by ID, then by its page:line for a
+// sample whose code changed
+func (r HistoryRecord) sample(id, location string) (SampleHistory, bool) {
+	for _, sample := range r.Samples {
+		if sample.ID == id {
+			return sample, true
+		}
+	}
+	for _, sample := range r.Samples {
+		if sample.Location == location {
+			return sample, true
+		}
+	}
+	return SampleHistory{}, false
+}
+
+// loadHistory reads every record in a history file, oldest first
+func loadHistory(path string) ([]HistoryRecord, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []HistoryRecord
+	lines := bufio.NewScanner(file)
+	lines.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for lines.Scan() {
+		if len(lines.Bytes()) == 0 {
+			continue
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(lines.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("parsing %s: %v", path, err)
+		}
+		records = append(records, record)
+	}
+	return records, lines.Err()
+}
+
+// appendHistory adds a record to the end of a history file
+func appendHistory(path string, record HistoryRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// buildRegressions compares each built sample with its most recent earlier
+// measurement and flags binaries that grew by more than reporting.history.size_growth
+func (e *GoExecutor) buildRegressions(history []HistoryRecord, current HistoryRecord) []BuildRegression {
+	config := configSection(configSection(e.FrameworkConfig, "reporting"), "history")
+	growthPercent := configInt(config, "size_growth_percent", 50)
+	minGrowth := int64(configInt(config, "min_size_growth_kb", 1024)) * 1024
+
+	type previous struct {
+		sample SampleHistory
+		run    time.Time
+	}
+	// Samples are matched by ID, then by location for samples whose code
+	// changed. Records from before stable IDs used the location as the ID.
+	byID := make(map[string]previous)
+	byLocation := make(map[string]previous)
+	for _, record := range history {
+		for _, sample := range record.Samples {
+			if sample.BinarySize == 0 {
+				continue
+			}
+			location := sample.Location
+			if location == "" {
+				location = sample.ID
+			}
+			byID[sample.ID] = previous{sample, record.Time}
+			byLocation[location] = previous{sample, record.Time}
+		}
+	}
+
+	var regressions []BuildRegression
+	for _, sample := range current.Samples {
+		before, ok := byID[sample.ID]
+		if !ok {
+			before, ok = byLocation[sample.Location]
+		}
+		if !ok || sample.BinarySize == 0 {
+			continue
+		}
+		growth := sample.BinarySize - before.sample.BinarySize
+		if growth < minGrowth || growth*100 <= before.sample.BinarySize*int64(growthPercent) {
+			continue
+		}
+		regressions = append(regressions, BuildRegression{
+			ID:             sample.ID,
+			Location:       sample.Location,
+			PreviousSize:   before.sample.BinarySize,
+			Size:           sample.BinarySize,
+			PreviousRun:    before.run,
+			PreviousBuild:  before.sample.BuildTime,
+			BuildTime:      sample.BuildTime,
+			GrowthFraction: float64(growth) / float64(before.sample.BinarySize),
+		})
+	}
+	return regressions
+}
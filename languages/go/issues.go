@@ -0,0 +1,357 @@
+package main
+
+// Filing issues for persistent failures
+// A sample failing one scheduled run may be a blip; one failing run after
+// run is broken docs nobody has noticed. run --file-issues opens a GitHub
+// issue in the docs repo for every sample that has failed the last
+// issues.after_failures scheduled runs and isn't quarantined, or updates
+// the issue already open for it. Issues carry a hidden marker with the
+// sample's stable ID, so a sample never gets two, wherever it moves on its
+// page. Requests are spaced out and back off on GitHub's rate limits, and a
+// run files at most issues.max_per_run.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// issueMarkerRegex finds the sample ID marker in an issue body
+var issueMarkerRegex = regexp.MustCompile(`<!-- dgtest-sample: (\S+) -->`)
+
+// maxRateLimitWait is the longest the client sleeps for a rate limit to
+// reset; a longer wait stops filing for the run
+const maxRateLimitWait = 2 * time.Minute
+
+// issueOutputLines is how much of a failure's output an issue quotes
+const issueOutputLines = 40
+
+// PersistentFailure is a sample that failed every one of its recent scheduled runs
+type PersistentFailure struct {
+	Result TestResult
+	// Runs is how many scheduled runs in a row it failed, this one included
+	Runs int
+	// Since is the first of those runs
+	Since time.Time
+}
+
+// persistentFailures returns the failed or timed out results that also
+// failed the previous scheduled runs, afterFailures in all, and aren't
+// quarantined. history must end with this run's record. Runs that didn't
+// include a sample, such as --product runs, neither break nor extend its
+// streak.
+func persistentFailures(results []TestResult, history []HistoryRecord, afterFailures int) []PersistentFailure {
+	var failures []PersistentFailure
+	for _, result := range results {
+		if result.Quarantine != nil || (result.Status != StatusFailed && result.Status != StatusTimeout) {
+			continue
+		}
+		location := fmt.Sprintf("%s:%d", result.Sample.Page, result.Sample.LineNumber)
+		failure := PersistentFailure{Result: result}
+		for i := len(history) - 1; i >= 0; i-- {
+			if !history[i].Scheduled {
+				continue
+			}
+			recorded, ok := history[i].sample(result.Sample.ID, location)
+			if !ok {
+				continue
+			}
+			if recorded.Status != StatusFailed && recorded.Status != StatusTimeout {
+				break
+			}
+			failure.Runs++
+			failure.Since = history[i].Time
+		}
+		if failure.Runs >= afterFailures {
+			failures = append(failures, failure)
+		}
+	}
+	return failures
+}
+
+// issueFiler files and updates issues in the docs repo
+type issueFiler struct {
+	client   *githubClient
+	labels   []string
+	maxFiled int
+}
+
+// newIssueFiler configures filing from the issues section, with the token
+// from the environment variable it names (GITHUB_TOKEN by default)
+func (e *GoExecutor) newIssueFiler() (*issueFiler, int, error) {
+	config := configSection(e.FrameworkConfig, "issues")
+	repo := configString(config, "repo", "")
+	if strings.Count(repo, "/") != 1 {
+		return nil, 0, fmt.Errorf("issues.repo must name the docs repo as owner/name, got %q", repo)
+	}
+	tokenEnv := configString(config, "token_env", "GITHUB_TOKEN")
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return nil, 0, fmt.Errorf("filing issues needs a GitHub token in %s", tokenEnv)
+	}
+	afterFailures := configInt(config, "after_failures", 3)
+	if afterFailures < 1 {
+		return nil, 0, fmt.Errorf("issues.after_failures must be at least 1, got %d", afterFailures)
+	}
+
+	filer := &issueFiler{
+		client: &githubClient{
+			apiURL:   strings.TrimRight(configString(config, "api_url", "https://api.github.com"), "/"),
+			repo:     repo,
+			token:    token,
+			interval: time.Duration(configInt(config, "request_interval_ms", 1000)) * time.Millisecond,
+			client:   &http.Client{Timeout: 30 * time.Second},
+		},
+		labels:   configStrings(config, "labels"),
+		maxFiled: configInt(config, "max_per_run", 10),
+	}
+	return filer, afterFailures, nil
+}
+
+// githubIssue is the part of a GitHub issue the filer reads
+type githubIssue struct {
+	Number      int             `json:"number"`
+	HTMLURL     string          `json:"html_url"`
+	Body        string          `json:"body"`
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
+}
+
+// openIssues returns the open issues carrying the filer's labels, by the
+// sample ID in their marker
+func (f *issueFiler) openIssues(ctx context.Context) (map[string]githubIssue, error) {
+	listed, err := f.listOpenIssues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	issues := make(map[string]githubIssue)
+	for _, issue := range listed {
+		if match := issueMarkerRegex.FindStringSubmatch(issue.Body); match != nil {
+			issues[match[1]] = issue
+		}
+	}
+	return issues, nil
+}
+
+// listOpenIssues returns every open issue carrying the filer's labels
+func (f *issueFiler) listOpenIssues(ctx context.Context) ([]githubIssue, error) {
+	var issues []githubIssue
+	for page := 1; ; page++ {
+		query := url.Values{"state": {"open"}, "per_page": {"100"}, "page": {strconv.Itoa(page)}}
+		if len(f.labels) > 0 {
+			query.Set("labels", strings.Join(f.labels, ","))
+		}
+		var batch []githubIssue
+		if err := f.client.do(ctx, http.MethodGet, "/issues?"+query.Encode(), nil, &batch); err != nil {
+			return nil, err
+		}
+		for _, issue := range batch {
+			// The issues API lists pull requests too
+			if issue.PullRequest == nil {
+				issues = append(issues, issue)
+			}
+		}
+		if len(batch) < 100 {
+			return issues, nil
+		}
+	}
+}
+
+// file opens an issue for each failure without one and updates the body of
+// those already open, returning the issues' URLs. artifacts, when not
+// empty, is where the run's failure artifacts were stored.
+func (f *issueFiler) file(ctx context.Context, failures []PersistentFailure, artifacts string) ([]string, error) {
+	if len(failures) == 0 {
+		return nil, nil
+	}
+	open, err := f.openIssues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var filed []string
+	for i, failure := range failures {
+		if f.maxFiled > 0 && i >= f.maxFiled {
+			fmt.Fprintf(os.Stderr, "⚠️  Filed the first %d of %d persistent failures (issues.max_per_run)\n", f.maxFiled, len(failures))
+			break
+		}
+		sample := failure.Result.Sample
+		body := issueBody(failure, artifacts)
+
+		var issue githubIssue
+		if existing, ok := open[sample.ID]; ok {
+			err = f.client.do(ctx, http.MethodPatch, fmt.Sprintf("/issues/%d", existing.Number), map[string]interface{}{"body": body}, &issue)
+		} else {
+			request := map[string]interface{}{"title": issueTitle(sample), "body": body}
+			if len(f.labels) > 0 {
+				request["labels"] = f.labels
+			}
+			err = f.client.do(ctx, http.MethodPost, "/issues", request, &issue)
+		}
+		if err != nil {
+			return filed, fmt.Errorf("filing issue for %s: %v", sample.ID, err)
+		}
+		filed = append(filed, issue.HTMLURL)
+	}
+	return filed, nil
+}
+
+// issueTitle names a failing sample by its page, which doesn't change as
+// the sample moves
+func issueTitle(sample CodeSample) string {
+	return fmt.Sprintf("Go docs sample %s is failing on %s", sample.ID, sample.Page)
+}
+
+// issueBody describes a persistent failure. Its output should already be
+// redacted.
+func issueBody(failure PersistentFailure, artifacts string) string {
+	result := failure.Result
+	sample := result.Sample
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<!-- dgtest-sample: %s -->\n", sample.ID)
+	fmt.Fprintf(&body, "The Go sample `%s` at `%s:%d` has failed the last %d scheduled runs, since %s.\n\n",
+		sample.ID, sample.Page, sample.LineNumber, failure.Runs, failure.Since.UTC().Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&body, "- **Status:** %s", result.Status)
+	if result.ErrorCategory != "" {
+		fmt.Fprintf(&body, " (%s)", result.ErrorCategory)
+	}
+	body.WriteString("\n")
+	if result.ErrorMessage != "" {
+		fmt.Fprintf(&body, "- **Error:** %s\n", strings.TrimSpace(result.ErrorMessage))
+	}
+	if artifacts != "" {
+		fmt.Fprintf(&body, "- **Artifacts:** `%s`\n", artifacts)
+	}
+
+	output := strings.TrimSpace(result.Stderr)
+	if output == "" {
+		output = strings.TrimSpace(result.Stdout)
+	}
+	if output != "" {
+		lines := strings.Split(output, "\n")
+		if len(lines) > issueOutputLines {
+			lines = append([]string{"..."}, lines[len(lines)-issueOutputLines:]...)
+		}
+		fmt.Fprintf(&body, "\n<details><summary>Output</summary>\n\n```\n%s\n```\n</details>\n", strings.Join(lines, "\n"))
+	}
+	fmt.Fprintf(&body, "\n<details><summary>Code</summary>\n\n```go\n%s\n```\n</details>\n", sample.Code)
+
+	body.WriteString("\nFix the sample, or quarantine it under `quarantine` in `framework_config.yaml` while it can't be fixed. ")
+	body.WriteString("This issue is updated by each scheduled run the sample fails; close it once the sample passes.\n")
+	return body.String()
+}
+
+// githubClient calls the GitHub REST API for one repo, spacing requests
+// apart and waiting out rate limits
+type githubClient struct {
+	apiURL   string
+	repo     string
+	token    string
+	interval time.Duration
+	last     time.Time
+	client   *http.Client
+}
+
+// do sends a request for a path under the repo, decoding the response into
+// out. A request refused by a rate limit that resets soon is retried.
+func (g *githubClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var payload []byte
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := sleepContext(ctx, time.Until(g.last.Add(g.interval))); err != nil {
+			return err
+		}
+		g.last = time.Now()
+
+		request, err := http.NewRequestWithContext(ctx, method, g.apiURL+"/repos/"+g.repo+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Accept", "application/vnd.github+json")
+		request.Header.Set("Authorization", "Bearer "+g.token)
+		request.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		request.Header.Set("User-Agent", "dgtest/"+executorVersion)
+		if payload != nil {
+			request.Header.Set("Content-Type", "application/json")
+		}
+
+		response, err := g.client.Do(request)
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if wait, limited := rateLimitWait(response, time.Now()); limited {
+			if attempt >= 2 || wait > maxRateLimitWait {
+				return fmt.Errorf("GitHub rate limit exceeded; it resets in %s", wait.Round(time.Second))
+			}
+			fmt.Fprintf(os.Stderr, "⏳ GitHub rate limit hit; waiting %s\n", wait.Round(time.Second))
+			if err := sleepContext(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+		if response.StatusCode/100 != 2 {
+			return fmt.Errorf("%s %s: %s: %s", method, path, response.Status, strings.TrimSpace(string(content)))
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(content, out)
+	}
+}
+
+// rateLimitWait reports whether a response was refused by a primary or
+// secondary rate limit, and how long to wait before retrying
+func rateLimitWait(response *http.Response, now time.Time) (time.Duration, bool) {
+	if response.StatusCode != http.StatusForbidden && response.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if response.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(response.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0).Sub(now) + time.Second, true
+		}
+	}
+	// GitHub asks for a minute's wait on a secondary limit without Retry-After
+	if response.StatusCode == http.StatusTooManyRequests {
+		return time.Minute, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
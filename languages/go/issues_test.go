@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPersistentFailures(t *testing.T) {
+	day := func(n int) time.Time { return time.Date(2026, 10, n, 6, 0, 0, 0, time.UTC) }
+	run := func(n int, scheduled bool, statuses map[string]string) HistoryRecord {
+		record := HistoryRecord{Time: day(n), Scheduled: scheduled}
+		for id, status := range statuses {
+			record.Samples = append(record.Samples, SampleHistory{ID: id, Location: "fern/pages/stt.mdx:" + id, Status: status})
+		}
+		return record
+	}
+	history := []HistoryRecord{
+		run(1, true, map[string]string{"a": StatusFailed, "b": StatusPassed, "c": StatusFailed}),
+		run(2, true, map[string]string{"a": StatusFailed, "b": StatusFailed, "c": StatusFailed}),
+		// Unscheduled runs and runs without a sample don't count
+		run(3, false, map[string]string{"a": StatusPassed, "b": StatusPassed}),
+		run(4, true, map[string]string{"b": StatusTimeout}),
+		run(5, true, map[string]string{"a": StatusFailed, "b": StatusFailed, "c": StatusFailed, "d": StatusFailed}),
+	}
+	result := func(id, status string) TestResult {
+		return TestResult{Sample: CodeSample{ID: id, Page: "fern/pages/stt.mdx"}, Status: status}
+	}
+	quarantined := result("c", StatusFailed)
+	quarantined.Quarantine = &Quarantine{Sample: "c", Reason: "API incident"}
+	results := []TestResult{
+		result("a", StatusFailed),
+		result("b", StatusFailed),
+		quarantined,
+		result("d", StatusFailed),
+		result("e", StatusPassed),
+	}
+
+	tests := []struct {
+		afterFailures int
+		want          map[string]int
+	}{
+		{1, map[string]int{"a": 3, "b": 3, "d": 1}},
+		{3, map[string]int{"a": 3, "b": 3}},
+		{4, map[string]int{}},
+	}
+	for _, test := range tests {
+		got := make(map[string]int)
+		for _, failure := range persistentFailures(results, history, test.afterFailures) {
+			got[failure.Result.Sample.ID] = failure.Runs
+			if id := failure.Result.Sample.ID; id == "a" && !failure.Since.Equal(day(1)) {
+				t.Errorf("after %d: a failing since %s, want %s", test.afterFailures, failure.Since, day(1))
+			}
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("after %d: failures = %v, want %v", test.afterFailures, got, test.want)
+			continue
+		}
+		for id, runs := range test.want {
+			if got[id] != runs {
+				t.Errorf("after %d: failures = %v, want %v", test.afterFailures, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	response := func(status int, headers map[string]string) *http.Response {
+		response := &http.Response{StatusCode: status, Header: http.Header{}}
+		for name, value := range headers {
+			response.Header.Set(name, value)
+		}
+		return response
+	}
+	tests := []struct {
+		name     string
+		response *http.Response
+		wait     time.Duration
+		limited  bool
+	}{
+		{"ok", response(http.StatusOK, nil), 0, false},
+		{"forbidden", response(http.StatusForbidden, nil), 0, false},
+		{"retry after", response(http.StatusForbidden, map[string]string{"Retry-After": "30"}), 30 * time.Second, true},
+		{"primary limit", response(http.StatusForbidden, map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(now.Add(90*time.Second).Unix(), 10),
+		}), 91 * time.Second, true},
+		{"remaining requests", response(http.StatusForbidden, map[string]string{
+			"X-RateLimit-Remaining": "12",
+			"X-RateLimit-Reset":     strconv.FormatInt(now.Unix(), 10),
+		}), 0, false},
+		{"secondary limit", response(http.StatusTooManyRequests, nil), time.Minute, true},
+		{"not found", response(http.StatusNotFound, map[string]string{"Retry-After": "30"}), 0, false},
+	}
+	for _, test := range tests {
+		wait, limited := rateLimitWait(test.response, now)
+		if wait != test.wait || limited != test.limited {
+			t.Errorf("%s: rateLimitWait = %s, %t, want %s, %t", test.name, wait, limited, test.wait, test.limited)
+		}
+	}
+}
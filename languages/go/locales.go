@@ -0,0 +1,213 @@
+package main
+
+// Localized docs parity
+// Translated docs trees mirror fern/pages under a directory per locale.
+// Prose is translated but code is not, so every translated page should carry
+// byte-identical copies of the English page's samples; anything else means
+// the English page changed and the translation wasn't refreshed. Translated
+// pages are filtered as the English pages they mirror, by the walk's
+// include and exclude globs and .dgtestignore.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LocaleParity compares one locale's translated pages with the English pages
+type LocaleParity struct {
+	Locale string `json:"locale"`
+	// Pages is how many English pages with samples have a translation
+	Pages int `json:"pages"`
+	// Untranslated lists English pages with samples the locale lacks
+	Untranslated []string       `json:"untranslated,omitempty"`
+	Stale        []StaleSnippet `json:"stale,omitempty"`
+}
+
+// StaleSnippet is a translated sample that doesn't match the English one
+type StaleSnippet struct {
+	Page        string `json:"page"`
+	EnglishLine int    `json:"english_line,omitempty"`
+	LocaleLine  int    `json:"locale_line,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// translationsPath returns the directory holding one translated tree per locale
+func (e *GoExecutor) translationsPath(documentationPath string) string {
+	documentation := configSection(e.FrameworkConfig, "documentation")
+	return filepath.Join(documentationPath, configString(documentation, "translations_path", filepath.Join("fern", "translations")))
+}
+
+// CheckLocaleParity compares the samples of every translated page with
+// those of the English page it translates
+func (e *GoExecutor) CheckLocaleParity(ctx context.Context, documentationPath string) ([]LocaleParity, error) {
+	roots, err := e.docsRoots(documentationPath)
+	if err != nil {
+		return nil, err
+	}
+	root := roots[0]
+	english, err := e.extractRoot(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := newWalkFilter(root)
+	if err != nil {
+		return nil, err
+	}
+
+	pagesPath := filepath.Join(root.Path, filepath.FromSlash(root.PagesPath))
+	englishPages := make(map[string][]CodeSample)
+	for _, sample := range english {
+		page, err := filepath.Rel(pagesPath, sample.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		englishPages[page] = append(englishPages[page], sample)
+	}
+
+	translations := e.translationsPath(documentationPath)
+	entries, err := os.ReadDir(translations)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report []LocaleParity
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		parity, err := e.checkLocale(ctx, entry.Name(), filepath.Join(translations, entry.Name()), englishPages, func(page string) bool {
+			return filter.visits(root, filepath.Join(pagesPath, page))
+		})
+		if err != nil {
+			return nil, err
+		}
+		report = append(report, parity)
+	}
+	return report, nil
+}
+
+// checkLocale compares one locale's tree against the English pages,
+// reading only the translated pages included reports the docs walk visits
+func (e *GoExecutor) checkLocale(ctx context.Context, locale, root string, englishPages map[string][]CodeSample, included func(page string) bool) (LocaleParity, error) {
+	parity := LocaleParity{Locale: locale}
+
+	translatedPages := make(map[string][]CodeSample)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if !included(page) {
+			return nil
+		}
+
+		samples, err := e.ExtractSamplesFromFile(path)
+		if err != nil {
+			return err
+		}
+		translatedPages[page] = samples
+		return nil
+	})
+	if err != nil {
+		return parity, err
+	}
+
+	for _, page := range sortedKeys(pageSet(englishPages)) {
+		translated, ok := translatedPages[page]
+		if !ok {
+			parity.Untranslated = append(parity.Untranslated, page)
+			continue
+		}
+		parity.Pages++
+		parity.Stale = append(parity.Stale, compareSamples(page, englishPages[page], translated)...)
+	}
+
+	// Samples on pages that no longer exist in English are stale too
+	for _, page := range sortedKeys(pageSet(translatedPages)) {
+		if _, ok := englishPages[page]; ok || len(translatedPages[page]) == 0 {
+			continue
+		}
+		parity.Stale = append(parity.Stale, StaleSnippet{
+			Page:       page,
+			LocaleLine: translatedPages[page][0].LineNumber,
+			Reason:     "page has samples but no English page",
+		})
+	}
+
+	return parity, nil
+}
+
+// compareSamples pairs a page's English and translated samples in order
+func compareSamples(page string, english, translated []CodeSample) []StaleSnippet {
+	var stale []StaleSnippet
+	for i := 0; i < len(english) || i < len(translated); i++ {
+		switch {
+		case i >= len(translated):
+			stale = append(stale, StaleSnippet{
+				Page:        page,
+				EnglishLine: english[i].LineNumber,
+				Reason:      "sample missing from translation",
+			})
+		case i >= len(english):
+			stale = append(stale, StaleSnippet{
+				Page:       page,
+				LocaleLine: translated[i].LineNumber,
+				Reason:     "sample not on the English page",
+			})
+		case english[i].Code != translated[i].Code:
+			stale = append(stale, StaleSnippet{
+				Page:        page,
+				EnglishLine: english[i].LineNumber,
+				LocaleLine:  translated[i].LineNumber,
+				Reason:      "sample differs from English",
+			})
+		}
+	}
+	return stale
+}
+
+// pageSet returns the pages of a page-to-samples map as a set
+func pageSet(pages map[string][]CodeSample) map[string]bool {
+	set := make(map[string]bool, len(pages))
+	for page := range pages {
+		set[page] = true
+	}
+	return set
+}
+
+// staleLocales returns the locales with at least one stale snippet
+func staleLocales(report []LocaleParity) []string {
+	var locales []string
+	for _, parity := range report {
+		if len(parity.Stale) > 0 {
+			locales = append(locales, parity.Locale)
+		}
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// formatStaleSnippet describes a stale snippet for plain output
+func formatStaleSnippet(snippet StaleSnippet) string {
+	switch {
+	case snippet.EnglishLine == 0:
+		return fmt.Sprintf("%s:%d %s", snippet.Page, snippet.LocaleLine, snippet.Reason)
+	case snippet.LocaleLine == 0:
+		return fmt.Sprintf("%s (English line %d) %s", snippet.Page, snippet.EnglishLine, snippet.Reason)
+	default:
+		return fmt.Sprintf("%s:%d %s (English line %d)", snippet.Page, snippet.LocaleLine, snippet.Reason, snippet.EnglishLine)
+	}
+}
@@ -0,0 +1,1083 @@
+package main
+
+// CLI interface for integration with Python test runner
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	if serveWASM() {
+		return
+	}
+	if len(os.Args) < 2 {
+		fmt.Println("Go executor ready")
+		return
+	}
+
+	command := os.Args[1]
+	args := os.Args[2:]
+
+	// Ctrl-C cancels the command, stopping running samples and their tools
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	var err error
+	switch command {
+	case "run":
+		err = runCommand(ctx, args)
+	case "debug":
+		err = debugCommand(ctx, args)
+	case "diagnostics":
+		err = diagnosticsCommand(ctx, args)
+	case "generate-tests":
+		err = generateTestsCommand(ctx, args)
+	case "list":
+		err = listCommand(ctx, args)
+	case "mock":
+		err = mockCommand(ctx, args)
+	case "prefetch":
+		err = prefetchCommand(ctx, args)
+	case "locales":
+		err = localesCommand(ctx, args)
+	case "scopes":
+		err = scopesCommand(ctx, args)
+	case "api-surface":
+		err = apiSurfaceCommand(ctx, args)
+	case "freshness":
+		err = freshnessCommand(ctx, args)
+	case "errors":
+		err = errorsCommand(ctx, args)
+	case "complexity":
+		err = complexityCommand(ctx, args)
+	case "ergonomics":
+		err = ergonomicsCommand(ctx, args)
+	case "canary":
+		err = canaryCommand(ctx, args)
+	case "report":
+		err = reportCommand(ctx, args)
+	case "version":
+		err = versionCommand(ctx, args)
+	case "health":
+		err = healthCommand(ctx, args)
+	case "new-sample":
+		err = newSampleCommand(ctx, args)
+	case "webhook":
+		err = webhookCommand(ctx, args)
+	case "required-samples":
+		err = requiredSamplesCommand(ctx, args)
+	default:
+		err = fmt.Errorf("unknown command: %s", command)
+	}
+
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Go executor: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// docsFlags are the flags shared by every command that reads the docs tree
+type docsFlags struct {
+	docsPath       *string
+	configPath     *string
+	baseURL        *string
+	env            *string
+	requireVersion *string
+}
+
+func addDocsFlags(flags *flag.FlagSet) docsFlags {
+	return docsFlags{
+		docsPath:       flags.String("docs-path", "", "Path to documentation directory (default: the roots in documentation.roots)"),
+		configPath:     flags.String("config", "", "JSON file with language and framework configuration"),
+		baseURL:        flags.String("base-url", "", "Point samples at this API base URL, e.g. a mock server (overrides api.base_url)"),
+		env:            flags.String("env", "", "Run against this API environment from api.environments, e.g. staging"),
+		requireVersion: flags.String("require-version", "", "Fail unless this executor is at least this version, e.g. v1.4.0 (overrides require_version)"),
+	}
+}
+
+// executor builds an executor from the configuration
+func (f docsFlags) executor() (*GoExecutor, error) {
+	langConfig, frameworkConfig, err := loadConfig(*f.configPath)
+	if err != nil {
+		return nil, err
+	}
+	required := *f.requireVersion
+	if required == "" {
+		required = configString(frameworkConfig, "require_version", "")
+	}
+	if err := checkRequiredVersion(required); err != nil {
+		return nil, err
+	}
+	executor, err := NewGoExecutor(langConfig, frameworkConfig)
+	if err != nil {
+		return nil, err
+	}
+	environment, err := executor.apiEnvironment(*f.env)
+	if err != nil {
+		return nil, err
+	}
+	executor.useEnvironment(environment)
+	if *f.baseURL != "" {
+		executor.BaseURL = *f.baseURL
+	}
+	return executor, nil
+}
+
+// load builds an executor from the configuration and extracts the samples
+// from --docs-path or, without it, every configured documentation root
+func (f docsFlags) load(ctx context.Context) (*GoExecutor, []CodeSample, error) {
+	executor, err := f.executor()
+	if err != nil {
+		return nil, nil, err
+	}
+	samples, err := f.extract(ctx, executor)
+	if err != nil {
+		return nil, nil, err
+	}
+	return executor, samples, nil
+}
+
+// extract extracts the samples from --docs-path or, without it, every
+// configured documentation root
+func (f docsFlags) extract(ctx context.Context, executor *GoExecutor) ([]CodeSample, error) {
+	roots, err := executor.docsRoots(*f.docsPath)
+	if err != nil {
+		return nil, err
+	}
+	return executor.ExtractRoots(ctx, roots)
+}
+
+// runCommand extracts, executes, and reports on every sample under a docs
+// tree, or on the single sample at page.mdx:line when one is given
+func runCommand(ctx context.Context, args []string) (err error) {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain, json, markdown, or problems")
+	outputPath := flags.String("output", "", "Write the report to this file instead of stdout")
+	product := flags.String("product", "", "Comma-separated products to test, e.g. stt-live,tts")
+	live := flags.Bool("live", false, "Run network-bound samples against the live API using DEEPGRAM_API_KEY")
+	mock := flags.Bool("mock", false, "Run network-bound samples against a local mock of the API")
+	faults := flags.String("faults", "", "Comma-separated faults the mock injects: rate_limit, server_error, unauthorized, malformed_json, disconnect, or all (overrides mocking.faults)")
+	tier := flags.String("tier", "", "API key tier the mock simulates: owner, member, usage_only, expired, or one from mocking.tiers (overrides mocking.tier)")
+	failOn := flags.String("fail-on", "failed,timeout", "Comma-separated statuses that make the run exit non-zero, or \"none\"")
+	keepWorkspace := flags.Bool("keep-workspace", true, "Leave the workspace in place when running a single sample")
+	stamp := flags.String("stamp", "", "Record last_verified for pages whose samples all passed in this JSON manifest")
+	stampPages := flags.Bool("stamp-frontmatter", false, "Record last_verified in the frontmatter of pages whose samples all passed")
+	golden := flags.String("golden", "", "Compare sample output with golden files in this directory")
+	updateGolden := flags.Bool("update-golden", false, "Write the output of passing samples to the --golden directory")
+	historyPath := flags.String("history", "", "Append per-sample build and run measurements to this JSON-lines file and flag binaries that grew")
+	store := flags.Bool("store", false, "Record history, the report, and failure artifacts in the backend configured under storage (local disk by default)")
+	fileIssues := flags.Bool("file-issues", false, "Record this as a scheduled run and open or update a GitHub issue for samples failing issues.after_failures scheduled runs in a row (needs --store or --history)")
+	coverDir := flags.String("coverage", "", "Collect SDK coverage into this directory and include it in the report (needs a local SDK checkout)")
+	manifestPath := flags.String("manifest", "", "Also write the run manifest (toolchain, commits, config hash, host) to this JSON file")
+	triggeredBy := flags.String("triggered-by", "", "Revalidate only what an event affects: \"sdk-release\" followed by the release, e.g. --triggered-by sdk-release v3.2.0")
+	plan := flags.Bool("plan", false, "Print which samples would run, how, and with what substitutions and env, without running them")
+	auditPath := flags.String("audit-log", "", "Log every command the executor spawns, with its arguments, directory, redacted env, duration, and exit status, to this JSON-lines file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *plan && *format != FormatPlain && *format != FormatJSON {
+		return fmt.Errorf("--plan supports plain or json format")
+	}
+	if *updateGolden && *golden == "" {
+		return fmt.Errorf("--update-golden needs --golden")
+	}
+	if *faults != "" && !*mock {
+		return fmt.Errorf("--faults needs --mock")
+	}
+	if *tier != "" && !*mock {
+		return fmt.Errorf("--tier needs --mock")
+	}
+	if *store && *historyPath != "" {
+		return fmt.Errorf("--store keeps history in the configured storage; drop --history")
+	}
+	if *fileIssues && !*store && *historyPath == "" {
+		return fmt.Errorf("--file-issues counts failures in the run history; add --store or --history")
+	}
+	// An SDK release trigger takes the release as its argument
+	release := ""
+	if *triggeredBy != "" {
+		if *triggeredBy != TriggerSDKRelease {
+			return fmt.Errorf("unknown --triggered-by event %q (expected %s)", *triggeredBy, TriggerSDKRelease)
+		}
+		if release = flags.Arg(0); flags.NArg() != 1 {
+			return fmt.Errorf("--triggered-by %s needs the release, e.g. v3.2.0", TriggerSDKRelease)
+		}
+		if _, ok := parseVersion(release); !ok || !strings.HasPrefix(release, "v") {
+			return fmt.Errorf("SDK release %q isn't a version like v3.2.0", release)
+		}
+	}
+
+	if flags.NArg() > 0 && release == "" {
+		// A single sample prints its own output rather than a report
+		var reportFlags []string
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "output", "product", "store", "history", "stamp", "stamp-frontmatter", "coverage", "file-issues", "manifest":
+				reportFlags = append(reportFlags, "--"+f.Name)
+			case "format":
+				if !*plan {
+					reportFlags = append(reportFlags, "--"+f.Name)
+				}
+			}
+		})
+		if len(reportFlags) > 0 {
+			return fmt.Errorf("%s can't be used when running a single sample", strings.Join(reportFlags, ", "))
+		}
+		var executor *GoExecutor
+		if executor, err = docs.executor(); err != nil {
+			return err
+		}
+		executor.Live = *live
+		if err := executor.checkEnvironmentKey(); err != nil {
+			return err
+		}
+		executor.KeepWorkspace = *keepWorkspace
+		executor.GoldenDir, executor.UpdateGolden = *golden, *updateGolden
+		if *mock {
+			if err := executor.startMock(*faults, *tier); err != nil {
+				return err
+			}
+			defer executor.Mock.Close()
+		}
+		if *auditPath != "" && !*plan {
+			if executor.Audit, err = openAuditLog(*auditPath); err != nil {
+				return err
+			}
+			defer closeAuditLog(executor.Audit, &err)
+		}
+		if *plan {
+			_, chain, err := sampleChain(executor, flags.Arg(0))
+			if err != nil {
+				return err
+			}
+			return writePlan(os.Stdout, *format, executor.Plan(chain))
+		}
+		return runOneSample(ctx, executor, flags.Arg(0))
+	}
+
+	products, err := parseProductFilter(*product)
+	if err != nil {
+		return err
+	}
+
+	executor, err := docs.executor()
+	if err != nil {
+		return err
+	}
+	// The log is opened first so it records checking out git roots
+	if *auditPath != "" && !*plan {
+		if executor.Audit, err = openAuditLog(*auditPath); err != nil {
+			return err
+		}
+		defer closeAuditLog(executor.Audit, &err)
+	}
+	samples, err := docs.extract(ctx, executor)
+	if err != nil {
+		return err
+	}
+	executor.Live = *live
+	if err := executor.checkEnvironmentKey(); err != nil {
+		return err
+	}
+	executor.GoldenDir, executor.UpdateGolden = *golden, *updateGolden
+	if *mock {
+		if err := executor.startMock(*faults, *tier); err != nil {
+			return err
+		}
+		defer executor.Mock.Close()
+	}
+
+	// Filing is configured before the run so a bad config fails fast
+	var filer *issueFiler
+	afterFailures := 0
+	if *fileIssues && !*plan {
+		if filer, afterFailures, err = executor.newIssueFiler(); err != nil {
+			return err
+		}
+	}
+
+	if *coverDir != "" {
+		if _, _, ok := executor.localSDKModule(); !ok {
+			return fmt.Errorf("--coverage needs a local SDK checkout with a go.mod at %s", executor.SDKPath)
+		}
+		if executor.CoverDir, err = filepath.Abs(*coverDir); err != nil {
+			return err
+		}
+		if !*plan {
+			if err := resetCoverDir(executor.CoverDir); err != nil {
+				return err
+			}
+		}
+	}
+
+	allSamples := samples
+	selected := filterByProduct(samples, products)
+	if release != "" {
+		executor.SDKRelease = release
+		impact := executor.sdkReleaseImpact(ctx, release)
+		selected = affectedByRelease(selected, impact)
+		writeReleaseImpact(os.Stderr, impact, len(selected))
+	}
+	samples = includeDependencies(selected, samples)
+
+	if *plan {
+		return writePlan(os.Stdout, *format, executor.Plan(samples))
+	}
+
+	roots, err := executor.docsRoots(*docs.docsPath)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	results, summary := executor.RunSamples(ctx, samples)
+	executor.applyQuarantines(results, time.Now())
+	report := Report{
+		Language: "go",
+		Results:  results,
+		Summary:  summary,
+		Manifest: executor.runManifest(context.WithoutCancel(ctx), roots, started),
+	}
+	if release != "" {
+		report.Manifest.Trigger = TriggerSDKRelease + " " + release
+	}
+	if executor.Environment != nil {
+		report.Environment = executor.Environment.Name
+	}
+
+	if executor.CoverDir != "" {
+		if report.Coverage, err = executor.SDKCoverage(); err != nil {
+			return err
+		}
+	}
+
+	// Storage is written even for an interrupted run, like its report
+	storeCtx := context.WithoutCancel(ctx)
+	var storage Storage
+	switch {
+	case *store:
+		if storage, err = executor.storage(); err != nil {
+			return err
+		}
+	case *historyPath != "":
+		storage = diskStorage{historyFile: *historyPath}
+	}
+	var history []HistoryRecord
+	if storage != nil {
+		if history, err = storage.LoadHistory(storeCtx); err != nil {
+			return err
+		}
+		record := historyRecord(results, time.Now())
+		// An interrupted run's failures may be the interruption, so it
+		// doesn't count toward a failure streak
+		record.Scheduled = *fileIssues && ctx.Err() == nil
+		report.BuildRegressions = executor.buildRegressions(history, record)
+		if err := storage.AppendHistory(storeCtx, record); err != nil {
+			return err
+		}
+		history = append(history, record)
+	}
+
+	output := os.Stdout
+	if *outputPath != "" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+
+	rules, err := executor.redactionRules()
+	if err != nil {
+		return err
+	}
+	redacted := redactReport(report, rules)
+	if err := writeReport(output, *format, redacted); err != nil {
+		return err
+	}
+	artifacts := ""
+	if *store {
+		run, err := storeRun(storeCtx, storage, redacted)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "🗄️  Stored %s in %s\n", run, storage.Location())
+		artifacts = strings.TrimRight(storage.Location(), "/") + "/" + run
+	}
+	// An interrupted run's failures may be the interruption, so it files nothing
+	if filer != nil && ctx.Err() == nil {
+		filed, err := filer.file(storeCtx, persistentFailures(redacted.Results, history, afterFailures), artifacts)
+		for _, issue := range filed {
+			fmt.Fprintf(os.Stderr, "📮 Filed %s\n", issue)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if *manifestPath != "" {
+		if err := writeManifest(*manifestPath, report.Manifest); err != nil {
+			return err
+		}
+	}
+
+	// Pages are only stamped when every one of their samples ran and passed,
+	// so a --product run never vouches for samples it skipped
+	if *stamp != "" || *stampPages {
+		verified := verifiedPages(allSamples, results)
+		now := time.Now()
+		if *stamp != "" {
+			if err := stampManifest(*stamp, allSamples, verified, now); err != nil {
+				return err
+			}
+		}
+		if *stampPages {
+			if err := stampFrontmatter(verified, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	// An interrupted run still writes its partial report, but never passes
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("run interrupted: %v", err)
+	}
+	return checkFailOn(*failOn, withoutQuarantined(summary, results))
+}
+
+// runOneSample executes the sample at a page.mdx:line position with verbose
+// output, the loop a writer wants when fixing a snippet
+func runOneSample(ctx context.Context, executor *GoExecutor, target string) error {
+	sample, chain, err := sampleChain(executor, target)
+	if err != nil {
+		return err
+	}
+
+	// Dependencies get a line each; the target gets everything
+	var result TestResult
+	results, _ := executor.RunSamples(ctx, chain)
+	for _, candidate := range results {
+		if candidate.Sample.LineNumber == sample.LineNumber {
+			result = candidate
+			continue
+		}
+		writePlainResult(os.Stdout, candidate)
+	}
+	writeVerboseResult(os.Stdout, result)
+
+	if result.Status == StatusFailed || result.Status == StatusTimeout {
+		return fmt.Errorf("sample %s:%d %s", result.Sample.FilePath, result.Sample.LineNumber, result.Status)
+	}
+	return nil
+}
+
+// sampleChain resolves a page.mdx:line argument to its sample, and returns
+// it together with the samples on the page it depends on
+func sampleChain(executor *GoExecutor, target string) (CodeSample, []CodeSample, error) {
+	sample, err := sampleAtTarget(executor, target)
+	if err != nil {
+		return CodeSample{}, nil, err
+	}
+	page, err := executor.ExtractSamplesFromFile(sample.FilePath)
+	if err != nil {
+		return CodeSample{}, nil, err
+	}
+	return sample, includeDependencies([]CodeSample{sample}, page), nil
+}
+
+// sampleAtTarget resolves a page.mdx:line argument to the sample containing that line
+func sampleAtTarget(executor *GoExecutor, target string) (CodeSample, error) {
+	separator := strings.LastIndex(target, ":")
+	if separator < 0 {
+		return CodeSample{}, fmt.Errorf("expected page.mdx:line, got %q", target)
+	}
+	line, err := strconv.Atoi(target[separator+1:])
+	if err != nil {
+		return CodeSample{}, fmt.Errorf("invalid line number in %q", target)
+	}
+	return executor.SampleAt(target[:separator], line)
+}
+
+// checkFailOn returns an error if the run produced any sample whose status is
+// listed in failOn, letting CI treat timeouts differently from genuine failures
+func checkFailOn(failOn string, summary RunSummary) error {
+	if failOn == "none" {
+		return nil
+	}
+
+	var matched []string
+	for _, status := range strings.Split(failOn, ",") {
+		status = strings.TrimSpace(status)
+		switch status {
+		case StatusPassed, StatusFailed, StatusTimeout, StatusSkipped:
+		default:
+			return fmt.Errorf("unknown status in --fail-on: %q", status)
+		}
+		if count := summary.ByStatus[status]; count > 0 {
+			matched = append(matched, fmt.Sprintf("%s=%d", status, count))
+		}
+	}
+
+	if len(matched) > 0 {
+		return fmt.Errorf("samples matched --fail-on (%s)", strings.Join(matched, ", "))
+	}
+	return nil
+}
+
+// ListedSample identifies a sample for tools that drive dgtest, such as the
+// dgtest package's go test integration
+type ListedSample struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Target  string `json:"target"`
+	Product string `json:"product"`
+	Skip    string `json:"skip,omitempty"`
+}
+
+// listCommand prints the samples under a docs tree with the page.mdx:line
+// target that runs each one
+func listCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Output format: plain or json")
+	product := flags.String("product", "", "Comma-separated products to list, e.g. stt-live,tts")
+	live := flags.Bool("live", false, "List network-bound samples as runnable")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	products, err := parseProductFilter(*product)
+	if err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+	executor.Live = *live
+
+	listed := []ListedSample{}
+	for _, sample := range filterByProduct(samples, products) {
+		listed = append(listed, ListedSample{
+			ID:      sample.ID,
+			Name:    sampleTestName(sample.Page, sample.LineNumber),
+			Target:  fmt.Sprintf("%s:%d", sample.FilePath, sample.LineNumber),
+			Product: sampleProduct(sample),
+			Skip:    executor.skipReason(sample),
+		})
+	}
+
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listed)
+	case FormatPlain:
+		for _, sample := range listed {
+			fmt.Printf("%s\t%s\t%s\n", sample.Target, sample.Product, sample.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown list format: %s", *format)
+	}
+}
+
+// generateTestsCommand writes a go test package covering every sample
+func generateTestsCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("generate-tests", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	out := flags.String("out", "./docSampleTests", "Directory to write the test package to")
+	run := flags.Bool("run", false, "Run samples in the generated tests instead of only building them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := executor.GenerateTests(ctx, samples, *out, *run); err != nil {
+		return err
+	}
+	fmt.Printf("🧪 Wrote %d sample subtests to %s; run with: cd %s && go test ./...\n", len(samples), *out, *out)
+	return nil
+}
+
+// mockCommand serves the mock API until interrupted, for pointing samples
+// at it by hand
+func mockCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("mock", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:8443", "Address to listen on")
+	useTLS := flags.Bool("tls", mockTLSTrusted(), "Serve TLS with an ephemeral CA (default mocking.tls, else off on macOS and Windows, whose TLS ignores SSL_CERT_FILE)")
+	faultKinds := flags.String("faults", "", "Comma-separated faults to inject: rate_limit, server_error, unauthorized, malformed_json, disconnect, or all")
+	faultRate := flags.Float64("fault-rate", 1, "Fraction of requests that get a fault")
+	seed := flags.Int64("seed", 1, "Seed for choosing which requests fail")
+	tierName := flags.String("tier", "", "API key tier to simulate: owner, member, usage_only, expired, or one from mocking.tiers")
+	configPath := flags.String("config", "", "JSON file with language and framework configuration, for mocking.tiers")
+	shellName := flags.String("shell", "", "Shell to print commands for: sh, bash, zsh, pwsh, powershell, or cmd (overrides execution.shell)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	faults, err := NewMockFaults(*faultKinds, *faultRate, *seed)
+	if err != nil {
+		return err
+	}
+	langConfig, frameworkConfig, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if err := checkRequiredVersion(configString(frameworkConfig, "require_version", "")); err != nil {
+		return err
+	}
+	executor, err := NewGoExecutor(langConfig, frameworkConfig)
+	if err != nil {
+		return err
+	}
+	tlsSet := false
+	flags.Visit(func(f *flag.Flag) { tlsSet = tlsSet || f.Name == "tls" })
+	if !tlsSet {
+		*useTLS = executor.mockTLSEnabled()
+	}
+	tier, err := executor.keyTier(*tierName)
+	if err != nil {
+		return err
+	}
+	shell, err := executor.commandShell(*shellName)
+	if err != nil {
+		return err
+	}
+	agent, err := executor.mockAgent()
+	if err != nil {
+		return err
+	}
+	mock, err := StartMockServer(*addr, *useTLS, faults, tier, agent)
+	if err != nil {
+		return err
+	}
+	defer mock.Close()
+
+	fmt.Printf("🧪 Mock Deepgram API at %s\n", mock.URL())
+	if faults != nil {
+		fmt.Printf("   Injecting %s into %.0f%% of requests\n", strings.Join(faults.Kinds, ", "), 100*faults.Rate)
+	}
+	if tier != nil {
+		fmt.Printf("   Simulating a %s key with scopes %s\n", tier.Name, strings.Join(tier.Scopes, ", "))
+	}
+	for _, variable := range mock.Env() {
+		fmt.Printf("   %s\n", shell.setEnv(variable))
+	}
+	fmt.Printf("   Run samples with --base-url %s; Ctrl-C to stop\n", mock.URL())
+
+	<-ctx.Done()
+	return nil
+}
+
+// prefetchCommand downloads the modules every sample needs, e.g. to
+// populate a CI module cache before the run
+func prefetchCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("prefetch", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := executor.PrefetchModules(ctx, samples); err != nil {
+		return err
+	}
+	fmt.Printf("📦 Prefetched modules for %d samples\n", len(samples))
+	return nil
+}
+
+// localesCommand checks that translated pages carry the same samples as
+// English, failing when any locale has stale snippets
+func localesCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("locales", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *docs.docsPath == "" {
+		return fmt.Errorf("--docs-path is required")
+	}
+
+	executor, err := docs.executor()
+	if err != nil {
+		return err
+	}
+
+	report, err := executor.CheckLocaleParity(ctx, *docs.docsPath)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	case FormatPlain:
+		if len(report) == 0 {
+			fmt.Printf("No translations found under %s\n", executor.translationsPath(*docs.docsPath))
+		}
+		for _, parity := range report {
+			if len(parity.Stale) == 0 {
+				fmt.Printf("✅ %s: %d pages match English (%d untranslated)\n", parity.Locale, parity.Pages, len(parity.Untranslated))
+				continue
+			}
+			fmt.Printf("⚠️  %s: %d stale snippets in %d pages (%d untranslated)\n", parity.Locale, len(parity.Stale), parity.Pages, len(parity.Untranslated))
+			for _, snippet := range parity.Stale {
+				fmt.Printf("   %s\n", formatStaleSnippet(snippet))
+			}
+		}
+	default:
+		return fmt.Errorf("unknown locales format: %s", *format)
+	}
+
+	if stale := staleLocales(report); len(stale) > 0 {
+		return fmt.Errorf("stale snippets in locales: %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// scopesCommand reports the API key scopes samples need, without running them
+func scopesCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("scopes", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	_, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	report, err := buildScopeReport(samples)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatPlain:
+		fmt.Printf("🔑 Scopes needed for a live run: %s\n", strings.Join(report.Required, ", "))
+		for _, page := range report.Pages {
+			if len(page.Missing) == 0 {
+				fmt.Printf("✅ %s mentions %s\n", page.FilePath, strings.Join(page.Required, ", "))
+			} else {
+				fmt.Printf("⚠️  %s never mentions %s\n", page.FilePath, strings.Join(page.Missing, ", "))
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown scopes format: %s", *format)
+	}
+}
+
+// apiSurfaceCommand lists exported SDK symbols that no sample references
+func apiSurfaceCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("api-surface", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	report, err := executor.BuildAPISurfaceReport(samples)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatPlain:
+		writeAPISurfacePlain(report)
+		return nil
+	default:
+		return fmt.Errorf("unknown api-surface format: %s", *format)
+	}
+}
+
+// freshnessCommand lists pages whose samples haven't passed within --max-age-days
+func freshnessCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("freshness", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain or json")
+	manifestPath := flags.String("manifest", "", "JSON manifest written by run --stamp")
+	maxAgeDays := flags.Int("max-age-days", 30, "Flag pages last verified longer ago than this")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	_, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	manifest := FreshnessManifest{}
+	if *manifestPath != "" {
+		if manifest, err = loadFreshnessManifest(*manifestPath); err != nil {
+			return err
+		}
+	}
+
+	stale, err := stalePages(samples, manifest, time.Duration(*maxAgeDays)*24*time.Hour, time.Now())
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stale)
+	case FormatPlain:
+		if len(stale) == 0 {
+			fmt.Printf("✅ Every page with samples was verified in the last %d days\n", *maxAgeDays)
+			return nil
+		}
+		for _, page := range stale {
+			if page.LastVerified == nil {
+				fmt.Printf("⚠️  %s has never been verified\n", page.Page)
+			} else {
+				fmt.Printf("⚠️  %s last verified %s (%d days ago)\n", page.Page, page.LastVerified.Format("2006-01-02"), page.AgeDays)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown freshness format: %s", *format)
+	}
+}
+
+// errorsCommand checks that samples print the error messages their pages
+// document when the mock fails the way the page describes
+func errorsCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("errors", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+	checks, err := executor.CheckDocumentedErrors(ctx, samples)
+	if err != nil {
+		return err
+	}
+
+	drifted := 0
+	for _, check := range checks {
+		if !check.Matched() {
+			drifted++
+		}
+	}
+
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(checks); err != nil {
+			return err
+		}
+	case FormatPlain:
+		if len(checks) == 0 {
+			fmt.Println("No samples document an error message")
+			return nil
+		}
+		for _, check := range checks {
+			location := fmt.Sprintf("%s:%d", check.Sample.FilePath, check.Sample.LineNumber)
+			switch {
+			case check.Problem != "":
+				fmt.Printf("⚠️  %s: %s\n", location, check.Problem)
+			case check.Absent && check.Matched():
+				fmt.Printf("✅ %s doesn't print its documented error (%s)\n", location, check.Condition())
+			case check.Matched():
+				fmt.Printf("✅ %s prints its documented error (%s)\n", location, check.Condition())
+			case check.Unexpected:
+				fmt.Printf("❌ %s prints its documented error under %s, which shouldn't reject it that way\n", location, check.Condition())
+			default:
+				fmt.Printf("❌ %s documented error drifted (%s)\n", location, check.Condition())
+				for _, line := range check.Missing {
+					fmt.Printf("   - %s\n", line)
+				}
+				for _, line := range strings.Split(check.Actual, "\n") {
+					fmt.Printf("   + %s\n", line)
+				}
+			}
+		}
+	default:
+		return fmt.Errorf("unknown errors format: %s", *format)
+	}
+
+	if drifted > 0 {
+		return fmt.Errorf("%d of %d documented errors don't match", drifted, len(checks))
+	}
+	return nil
+}
+
+// complexityCommand lists samples whose complexity makes them hard to follow
+func complexityCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("complexity", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+	outliers := executor.complexityOutliers(samples)
+
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(outliers)
+	case FormatPlain:
+		if len(outliers) == 0 {
+			fmt.Printf("✅ No complexity outliers in %d samples\n", len(samples))
+			return nil
+		}
+		for _, outlier := range outliers {
+			c := outlier.Sample.Complexity
+			fmt.Printf("⚠️  %s:%d score %d (%d lines, %d imports, %d branches, %d goroutines): %s\n",
+				outlier.Sample.FilePath, outlier.Sample.LineNumber, c.Score,
+				c.LOC, c.Imports, c.Branches, c.Goroutines, outlier.Reason)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown complexity format: %s", *format)
+	}
+}
+
+// reportCommand works with saved reports; `report diff old.json new.json`
+// prints what changed between two runs, and `report merge` joins several
+// languages' reports on the same docs
+func reportCommand(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			return reportDiffCommand(args[1:])
+		case "merge":
+			return reportMergeCommand(args[1:])
+		}
+	}
+	return fmt.Errorf("usage: report diff [--format plain|json|markdown] old.json new.json, or report merge [--format plain|json|markdown] report.json...")
+}
+
+// reportDiffCommand prints what changed between two runs
+func reportDiffCommand(args []string) error {
+	flags := flag.NewFlagSet("report diff", flag.ContinueOnError)
+	format := flags.String("format", FormatPlain, "Diff format: plain, json, or markdown")
+	outputPath := flags.String("output", "", "Write the diff to this file instead of stdout")
+	force := flags.Bool("force", false, "Compare runs even if their manifests say they aren't comparable")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("report diff needs an old and a new JSON report")
+	}
+
+	old, err := loadReport(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	new, err := loadReport(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	if mismatches := manifestMismatches(old.Manifest, new.Manifest); len(mismatches) > 0 {
+		if !*force {
+			return fmt.Errorf("runs aren't comparable (%s); pass --force to compare anyway", strings.Join(mismatches, "; "))
+		}
+		for _, mismatch := range mismatches {
+			fmt.Fprintf(os.Stderr, "⚠️  %s\n", mismatch)
+		}
+	}
+
+	output := os.Stdout
+	if *outputPath != "" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+	return writeReportDiff(output, *format, DiffReports(old, new))
+}
+
+// reportMergeCommand joins reports from several languages' executors by
+// page and sample position
+func reportMergeCommand(args []string) error {
+	flags := flag.NewFlagSet("report merge", flag.ContinueOnError)
+	format := flags.String("format", FormatPlain, "Merge format: plain, json, or markdown")
+	outputPath := flags.String("output", "", "Write the merged report to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("report merge needs JSON reports, e.g. go_test_report.json python_test_report.json")
+	}
+
+	var languages []string
+	var reports [][]languageResult
+	for _, file := range flags.Args() {
+		language, results, err := loadLanguageReport(file)
+		if err != nil {
+			return err
+		}
+		languages = append(languages, language)
+		reports = append(reports, results)
+	}
+
+	output := os.Stdout
+	if *outputPath != "" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		output = file
+	}
+	return writeMergedReport(output, *format, MergeReports(languages, reports))
+}
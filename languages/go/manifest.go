@@ -0,0 +1,147 @@
+package main
+
+// Run manifests
+// Two reports are only worth comparing if the runs differed in what was
+// meant to change. Every report carries a manifest of what produced it: the
+// Go toolchain, SDK and docs commits, executor version, a hash of the
+// configuration, and the host. `report diff` refuses runs whose toolchain,
+// executor, config, or platform differ.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// RunManifest records what produced a report
+type RunManifest struct {
+	Time            time.Time `json:"time"`
+	ExecutorVersion string    `json:"executor_version"`
+	// GoVersion is the toolchain samples were built with
+	GoVersion string `json:"go_version"`
+	SDKModule string `json:"sdk_module,omitempty"`
+	// SDKCommit is the local SDK checkout's commit, suffixed -dirty when it
+	// has uncommitted changes
+	SDKCommit string `json:"sdk_commit,omitempty"`
+	// SDKRelease is the SDK release samples were built against instead of
+	// the local checkout
+	SDKRelease string `json:"sdk_release,omitempty"`
+	// Trigger is the event a run revalidates, e.g. "sdk-release v3.2.0"
+	Trigger string `json:"trigger,omitempty"`
+	// DocsCommits maps each docs root, by name or path, to its commit
+	DocsCommits map[string]string `json:"docs_commits,omitempty"`
+	ConfigHash  string            `json:"config_hash"`
+	Host        HostInfo          `json:"host"`
+}
+
+// HostInfo describes the machine a run happened on
+type HostInfo struct {
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	CPUs     int    `json:"cpus"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// runManifest describes this executor's run over roots. Commits that can't
+// be read, e.g. for a docs tree outside git, are left out.
+func (e *GoExecutor) runManifest(ctx context.Context, roots []docsRoot, started time.Time) *RunManifest {
+	config, _ := json.Marshal(map[string]interface{}{
+		"language":  e.LanguageConfig,
+		"framework": e.FrameworkConfig,
+	})
+	manifest := &RunManifest{
+		Time:            started.UTC().Truncate(time.Second),
+		ExecutorVersion: executorVersion,
+		ConfigHash:      hashString(string(config))[:16],
+		Host: HostInfo{
+			OS:   runtime.GOOS,
+			Arch: runtime.GOARCH,
+			CPUs: runtime.NumCPU(),
+		},
+	}
+	manifest.Host.Hostname, _ = os.Hostname()
+
+	goVersion := exec.CommandContext(ctx, "go", "env", "GOVERSION")
+	goVersion.Env = e.goToolEnv()
+	asked := time.Now()
+	output, err := goVersion.Output()
+	e.Audit.record(goVersion, "", asked, err)
+	if err == nil {
+		manifest.GoVersion = strings.TrimSpace(string(output))
+	}
+
+	if e.SDKRelease != "" {
+		manifest.SDKModule, manifest.SDKRelease = e.sdkReleaseModule(e.SDKRelease), e.SDKRelease
+	} else if modulePath, dir, ok := e.localSDKModule(); ok {
+		manifest.SDKModule = modulePath
+		manifest.SDKCommit = gitCommit(ctx, e.Audit, dir)
+	}
+
+	for _, root := range roots {
+		dir, name := root.Path, root.Name
+		if root.Git != "" {
+			dir, _ = checkoutDir(root)
+		}
+		if name == "" {
+			name = root.Path
+		}
+		if commit := gitCommit(ctx, e.Audit, dir); commit != "" {
+			if manifest.DocsCommits == nil {
+				manifest.DocsCommits = make(map[string]string)
+			}
+			manifest.DocsCommits[name] = commit
+		}
+	}
+	return manifest
+}
+
+// gitCommit returns the commit checked out in dir, suffixed -dirty when the
+// tree has uncommitted changes, or "" outside a git repository
+func gitCommit(ctx context.Context, audit *AuditLog, dir string) string {
+	if dir == "" {
+		return ""
+	}
+	commit, err := gitOutput(ctx, audit, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return ""
+	}
+	if status, err := gitOutput(ctx, audit, dir, "status", "--porcelain", "--untracked-files=no"); err == nil && status != "" {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+// manifestMismatches lists why two runs aren't comparable: they were built
+// by different toolchains or executors, configured differently, or ran on
+// different platforms. Docs and SDK commits are expected to differ.
+func manifestMismatches(old, new *RunManifest) []string {
+	if old == nil || new == nil {
+		return []string{"a report has no run manifest"}
+	}
+
+	var mismatches []string
+	compare := func(field, before, after string) {
+		if before != after {
+			mismatches = append(mismatches, fmt.Sprintf("%s differs: %s vs %s", field, before, after))
+		}
+	}
+	compare("Go version", old.GoVersion, new.GoVersion)
+	compare("executor version", old.ExecutorVersion, new.ExecutorVersion)
+	compare("config hash", old.ConfigHash, new.ConfigHash)
+	compare("platform", old.Host.OS+"/"+old.Host.Arch, new.Host.OS+"/"+new.Host.Arch)
+	return mismatches
+}
+
+// writeManifest writes a run manifest as indented JSON
+func writeManifest(path string, manifest *RunManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
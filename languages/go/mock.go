@@ -0,0 +1,318 @@
+package main
+
+// Mock Deepgram API
+// Network-bound samples can't run offline, and running them live costs
+// credits and needs a key. The mock server answers the REST endpoints the
+// samples call with canned, correctly shaped responses; samples reach it
+// through the base-URL injection.
+//
+// The SDK insists on HTTPS in some paths, so the mock serves TLS with a
+// certificate from a CA generated for the run. The CA is written to a file
+// and handed to samples as SSL_CERT_FILE, which Go's TLS stack trusts on
+// Linux and other Unix systems, so docs code needs no InsecureSkipVerify.
+// macOS and Windows use the system verifier and ignore it, so there the
+// mock serves plain HTTP unless mocking.tls says otherwise.
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// MockServer is a running mock of the Deepgram API
+type MockServer struct {
+	// CAFile is the PEM file of the CA that signed the server certificate,
+	// empty when serving plain HTTP
+	CAFile string
+	// Agent holds the Voice Agent conversations
+	Agent *MockAgent
+
+	listener net.Listener
+	server   *http.Server
+	dir      string
+	tls      bool
+}
+
+// StartMockServer starts the mock on addr (e.g. "127.0.0.1:0"), serving TLS
+// with an ephemeral CA when useTLS is set, injecting faults when given,
+// checking requests against a key tier when given, and holding Voice Agent
+// conversations with agent
+func StartMockServer(addr string, useTLS bool, faults *MockFaults, tier *KeyTier, agent *MockAgent) (*MockServer, error) {
+	dir, err := os.MkdirTemp("", "go-mock-*")
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	mock := &MockServer{
+		Agent:    agent,
+		listener: listener,
+		server:   &http.Server{Handler: mockHandler(faults, tier, agent), ReadHeaderTimeout: 10 * time.Second},
+		dir:      dir,
+		tls:      useTLS,
+	}
+
+	if useTLS {
+		certificate, caPEM, err := ephemeralCertificate()
+		if err != nil {
+			mock.Close()
+			return nil, err
+		}
+		mock.CAFile = filepath.Join(dir, "ca.pem")
+		if err := os.WriteFile(mock.CAFile, caPEM, 0644); err != nil {
+			mock.Close()
+			return nil, err
+		}
+		mock.listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+
+	go mock.server.Serve(mock.listener)
+	return mock, nil
+}
+
+// URL returns the base URL samples should use to reach the mock
+func (m *MockServer) URL() string {
+	scheme := "http"
+	if m.tls {
+		scheme = "https"
+	}
+	return scheme + "://" + m.listener.Addr().String()
+}
+
+// Env returns the variables that make samples trust the mock's certificate
+func (m *MockServer) Env() []string {
+	if m.CAFile == "" {
+		return nil
+	}
+	return []string{"SSL_CERT_FILE=" + m.CAFile}
+}
+
+// Close stops the server and removes its CA
+func (m *MockServer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.server.Shutdown(ctx)
+	os.RemoveAll(m.dir)
+	return err
+}
+
+// ephemeralCertificate creates a CA and a localhost server certificate it
+// signs, returning the server certificate and the CA in PEM form
+func ephemeralCertificate() (tls.Certificate, []byte, error) {
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(24 * time.Hour)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dgtest mock CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	serverTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	serverDER, err := x509.CreateCertificate(rand.Reader, serverTemplate, caCert, &serverKey.PublicKey, caKey)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	certificate := tls.Certificate{
+		Certificate: [][]byte{serverDER, caDER},
+		PrivateKey:  serverKey,
+	}
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	return certificate, caPEM, nil
+}
+
+// mockHandler answers the REST endpoints used by docs samples, and the
+// Voice Agent's WebSocket
+func mockHandler(faults *MockFaults, tier *KeyTier, agent *MockAgent) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/listen", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"request_id": "00000000-0000-0000-0000-000000000000",
+				"duration":   1.5,
+				"channels":   1,
+			},
+			"results": map[string]interface{}{
+				"channels": []interface{}{map[string]interface{}{
+					"alternatives": []interface{}{map[string]interface{}{
+						"transcript": "hello from the mock server",
+						"confidence": 0.99,
+						"words":      mockWords("hello from the mock server"),
+					}},
+				}},
+			},
+		})
+	})
+
+	mux.HandleFunc("/v1/speak", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write(make([]byte, 1024))
+	})
+
+	mux.HandleFunc("/v1/read", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"metadata": map[string]interface{}{"request_id": "00000000-0000-0000-0000-000000000000"},
+			"results": map[string]interface{}{
+				"summary": map[string]interface{}{"text": "A summary from the mock server."},
+			},
+		})
+	})
+
+	mux.HandleFunc("/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, http.StatusOK, map[string]interface{}{
+			"projects": []interface{}{map[string]interface{}{
+				"project_id": "00000000-0000-0000-0000-000000000000",
+				"name":       "Mock Project",
+			}},
+		})
+	})
+
+	for _, path := range agentPaths {
+		mux.Handle(path, agent)
+		mux.Handle(path+"/", agent)
+	}
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeMockError(w, http.StatusNotFound, "NOT_FOUND", "The mock server has no response for "+r.URL.Path)
+	})
+
+	// The API checks a key's permissions before rate limits apply
+	routes := tier.wrap(faults.wrap(mux))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Auth schemes are case-insensitive, and browsers can only send the
+		// key in the WebSocket subprotocols
+		auth := strings.ToLower(r.Header.Get("Authorization"))
+		if !strings.HasPrefix(auth, "token ") && !strings.HasPrefix(auth, "bearer ") && !(isWebSocketUpgrade(r) && mockAPIKey(r) != "") {
+			writeMockError(w, http.StatusUnauthorized, "INVALID_AUTH", "Invalid credentials.")
+			return
+		}
+		if isWebSocketUpgrade(r) && !isAgentPath(r.URL.Path) {
+			writeMockError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "The mock server only supports WebSocket streaming for the Voice Agent")
+			return
+		}
+		routes.ServeHTTP(w, r)
+	})
+}
+
+// mockWords times a transcript's words a third of a second apart
+func mockWords(transcript string) []interface{} {
+	var words []interface{}
+	for i, word := range strings.Fields(transcript) {
+		words = append(words, map[string]interface{}{
+			"word":            word,
+			"punctuated_word": word,
+			"start":           float64(i) / 3,
+			"end":             float64(i+1) / 3,
+			"confidence":      0.99,
+		})
+	}
+	return words
+}
+
+// writeMockJSON writes a JSON response
+func writeMockJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeMockError writes an error in the API's error format
+func writeMockError(w http.ResponseWriter, status int, code, message string) {
+	writeMockJSON(w, status, map[string]interface{}{
+		"err_code":   code,
+		"err_msg":    message,
+		"request_id": "00000000-0000-0000-0000-000000000000",
+	})
+}
+
+// mockTLSEnabled reports whether the mock serves TLS (mocking.tls). It
+// defaults to true except on macOS and Windows, where samples would ignore
+// SSL_CERT_FILE and reject the mock's certificate.
+func (e *GoExecutor) mockTLSEnabled() bool {
+	return configBool(configSection(e.FrameworkConfig, "mocking"), "tls", mockTLSTrusted())
+}
+
+// mockTLSTrusted reports whether samples on this system can be made to
+// trust the mock's CA through SSL_CERT_FILE
+func mockTLSTrusted() bool {
+	return runtime.GOOS != "darwin" && runtime.GOOS != "ios" && runtime.GOOS != "windows"
+}
+
+// startMock starts a mock server, injecting the given faults or those in
+// mocking.faults and simulating the given key tier or mocking.tier, and
+// points the executor's samples at it
+func (e *GoExecutor) startMock(faultKinds, tierName string) error {
+	faults, err := e.mockFaults(faultKinds)
+	if err != nil {
+		return err
+	}
+	tier, err := e.keyTier(tierName)
+	if err != nil {
+		return err
+	}
+	agent, err := e.mockAgent()
+	if err != nil {
+		return err
+	}
+	mock, err := StartMockServer("127.0.0.1:0", e.mockTLSEnabled(), faults, tier, agent)
+	if err != nil {
+		return fmt.Errorf("starting mock server: %v", err)
+	}
+	e.Mock = mock
+	e.BaseURL = mock.URL()
+	return nil
+}
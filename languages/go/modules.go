@@ -0,0 +1,127 @@
+package main
+
+// Module setup for sample workspaces
+// Samples that import the SDK or other third-party packages need their
+// requirements resolved before they build. When a local SDK checkout is
+// configured, workspaces build against it rather than a published release.
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// goToolEnv returns the environment for go tool invocations, pointing them
+// at the shared build cache when execution.gocache is configured
+func (e *GoExecutor) goToolEnv() []string {
+	env := os.Environ()
+	execution := configSection(e.LanguageConfig, "execution")
+	if cache := configString(execution, "gocache", ""); cache != "" {
+		if absolute, err := filepath.Abs(cache); err == nil {
+			cache = absolute
+		}
+		env = append(env, "GOCACHE="+cache)
+	}
+	return env
+}
+
+// localSDKModule returns the module path and absolute directory of the
+// local SDK checkout, if SDKPath holds one
+func (e *GoExecutor) localSDKModule() (modulePath, dir string, ok bool) {
+	dir, err := filepath.Abs(e.SDKPath)
+	if err != nil {
+		return "", "", false
+	}
+
+	file, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", "", false
+	}
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if strings.HasPrefix(line, "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "module ")), dir, true
+		}
+	}
+	return "", "", false
+}
+
+// resolveDependencies adds requirements for a workspace's third-party
+// imports with `go mod tidy`, replacing the SDK with the local checkout.
+// After PrefetchModules, the prefetched go.mod and go.sum are used instead.
+// Commands are audited under sampleID, empty for workspaces shared by samples.
+func (e *GoExecutor) resolveDependencies(ctx context.Context, dir, sampleID string, imports []string, usage *ResourceUsage) {
+	if !hasThirdPartyImport(imports) {
+		return
+	}
+
+	if e.sharedModule != nil {
+		// Write errors surface as dependency failures when the sample builds
+		os.WriteFile(filepath.Join(dir, "go.mod"), e.sharedModule.GoMod, 0644)
+		os.WriteFile(filepath.Join(dir, "go.sum"), e.sharedModule.GoSum, 0644)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.dependencyTimeout())
+	defer cancel()
+
+	run := func(args ...string) {
+		cmd := exec.CommandContext(ctx, "go", args...)
+		cmd.Dir = dir
+		cmd.Env = e.goToolEnv()
+		started := time.Now()
+		err := cmd.Run() // Unresolvable imports are left for the build step to report
+		e.Audit.record(cmd, sampleID, started, err)
+		usage.record(cmd.ProcessState)
+	}
+
+	if e.SDKRelease != "" {
+		run("mod", "edit", "-require", e.sdkReleaseModule(e.SDKRelease)+"@"+e.SDKRelease)
+	} else if modulePath, sdkDir, ok := e.localSDKModule(); ok {
+		run("mod", "edit", "-require", modulePath+"@"+placeholderVersion(modulePath), "-replace", modulePath+"="+sdkDir)
+	}
+	run("mod", "tidy", "-e")
+}
+
+// placeholderVersion returns a version satisfying a replaced module's major
+// version suffix, e.g. v3.0.0 for .../v3
+func placeholderVersion(modulePath string) string {
+	if element := path.Base(modulePath); isMajorVersionSuffix(element) {
+		return element + ".0.0"
+	}
+	return "v0.0.0"
+}
+
+// dependencyTimeout bounds module resolution, which may download modules
+func (e *GoExecutor) dependencyTimeout() time.Duration {
+	execution := configSection(e.LanguageConfig, "execution")
+	return time.Duration(configInt(execution, "dependency_timeout_seconds", 120)) * time.Second
+}
+
+// hasThirdPartyImport reports whether any import is outside the standard library
+func hasThirdPartyImport(imports []string) bool {
+	for _, importPath := range imports {
+		if !isStandardLibrary(importPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStandardLibrary reports whether an import path belongs to the standard
+// library, whose first path element never contains a dot
+func isStandardLibrary(importPath string) bool {
+	first := importPath
+	if slash := strings.Index(importPath, "/"); slash >= 0 {
+		first = importPath[:slash]
+	}
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,207 @@
+package main
+
+// Run plans
+// `run --plan` shows what a run would do without building or running
+// anything: which samples run and which are skipped and why, each sample's
+// time limit and retries, how preparation changes its code, the
+// environment samples get, and the local files they expect. Reviewing the
+// plan shows the effect of a config change before it costs CI time.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Sample modes in a run plan
+const (
+	PlanModeRun  = "run"
+	PlanModeSkip = "skip"
+)
+
+// RunPlan is what a run would do
+type RunPlan struct {
+	Environment    string       `json:"environment,omitempty"`
+	BaseURL        string       `json:"base_url,omitempty"`
+	Env            []string     `json:"env"`
+	BuildCommand   string       `json:"build_command"`
+	Workers        int          `json:"workers"`
+	NetworkWorkers int          `json:"network_workers"`
+	Strategy       string       `json:"strategy"`
+	Prefetch       bool         `json:"prefetch"`
+	WarmUp         bool         `json:"warm_up"`
+	Samples        []SamplePlan `json:"samples"`
+}
+
+// SamplePlan is what a run would do with one sample
+type SamplePlan struct {
+	ID         string `json:"id"`
+	Target     string `json:"target"`
+	Product    string `json:"product"`
+	Mode       string `json:"mode"`
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Class is the policy class, compile-only or network
+	Class          string  `json:"class"`
+	TimeoutSeconds float64 `json:"timeout_seconds,omitempty"`
+	Retries        int     `json:"retries,omitempty"`
+	// Transformers are the pipeline preparing the sample's code, in order
+	Transformers []string `json:"transformers,omitempty"`
+	// Substitutions are the lines preparation removes (-) from the sample
+	// and adds (+) to the code that would be built
+	Substitutions []string `json:"substitutions,omitempty"`
+	// Fixtures are local files the sample reads, which workspaces don't provide
+	Fixtures []string `json:"fixtures,omitempty"`
+	// Step and DependsOn are the sample's dependency declarations
+	Step      string   `json:"step,omitempty"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Captures maps the values the sample exports to their expressions, and
+	// Substitutes maps its placeholders to the captured values replacing them
+	Captures    map[string]string `json:"captures,omitempty"`
+	Substitutes map[string]string `json:"substitutes,omitempty"`
+}
+
+// Plan describes how RunSamples would handle samples
+func (e *GoExecutor) Plan(samples []CodeSample) RunPlan {
+	workers, networkWorkers := e.workerCounts()
+	plan := RunPlan{
+		BaseURL:        e.BaseURL,
+		Env:            maskSecrets(e.sampleEnv()),
+		BuildCommand:   strings.Join(e.buildCommandArgs(), " "),
+		Workers:        workers,
+		NetworkWorkers: networkWorkers,
+		Samples:        []SamplePlan{},
+	}
+	if e.Environment != nil {
+		plan.Environment = e.Environment.Name
+	}
+
+	runnable := 0
+	for _, sample := range samples {
+		class := PolicyCompileOnly
+		if sample.RequiresNetwork {
+			class = PolicyNetwork
+		}
+		entry := SamplePlan{
+			ID:          sample.ID,
+			Target:      fmt.Sprintf("%s:%d", sample.FilePath, sample.LineNumber),
+			Product:     sampleProduct(sample),
+			Mode:        PlanModeRun,
+			Class:       class,
+			Step:        sample.Metadata[metadataStep],
+			DependsOn:   dependsOn(sample),
+			Captures:    prefixedMetadata(sample, metadataCapturePrefix),
+			Substitutes: prefixedMetadata(sample, metadataSubstitutePrefix),
+		}
+
+		if reason := e.skipReason(sample); reason != "" {
+			entry.Mode = PlanModeSkip
+			entry.SkipReason = reason
+		} else {
+			runnable++
+			policy := e.samplePolicy(sample)
+			entry.TimeoutSeconds = policy.Timeout.Seconds()
+			entry.Retries = policy.Retries
+			entry.Transformers = transformerNames(e.samplePipeline(sample))
+			entry.Substitutions = changedLines(lineDiff(sample.Code, e.prepareCodeForExecution(sample)))
+			entry.Fixtures = analyzeSample(sample.Code).AudioFiles
+		}
+		plan.Samples = append(plan.Samples, entry)
+	}
+
+	plan.Prefetch = e.sharedModule == nil && runnable > 1 && e.prefetchEnabled()
+	plan.Strategy = StrategyCold
+	if e.usesWorkspacePool(runnable) {
+		plan.Strategy = StrategyWarm
+	}
+	plan.WarmUp = plan.Strategy == StrategyWarm || workers > 1 && runnable > 1 && e.warmUpEnabled()
+	return plan
+}
+
+// writePlan renders a run plan as plain text or JSON
+func writePlan(w io.Writer, format string, plan RunPlan) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(plan)
+	case FormatPlain:
+	default:
+		return fmt.Errorf("unknown plan format: %s", format)
+	}
+
+	running := 0
+	for _, sample := range plan.Samples {
+		if sample.Mode == PlanModeRun {
+			running++
+		}
+	}
+
+	fmt.Fprintf(w, "🗺️  Plan: %d of %d samples would run\n", running, len(plan.Samples))
+	if plan.Environment != "" {
+		fmt.Fprintf(w, "   Environment:  %s\n", plan.Environment)
+	}
+	if plan.BaseURL != "" {
+		fmt.Fprintf(w, "   Base URL:     %s\n", plan.BaseURL)
+	}
+	fmt.Fprintf(w, "   Build:        %s\n", plan.BuildCommand)
+	fmt.Fprintf(w, "   Workers:      %d (+%d network-bound), %s workspaces, prefetch %t, cache warm-up %t\n",
+		plan.Workers, plan.NetworkWorkers, plan.Strategy, plan.Prefetch, plan.WarmUp)
+	fmt.Fprintf(w, "   Env:\n")
+	for _, variable := range plan.Env {
+		fmt.Fprintf(w, "      %s\n", variable)
+	}
+
+	for _, sample := range plan.Samples {
+		fmt.Fprintln(w)
+		if sample.Mode == PlanModeSkip {
+			fmt.Fprintf(w, "⏭️  %s [%s] skip: %s\n", sample.Target, sample.ID, sample.SkipReason)
+			continue
+		}
+		fmt.Fprintf(w, "▶️  %s [%s] run as %s, %s, limit %.0fs, %d retries\n",
+			sample.Target, sample.ID, sample.Product, sample.Class, sample.TimeoutSeconds, sample.Retries)
+		if len(sample.DependsOn) > 0 {
+			fmt.Fprintf(w, "   after %s\n", strings.Join(sample.DependsOn, ", "))
+		}
+		for _, placeholder := range sortedStringKeys(sample.Substitutes) {
+			fmt.Fprintf(w, "   replaces %s with captured %s\n", placeholder, sample.Substitutes[placeholder])
+		}
+		for _, name := range sortedStringKeys(sample.Captures) {
+			fmt.Fprintf(w, "   captures %s from %s\n", name, sample.Captures[name])
+		}
+		for _, fixture := range sample.Fixtures {
+			fmt.Fprintf(w, "   needs %s (not provided in the workspace)\n", fixture)
+		}
+		fmt.Fprintf(w, "   prepared by %s\n", strings.Join(sample.Transformers, " → "))
+		for _, line := range sample.Substitutions {
+			fmt.Fprintf(w, "   %s\n", line)
+		}
+	}
+	return nil
+}
+
+// maskSecrets hides the values of variables that look like credentials, so
+// a plan for a live run can be pasted into a review
+func maskSecrets(env []string) []string {
+	masked := make([]string, len(env))
+	for i, variable := range env {
+		name, value, _ := strings.Cut(variable, "=")
+		upper := strings.ToUpper(name)
+		if value != "" && (strings.Contains(upper, "KEY") || strings.Contains(upper, "TOKEN") || strings.Contains(upper, "SECRET")) {
+			variable = name + "=<redacted>"
+		}
+		masked[i] = variable
+	}
+	return masked
+}
+
+// changedLines keeps the removed and added lines of a lineDiff
+func changedLines(diff string) []string {
+	var changed []string
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "+ ") {
+			changed = append(changed, line)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,169 @@
+package main
+
+// Platform differences between runners
+// Docs written for Windows or macOS developers should be verified on those
+// systems, so the executor runs on Windows and macOS runners as well as
+// Linux. Samples can name the systems they target on their fence and are
+// skipped elsewhere:
+//
+//	```go os=windows
+//
+// Commands printed for people to copy (debug, mock) follow the shell the
+// runner or CI job selects, from --shell, execution.shell, or the
+// environment.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// metadataOS is the metadata key of the systems a sample targets
+const metadataOS = "os"
+
+var fenceOSRegex = regexp.MustCompile(`\bos=["']?([\w,]+)`)
+
+// osAliases maps the names docs use for systems to GOOS values
+var osAliases = map[string]string{
+	"macos": "darwin",
+	"mac":   "darwin",
+	"osx":   "darwin",
+	"win":   "windows",
+}
+
+// sampleTargetOS records the systems a sample's fence says it targets
+func sampleTargetOS(sample *CodeSample, fenceMeta string) {
+	match := fenceOSRegex.FindStringSubmatch(fenceMeta)
+	if match == nil {
+		return
+	}
+	var systems []string
+	for _, system := range strings.Split(strings.ToLower(match[1]), ",") {
+		if alias, ok := osAliases[system]; ok {
+			system = alias
+		}
+		if system != "" && !containsString(systems, system) {
+			systems = append(systems, system)
+		}
+	}
+	sample.Metadata[metadataOS] = strings.Join(systems, ",")
+}
+
+// platformSkipReason explains why a sample targeting other systems can't
+// run on this runner, or returns ""
+func platformSkipReason(sample CodeSample) string {
+	targets := sample.Metadata[metadataOS]
+	if targets == "" || containsString(strings.Split(targets, ","), runtime.GOOS) {
+		return ""
+	}
+	return fmt.Sprintf("targets %s; this runner is %s", targets, runtime.GOOS)
+}
+
+// removeWorkspace deletes a sample workspace. Windows keeps a killed
+// sample's executable locked, and virus scanners briefly hold new files
+// open, so removal there is retried for a few seconds.
+func removeWorkspace(dir string) error {
+	err := os.RemoveAll(dir)
+	if runtime.GOOS != "windows" {
+		return err
+	}
+	for delay := 100 * time.Millisecond; err != nil && delay <= 2*time.Second; delay *= 2 {
+		time.Sleep(delay)
+		err = os.RemoveAll(dir)
+	}
+	return err
+}
+
+// Shell syntaxes for printed commands
+const (
+	ShellPOSIX      = "posix"
+	ShellPowerShell = "powershell"
+	ShellCmd        = "cmd"
+)
+
+// shellSyntaxes maps shell names to the syntax their commands use
+var shellSyntaxes = map[string]string{
+	"sh":         ShellPOSIX,
+	"bash":       ShellPOSIX,
+	"zsh":        ShellPOSIX,
+	"pwsh":       ShellPowerShell,
+	"powershell": ShellPowerShell,
+	"cmd":        ShellCmd,
+}
+
+// commandShell is the shell commands are printed for and debug opens
+type commandShell struct {
+	Name   string
+	Path   string
+	Syntax string
+}
+
+// commandShell resolves the shell named by a flag, execution.shell, or,
+// without either, the user's shell
+func (e *GoExecutor) commandShell(name string) (commandShell, error) {
+	if name == "" {
+		name = configString(configSection(e.FrameworkConfig, "execution"), "shell", "")
+	}
+	if name == "" {
+		path := interactiveShell()
+		name = strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		syntax, ok := shellSyntaxes[name]
+		if !ok {
+			syntax = ShellPOSIX
+		}
+		return commandShell{Name: name, Path: path, Syntax: syntax}, nil
+	}
+
+	syntax, ok := shellSyntaxes[name]
+	if !ok {
+		return commandShell{}, fmt.Errorf("unknown shell %q (expected %s)", name, strings.Join(sortedStringKeys(shellSyntaxes), ", "))
+	}
+	return commandShell{Name: name, Path: name, Syntax: syntax}, nil
+}
+
+// setEnv returns the command that sets a NAME=value variable
+func (s commandShell) setEnv(variable string) string {
+	name, value, _ := strings.Cut(variable, "=")
+	switch s.Syntax {
+	case ShellPowerShell:
+		return fmt.Sprintf("$env:%s = '%s'", name, strings.ReplaceAll(value, "'", "''"))
+	case ShellCmd:
+		return fmt.Sprintf(`set "%s=%s"`, name, value)
+	}
+	return "export " + name + "=" + posixQuote(value)
+}
+
+// runBinary returns the commands that run an executable in the current
+// directory with env added to its environment
+func (s commandShell) runBinary(binary string, env []string) []string {
+	if s.Syntax == ShellPOSIX {
+		var words []string
+		for _, variable := range env {
+			name, value, _ := strings.Cut(variable, "=")
+			words = append(words, name+"="+posixQuote(value))
+		}
+		return []string{strings.Join(append(words, "./"+binary), " ")}
+	}
+
+	var lines []string
+	for _, variable := range env {
+		lines = append(lines, s.setEnv(variable))
+	}
+	if s.Syntax == ShellPowerShell {
+		return append(lines, `.\`+binary)
+	}
+	return append(lines, binary)
+}
+
+// posixQuote single-quotes a value when the shell would otherwise split or
+// expand it
+func posixQuote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n'\"$`\\*?[]{}()<>|&;#~!") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
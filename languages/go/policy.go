@@ -0,0 +1,87 @@
+package main
+
+// Per-sample execution policies
+// A compile-only snippet and a live streaming sample need very different
+// time budgets, and only samples talking to a service benefit from retries.
+// execution.policies in the framework config overrides the global
+// timeout_seconds, retries, and retry_backoff_seconds for a class of samples:
+//
+//	execution:
+//	  timeout_seconds: 10
+//	  policies:
+//	    compile-only: {timeout_seconds: 60}
+//	    stt-prerecorded: {timeout_seconds: 120, retries: 1}
+//	    websocket: {timeout_seconds: 300, retries: 2, retry_backoff_seconds: 5}
+
+import (
+	"time"
+)
+
+// Policy classes for samples by whether they reach a service
+const (
+	PolicyCompileOnly = "compile-only"
+	PolicyNetwork     = "network"
+)
+
+// executionPolicy is how long a sample may run and how failures are retried
+type executionPolicy struct {
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+}
+
+// samplePolicy resolves a sample's execution policy. Policies are keyed by
+// product, feature, compile-only/network, or sample type; for each setting
+// the first of those keys that sets it wins, falling back to the global value.
+func (e *GoExecutor) samplePolicy(sample CodeSample) executionPolicy {
+	execution := configSection(e.FrameworkConfig, "execution")
+	policies := configSection(execution, "policies")
+
+	class := PolicyCompileOnly
+	if sample.RequiresNetwork {
+		class = PolicyNetwork
+	}
+
+	var matched []map[string]interface{}
+	for _, key := range []string{sample.Product, sample.Feature, class, sample.SampleType} {
+		if key == "" {
+			continue
+		}
+		if _, ok := policies[key]; ok {
+			matched = append(matched, configSection(policies, key))
+		}
+	}
+	matched = append(matched, execution)
+
+	setting := func(key string, fallback int) int {
+		for _, section := range matched {
+			if _, ok := section[key]; ok {
+				return configInt(section, key, fallback)
+			}
+		}
+		return fallback
+	}
+
+	policy := executionPolicy{
+		Timeout: time.Duration(setting("timeout_seconds", 10)) * time.Second,
+		Retries: setting("retries", 0),
+		Backoff: time.Duration(setting("retry_backoff_seconds", 1)) * time.Second,
+	}
+	if policy.Retries < 0 {
+		policy.Retries = 0
+	}
+	return policy
+}
+
+// retryable reports whether a failed attempt might pass if run again.
+// Build and dependency failures are deterministic, so only timeouts and
+// runtime failures are retried.
+func retryable(result TestResult) bool {
+	return result.Status == StatusTimeout ||
+		(result.Status == StatusFailed && result.ErrorCategory == ErrorCategoryRuntime)
+}
+
+// retryDelay doubles the backoff for each attempt already made
+func (p executionPolicy) retryDelay(attempt int) time.Duration {
+	return p.Backoff << uint(attempt-1)
+}
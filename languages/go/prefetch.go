@@ -0,0 +1,103 @@
+package main
+
+// Module prefetching
+// Resolving each sample's dependencies separately means hundreds of
+// `go mod tidy` runs hitting the module proxy mid-run, and a rate-limited
+// proxy then shows up as unrelated sample failures. Instead, the modules
+// needed by every sample are resolved and downloaded once, and each sample
+// workspace starts from the resulting go.mod and go.sum.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// moduleFiles are a resolved go.mod and go.sum shared by sample workspaces
+type moduleFiles struct {
+	GoMod []byte
+	GoSum []byte
+}
+
+// PrefetchModules resolves and downloads the modules needed by all samples,
+// after which sample workspaces reuse the resolved requirements instead of
+// contacting the module proxy themselves
+func (e *GoExecutor) PrefetchModules(ctx context.Context, samples []CodeSample) error {
+	packages := e.importUnion(samples)
+	if !hasThirdPartyImport(packages) {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "go-prefetch-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, e.dependencyTimeout())
+	defer cancel()
+
+	if err := e.createImportAllModule(ctx, dir, packages); err != nil {
+		return err
+	}
+
+	var usage ResourceUsage
+	e.resolveDependencies(ctx, dir, "", packages, &usage)
+
+	download := exec.CommandContext(ctx, "go", "mod", "download")
+	download.Dir = dir
+	download.Env = e.goToolEnv()
+	started := time.Now()
+	output, err := download.CombinedOutput()
+	e.Audit.record(download, "", started, err)
+	if err != nil {
+		return fmt.Errorf("go mod download: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return err
+	}
+	goSum, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	e.sharedModule = &moduleFiles{GoMod: goMod, GoSum: goSum}
+	return nil
+}
+
+// createImportAllModule initializes a module in dir whose main package
+// imports every given package, so one `go mod tidy` resolves them all
+func (e *GoExecutor) createImportAllModule(ctx context.Context, dir string, packages []string) error {
+	var source strings.Builder
+	source.WriteString("package main\n\nimport (\n")
+	for _, pkg := range packages {
+		fmt.Fprintf(&source, "\t_ %q\n", pkg)
+	}
+	source.WriteString(")\n\nfunc main() {}\n")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(source.String()), 0644); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "mod", "init", "samples")
+	cmd.Dir = dir
+	cmd.Env = e.goToolEnv()
+	started := time.Now()
+	output, err := cmd.CombinedOutput()
+	e.Audit.record(cmd, "", started, err)
+	if err != nil {
+		return fmt.Errorf("go mod init: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// prefetchEnabled reports whether to prefetch modules before running samples
+func (e *GoExecutor) prefetchEnabled() bool {
+	execution := configSection(e.LanguageConfig, "execution")
+	return configBool(execution, "prefetch", true)
+}
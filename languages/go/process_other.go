@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package main
+
+import (
+	"os/exec"
+	"time"
+)
+
+// killProcessGroupOnCancel falls back to killing the direct child and
+// bounding how long we wait for orphaned descendants to release our pipes
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = 5 * time.Second
+}
@@ -0,0 +1,147 @@
+package main
+
+// API product classification
+// Samples are assigned to the Deepgram product they exercise so that reports
+// and filters can be split along product team lines.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// API products reported in CodeSample.Product
+const (
+	ProductSTTPrerecorded   = "stt-prerecorded"
+	ProductSTTLive          = "stt-live"
+	ProductTTS              = "tts"
+	ProductVoiceAgent       = "voice-agent"
+	ProductManagement       = "management"
+	ProductTextIntelligence = "text-intelligence"
+	ProductUnclassified     = "unclassified"
+)
+
+// Product features reported in CodeSample.Feature
+const (
+	FeatureREST      = "rest"
+	FeatureWebSocket = "websocket"
+)
+
+// productOrder is the order products are listed in reports
+var productOrder = []string{
+	ProductSTTPrerecorded,
+	ProductSTTLive,
+	ProductTTS,
+	ProductVoiceAgent,
+	ProductManagement,
+	ProductTextIntelligence,
+	ProductUnclassified,
+}
+
+// sdkPackageProducts maps the SDK package area (the element after
+// pkg/client or pkg/api) to its product, checked in order of specificity
+var sdkPackageProducts = []struct {
+	area    string
+	product string
+}{
+	{"agent", ProductVoiceAgent},
+	{"listen", ProductSTTPrerecorded},
+	{"speak", ProductTTS},
+	{"manage", ProductManagement},
+	{"analyze", ProductTextIntelligence},
+	{"read", ProductTextIntelligence},
+}
+
+// classifyProduct determines the product and feature a sample exercises from
+// the SDK packages it imports and the client constructors it calls
+func classifyProduct(imports, constructors []string) (product, feature string) {
+	websocket := false
+	for _, constructor := range constructors {
+		name := constructor[strings.LastIndex(constructor, ".")+1:]
+		if strings.Contains(name, "WS") || strings.Contains(name, "WebSocket") {
+			websocket = true
+		}
+	}
+
+	areas := make(map[string]bool)
+	for _, importPath := range imports {
+		if !strings.HasPrefix(importPath, sdkModulePrefix) {
+			continue
+		}
+		if area := sdkPackageArea(importPath); area != "" {
+			areas[area] = true
+		}
+		if strings.Contains(importPath, "websocket") {
+			websocket = true
+		}
+	}
+
+	product = ProductUnclassified
+	for _, candidate := range sdkPackageProducts {
+		if areas[candidate.area] {
+			product = candidate.product
+			break
+		}
+	}
+	if product == ProductUnclassified {
+		return product, ""
+	}
+
+	feature = FeatureREST
+	if websocket {
+		feature = FeatureWebSocket
+	}
+	if product == ProductSTTPrerecorded && websocket {
+		product = ProductSTTLive
+	}
+	if product == ProductVoiceAgent {
+		feature = FeatureWebSocket
+	}
+
+	return product, feature
+}
+
+// sdkPackageArea returns the element following pkg/client or pkg/api in an
+// SDK import path, e.g. "listen" for .../pkg/api/listen/v1/rest
+func sdkPackageArea(importPath string) string {
+	elements := strings.Split(importPath, "/")
+	for i := 0; i+2 < len(elements); i++ {
+		if elements[i] == "pkg" && (elements[i+1] == "client" || elements[i+1] == "api") {
+			return elements[i+2]
+		}
+	}
+	return ""
+}
+
+// parseProductFilter splits a comma-separated product list, rejecting
+// unknown names so typos don't silently filter out every sample
+func parseProductFilter(value string) (map[string]bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	products := make(map[string]bool)
+	for _, product := range strings.Split(value, ",") {
+		product = strings.TrimSpace(product)
+		if !containsString(productOrder, product) {
+			return nil, fmt.Errorf("unknown product %q (expected one of %s)", product, strings.Join(productOrder, ", "))
+		}
+		products[product] = true
+	}
+	return products, nil
+}
+
+// filterByProduct keeps only samples whose product is in products; a nil
+// filter keeps everything
+func filterByProduct(samples []CodeSample, products map[string]bool) []CodeSample {
+	if products == nil {
+		return samples
+	}
+
+	var filtered []CodeSample
+	for _, sample := range samples {
+		if products[sampleProduct(sample)] {
+			filtered = append(filtered, sample)
+		}
+	}
+	return filtered
+}
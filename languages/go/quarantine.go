@@ -0,0 +1,96 @@
+package main
+
+// Quarantines
+// A sample broken by something outside the docs, such as an API incident or
+// an SDK bug awaiting release, shouldn't fail every run until it's fixed.
+// Listing it under quarantine keeps it running and reported, but its
+// failures don't count towards --fail-on until the quarantine expires.
+
+import (
+	"fmt"
+	"time"
+)
+
+// Quarantine exempts a known-broken sample from failing runs
+type Quarantine struct {
+	// Sample is the sample's ID or page:line
+	Sample string `json:"sample"`
+	Reason string `json:"reason"`
+	// Issue links the work that will lift the quarantine
+	Issue string `json:"issue,omitempty"`
+	// Expires is the date (YYYY-MM-DD) after which the sample fails runs again
+	Expires string `json:"expires,omitempty"`
+}
+
+// quarantines reads the quarantine list from the framework config
+func (e *GoExecutor) quarantines() []Quarantine {
+	entries, _ := e.FrameworkConfig["quarantine"].([]interface{})
+	var quarantines []Quarantine
+	for _, entry := range entries {
+		config, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		quarantine := Quarantine{
+			Sample:  configString(config, "sample", ""),
+			Reason:  configString(config, "reason", ""),
+			Issue:   configString(config, "issue", ""),
+			Expires: configString(config, "expires", ""),
+		}
+		if quarantine.Sample != "" {
+			quarantines = append(quarantines, quarantine)
+		}
+	}
+	return quarantines
+}
+
+// open reports whether the quarantine still applies at now. An expiry
+// that can't be parsed never lapses, so a typo can't fail a run.
+func (q Quarantine) open(now time.Time) bool {
+	if q.Expires == "" {
+		return true
+	}
+	expires, err := time.Parse("2006-01-02", q.Expires)
+	if err != nil {
+		return true
+	}
+	return now.Before(expires.AddDate(0, 0, 1))
+}
+
+// covers reports whether the quarantine names a sample
+func (q Quarantine) covers(sample CodeSample) bool {
+	return q.Sample == sample.ID || q.Sample == fmt.Sprintf("%s:%d", sample.Page, sample.LineNumber)
+}
+
+// openQuarantine returns the open quarantine covering a sample, if any
+func (e *GoExecutor) openQuarantine(sample CodeSample, now time.Time) *Quarantine {
+	for _, quarantine := range e.quarantines() {
+		if quarantine.covers(sample) && quarantine.open(now) {
+			return &quarantine
+		}
+	}
+	return nil
+}
+
+// applyQuarantines marks the results of quarantined samples
+func (e *GoExecutor) applyQuarantines(results []TestResult, now time.Time) {
+	for i := range results {
+		results[i].Quarantine = e.openQuarantine(results[i].Sample, now)
+	}
+}
+
+// withoutQuarantined returns the summary with quarantined failures and
+// timeouts left out of the status counts, for --fail-on
+func withoutQuarantined(summary RunSummary, results []TestResult) RunSummary {
+	counts := make(map[string]int, len(summary.ByStatus))
+	for status, count := range summary.ByStatus {
+		counts[status] = count
+	}
+	for _, result := range results {
+		if result.Quarantine != nil && (result.Status == StatusFailed || result.Status == StatusTimeout) {
+			counts[result.Status]--
+		}
+	}
+	summary.ByStatus = counts
+	return summary
+}
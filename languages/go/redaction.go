@@ -0,0 +1,98 @@
+package main
+
+// Report redaction
+// Sample output carries request IDs, addresses, and local paths that
+// shouldn't leave the team. Redaction rules rewrite stdout, stderr, and
+// error messages before a report is written, so published reports and
+// dashboards can be shared as they are.
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// redactionRule replaces every match of a pattern in report output
+type redactionRule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// defaultRedactionRules apply when reporting.redaction sets no rules
+var defaultRedactionRules = []struct{ name, pattern, replacement string }{
+	{"request_id", `\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`, "<uuid>"},
+	{"ipv4", `\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`, "<ip>"},
+	{"api_key", `(?i)(token|bearer)\s+[A-Za-z0-9_\-.]{16,}`, "$1 <redacted>"},
+	{"temp_path", regexp.QuoteMeta(strings.TrimSuffix(os.TempDir(), string(os.PathSeparator))) + `[/\\][\w.\-]+`, "<tmp>"},
+}
+
+// redactionRules compiles reporting.redaction.rules, or the defaults. Rules
+// are maps with name, pattern, and replacement keys.
+func (e *GoExecutor) redactionRules() ([]redactionRule, error) {
+	redaction := configSection(configSection(e.FrameworkConfig, "reporting"), "redaction")
+	if !configBool(redaction, "enabled", true) {
+		return nil, nil
+	}
+
+	var rules []redactionRule
+	configured, _ := redaction["rules"].([]interface{})
+	for i, entry := range configured {
+		rule, _ := entry.(map[string]interface{})
+		pattern, err := regexp.Compile(configString(rule, "pattern", ""))
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %d: %v", i, err)
+		}
+		rules = append(rules, redactionRule{
+			Name:        configString(rule, "name", fmt.Sprintf("rule_%d", i)),
+			Pattern:     pattern,
+			Replacement: configString(rule, "replacement", "<redacted>"),
+		})
+	}
+
+	if len(configured) == 0 {
+		for _, rule := range defaultRedactionRules {
+			rules = append(rules, redactionRule{
+				Name:        rule.name,
+				Pattern:     regexp.MustCompile(rule.pattern),
+				Replacement: rule.replacement,
+			})
+		}
+	}
+	return rules, nil
+}
+
+// redact applies every rule to text
+func redact(text string, rules []redactionRule) string {
+	for _, rule := range rules {
+		text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+	}
+	return text
+}
+
+// redactReport returns a copy of the report with sample output redacted
+func redactReport(report Report, rules []redactionRule) Report {
+	if len(rules) == 0 {
+		return report
+	}
+
+	results := make([]TestResult, len(report.Results))
+	for i, result := range report.Results {
+		result.Stdout = redact(result.Stdout, rules)
+		result.Stderr = redact(result.Stderr, rules)
+		result.ErrorMessage = redact(result.ErrorMessage, rules)
+		result.GoldenDiff = redact(result.GoldenDiff, rules)
+		result.WorkDir = redact(result.WorkDir, rules)
+		if result.Captured != nil {
+			captured := make(map[string]string, len(result.Captured))
+			for name, value := range result.Captured {
+				captured[name] = redact(value, rules)
+			}
+			result.Captured = captured
+		}
+		results[i] = result
+	}
+	report.Results = results
+	return report
+}
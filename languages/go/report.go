@@ -0,0 +1,401 @@
+package main
+
+// Report output in plain, JSON, and Markdown formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Report output formats accepted by writeReport
+const (
+	FormatPlain    = "plain"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatProblems = "problems"
+)
+
+// Report is the document written at the end of a run
+type Report struct {
+	Language string `json:"language"`
+	// Environment is the API environment the run targeted, if one was selected
+	Environment string       `json:"environment,omitempty"`
+	Results     []TestResult `json:"results"`
+	Summary     RunSummary   `json:"run_summary"`
+	Coverage    *SDKCoverage `json:"sdk_coverage,omitempty"`
+	// BuildRegressions lists samples whose binaries grew sharply since the
+	// previous run in the history file
+	BuildRegressions []BuildRegression `json:"build_regressions,omitempty"`
+	// Manifest records what produced the report, so runs can be compared
+	Manifest *RunManifest `json:"manifest,omitempty"`
+}
+
+// writeReport renders a report in the requested format
+func writeReport(w io.Writer, format string, report Report) error {
+	switch format {
+	case FormatPlain:
+		return writePlainReport(w, report)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatMarkdown:
+		return writeMarkdownReport(w, report)
+	case FormatProblems:
+		return writeProblemsReport(w, report)
+	default:
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+}
+
+// groupByProduct splits results by product, in report order, dropping empty groups
+func groupByProduct(results []TestResult) [][]TestResult {
+	byProduct := make(map[string][]TestResult)
+	for _, result := range results {
+		product := sampleProduct(result.Sample)
+		byProduct[product] = append(byProduct[product], result)
+	}
+
+	var groups [][]TestResult
+	for _, product := range productOrder {
+		if len(byProduct[product]) > 0 {
+			groups = append(groups, byProduct[product])
+		}
+	}
+	return groups
+}
+
+func writePlainReport(w io.Writer, report Report) error {
+	for _, group := range groupByProduct(report.Results) {
+		fmt.Fprintf(w, "\n[%s]\n", sampleProduct(group[0].Sample))
+		for _, result := range group {
+			writePlainResult(w, result)
+		}
+	}
+
+	summary := report.Summary
+	fmt.Fprintf(w, "\n📊 Run summary: %d samples in %.2fs (prefetch %.2fs, cache warm-up %.2fs)\n",
+		summary.Total, summary.WallTime, summary.PrefetchTime, summary.WarmUpTime)
+	if report.Environment != "" {
+		fmt.Fprintf(w, "   Environment:       %s\n", report.Environment)
+	}
+	fmt.Fprintf(w, "   By status:         %s\n", formatCounts(summary.ByStatus))
+	fmt.Fprintf(w, "   By product:        %s\n", formatCounts(summary.ByProduct))
+	fmt.Fprintf(w, "   By error category: %s\n", formatCounts(summary.ByErrorCategory))
+	_, err := fmt.Fprintf(w, "   Parallelism:       peak %d of %d (+%d network-bound), effective %.2f\n",
+		summary.PeakParallelism, summary.MaxConcurrent, summary.MaxConcurrentNetwork, summary.EffectiveParallelism)
+	if err != nil {
+		return err
+	}
+
+	for _, regression := range report.BuildRegressions {
+		fmt.Fprintf(w, "⚠️  %s binary grew %.1fMB → %.1fMB (+%.0f%%) since %s\n",
+			regression.Location, megabytes(regression.PreviousSize), megabytes(regression.Size),
+			100*regression.GrowthFraction, regression.PreviousRun.Format("2006-01-02"))
+	}
+
+	missing := 0
+	for _, result := range report.Results {
+		if result.GoldenMissing {
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(w, "⚠️  %d passing samples have no golden file; write them with --update-golden\n", missing)
+	}
+
+	if report.Coverage == nil {
+		return nil
+	}
+	coverage := report.Coverage
+	fmt.Fprintf(w, "\n📈 SDK coverage: %.1f%% of %s functions reached by samples\n", coverage.Percent, coverage.Module)
+	for _, pkg := range coverage.Packages {
+		if pkg.Imported {
+			fmt.Fprintf(w, "   %-60s %5.1f%%\n", pkg.Package, pkg.Percent)
+		} else {
+			fmt.Fprintf(w, "   %-60s not imported by any sample\n", pkg.Package)
+		}
+	}
+	return nil
+}
+
+func writePlainResult(w io.Writer, result TestResult) {
+	location := fmt.Sprintf("%s:%d", filepath.Base(result.Sample.FilePath), result.Sample.LineNumber)
+	if result.Attempts > 1 {
+		location += fmt.Sprintf(" (attempt %d)", result.Attempts)
+	}
+	switch result.Status {
+	case StatusPassed:
+		fmt.Fprintf(w, "✅ %s (%.2fs)\n", location, result.ExecutionTime)
+	case StatusSkipped:
+		fmt.Fprintf(w, "⏭️  %s skipped: %s\n", location, result.ErrorMessage)
+	case StatusTimeout:
+		fmt.Fprintf(w, "⏱️  %s timed out after %.2fs (limit %.0fs)%s\n", location, result.ExecutionTime, result.TimeoutLimit, quarantineNote(result))
+	default:
+		fmt.Fprintf(w, "❌ %s [%s] (%.2fs)%s\n", location, result.ErrorCategory, result.ExecutionTime, quarantineNote(result))
+	}
+}
+
+// quarantineNote explains why a failure doesn't fail the run
+func quarantineNote(result TestResult) string {
+	if result.Quarantine == nil {
+		return ""
+	}
+	note := " quarantined: " + result.Quarantine.Reason
+	if result.Quarantine.Issue != "" {
+		note += " (" + result.Quarantine.Issue + ")"
+	}
+	return note
+}
+
+// writeVerboseResult prints everything known about a single sample run
+func writeVerboseResult(w io.Writer, result TestResult) {
+	sample := result.Sample
+	fmt.Fprintf(w, "📄 %s:%d-%d\n", sample.FilePath, sample.LineNumber, sample.EndLineNumber)
+	fmt.Fprintf(w, "   ID:       %s\n", sample.ID)
+	fmt.Fprintf(w, "   Product:  %s %s\n", sampleProduct(sample), sample.Feature)
+	fmt.Fprintf(w, "   Needs:    network=%t api_key=%t audio_file=%t\n",
+		sample.RequiresNetwork, sample.RequiresAPIKey, sample.RequiresAudioFile)
+	if len(sample.RequiredScopes) > 0 {
+		fmt.Fprintf(w, "   Scopes:   %s\n", strings.Join(sample.RequiredScopes, ", "))
+	}
+	fmt.Fprintf(w, "   Validate: %s\n", formatValidation(result.ValidationResults))
+	for _, rule := range sortedStringKeys(result.ValidationProblems) {
+		fmt.Fprintf(w, "             %s: %s\n", rule, result.ValidationProblems[rule])
+	}
+	for _, name := range sortedStringKeys(result.Captured) {
+		fmt.Fprintf(w, "   Captured: %s=%s\n", name, result.Captured[name])
+	}
+	fmt.Fprintln(w)
+
+	writePlainResult(w, result)
+	if result.GoldenDiff != "" {
+		fmt.Fprintf(w, "\n--- golden diff (- expected, + actual) ---\n%s", result.GoldenDiff)
+	}
+	if result.GoldenMissing {
+		fmt.Fprintf(w, "\n⚠️  No golden file; output wasn't compared\n")
+	}
+	if result.Stdout != "" {
+		fmt.Fprintf(w, "\n--- output ---\n%s", result.Stdout)
+		if !strings.HasSuffix(result.Stdout, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+	if result.Stderr != "" {
+		fmt.Fprintf(w, "\n--- error ---\n%s\n", result.Stderr)
+	}
+	if result.WorkDir != "" {
+		fmt.Fprintf(w, "\n📁 Workspace kept at %s\n", result.WorkDir)
+	}
+}
+
+// formatValidation renders validation results as "rule=ok, rule=FAIL"
+func formatValidation(results map[string]bool) string {
+	if len(results) == 0 {
+		return "none"
+	}
+
+	rules := make([]string, 0, len(results))
+	for rule := range results {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+
+	parts := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		state := "ok"
+		if !results[rule] {
+			state = "FAIL"
+		}
+		parts = append(parts, rule+"="+state)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeMarkdownReport(w io.Writer, report Report) error {
+	title := report.Language
+	if title != "" {
+		title = strings.ToUpper(title[:1]) + title[1:]
+	}
+	fmt.Fprintf(w, "# %s SDK Documentation Test Report\n\n", title)
+	if report.Environment != "" {
+		fmt.Fprintf(w, "Environment: `%s`\n\n", report.Environment)
+	}
+
+	fmt.Fprintf(w, "## Results\n\n")
+	for _, group := range groupByProduct(report.Results) {
+		fmt.Fprintf(w, "### %s\n\n", sampleProduct(group[0].Sample))
+		fmt.Fprintf(w, "| Sample | Status | Error Category | Time (s) | Build (s) | Binary (MB) | Max RSS (MB) | CPU user/sys (s) | Processes |\n")
+		fmt.Fprintf(w, "|--------|--------|----------------|----------|-----------|-------------|--------------|------------------|-----------|\n")
+		for _, result := range group {
+			usage := result.ResourceUsage
+			fmt.Fprintf(w, "| `%s:%d` | %s | %s | %.2f | %.2f | %.1f | %.1f | %.2f/%.2f | %d |\n",
+				filepath.Base(result.Sample.FilePath), result.Sample.LineNumber,
+				result.Status, result.ErrorCategory, result.ExecutionTime,
+				result.BuildTime, megabytes(result.BinarySize),
+				float64(usage.MaxRSSKB)/1024, usage.UserCPUTime, usage.SystemCPUTime, usage.Subprocesses)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	summary := report.Summary
+	fmt.Fprintf(w, "## Run Summary\n\n")
+	fmt.Fprintf(w, "- **Total samples:** %d\n", summary.Total)
+	fmt.Fprintf(w, "- **By status:** %s\n", formatCounts(summary.ByStatus))
+	fmt.Fprintf(w, "- **By product:** %s\n", formatCounts(summary.ByProduct))
+	fmt.Fprintf(w, "- **By error category:** %s\n", formatCounts(summary.ByErrorCategory))
+	fmt.Fprintf(w, "- **Wall time:** %.2fs (cumulative %.2fs, prefetch %.2fs, cache warm-up %.2fs)\n",
+		summary.WallTime, summary.CumulativeTime, summary.PrefetchTime, summary.WarmUpTime)
+	_, err := fmt.Fprintf(w, "- **Parallelism:** peak %d of %d workers (+%d network-bound), effective %.2f\n",
+		summary.PeakParallelism, summary.MaxConcurrent, summary.MaxConcurrentNetwork, summary.EffectiveParallelism)
+	if err != nil {
+		return err
+	}
+
+	if len(report.BuildRegressions) > 0 {
+		fmt.Fprintf(w, "\n## Build Size Regressions\n\n")
+		fmt.Fprintf(w, "| Sample | Previous (MB) | Now (MB) | Growth | Build (s) | Since |\n")
+		fmt.Fprintf(w, "|--------|---------------|----------|--------|-----------|-------|\n")
+		for _, regression := range report.BuildRegressions {
+			fmt.Fprintf(w, "| `%s` | %.1f | %.1f | +%.0f%% | %.2f → %.2f | %s |\n",
+				regression.Location, megabytes(regression.PreviousSize), megabytes(regression.Size),
+				100*regression.GrowthFraction, regression.PreviousBuild, regression.BuildTime,
+				regression.PreviousRun.Format("2006-01-02"))
+		}
+	}
+
+	if manifest := report.Manifest; manifest != nil {
+		fmt.Fprintf(w, "\n## Run Manifest\n\n")
+		fmt.Fprintf(w, "- **Executor:** %s, %s\n", manifest.ExecutorVersion, manifest.GoVersion)
+		if manifest.SDKCommit != "" {
+			fmt.Fprintf(w, "- **SDK:** `%s` at `%s`\n", manifest.SDKModule, manifest.SDKCommit)
+		}
+		for _, root := range sortedStringKeys(manifest.DocsCommits) {
+			fmt.Fprintf(w, "- **Docs:** `%s` at `%s`\n", root, manifest.DocsCommits[root])
+		}
+		fmt.Fprintf(w, "- **Config hash:** `%s`\n", manifest.ConfigHash)
+		fmt.Fprintf(w, "- **Host:** %s/%s, %d CPUs\n", manifest.Host.OS, manifest.Host.Arch, manifest.Host.CPUs)
+	}
+
+	missing := 0
+	for _, result := range report.Results {
+		if result.GoldenMissing {
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(w, "⚠️  %d passing samples have no golden file; write them with --update-golden\n", missing)
+	}
+
+	if report.Coverage == nil {
+		return nil
+	}
+	coverage := report.Coverage
+	fmt.Fprintf(w, "\n## SDK Coverage\n\n")
+	fmt.Fprintf(w, "%.1f%% of `%s` functions are reached by documentation samples.\n\n", coverage.Percent, coverage.Module)
+	fmt.Fprintf(w, "| Package | Statement Coverage |\n")
+	fmt.Fprintf(w, "|---------|--------------------|\n")
+	for _, pkg := range coverage.Packages {
+		if pkg.Imported {
+			fmt.Fprintf(w, "| `%s` | %.1f%% |\n", pkg.Package, pkg.Percent)
+		} else {
+			fmt.Fprintf(w, "| `%s` | not imported |\n", pkg.Package)
+		}
+	}
+
+	if uncovered := coverage.uncoveredFunctions(); len(uncovered) > 0 {
+		fmt.Fprintf(w, "\n### Functions No Sample Reaches\n\n")
+		for _, function := range uncovered {
+			fmt.Fprintf(w, "- `%s` (%s)\n", function.Function, function.Position)
+		}
+	}
+	return nil
+}
+
+// writeProblemsReport prints one "file:line:column: severity: message" line
+// per problem, the format matched by the documented VS Code problem matcher
+func writeProblemsReport(w io.Writer, report Report) error {
+	for _, result := range report.Results {
+		sample := result.Sample
+		path, err := filepath.Abs(sample.FilePath)
+		if err != nil {
+			path = sample.FilePath
+		}
+
+		problem := func(severity, message string) {
+			fmt.Fprintf(w, "%s:%d:1: %s: %s\n", path, sample.LineNumber, severity, message)
+		}
+
+		switch result.Status {
+		case StatusFailed:
+			problem("error", fmt.Sprintf("sample failed [%s]: %s", result.ErrorCategory, failureMessage(result)))
+		case StatusTimeout:
+			problem("warning", fmt.Sprintf("sample timed out after %.1fs (limit %.0fs)", result.ExecutionTime, result.TimeoutLimit))
+		}
+
+		rules := make([]string, 0, len(result.ValidationResults))
+		for rule, passed := range result.ValidationResults {
+			if !passed {
+				rules = append(rules, rule)
+			}
+		}
+		sort.Strings(rules)
+		for _, rule := range rules {
+			message := "sample fails validation rule " + rule
+			if explanation := result.ValidationProblems[rule]; explanation != "" {
+				message += ": " + explanation
+			}
+			problem("warning", message)
+		}
+	}
+	return nil
+}
+
+// failureMessage picks the most useful single line explaining a failure:
+// the first compiler error if there is one, else the first line of output
+func failureMessage(result TestResult) string {
+	if result.ErrorMessage != "" {
+		return result.ErrorMessage
+	}
+
+	lines := strings.Split(result.Stdout, "\n")
+	for _, line := range lines {
+		if buildErrorRegex.MatchString(line) && !strings.HasPrefix(line, "#") {
+			return strings.TrimSpace(line)
+		}
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return strings.TrimSpace(line)
+		}
+	}
+	return result.Stderr
+}
+
+// megabytes converts a size in bytes for display
+func megabytes(bytes int64) float64 {
+	return float64(bytes) / (1024 * 1024)
+}
+
+// formatCounts renders a count map as "a=1, b=2" in key order
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", key, counts[key]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,202 @@
+package main
+
+// Report diffs
+// Comparing two saved JSON reports shows what a change did to the docs:
+// samples that started or stopped failing, and samples that were added or
+// removed. The markdown form is meant for PR comments and release notes.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReportDiff is what changed between two runs
+type ReportDiff struct {
+	NewlyFailing []SampleChange `json:"newly_failing"`
+	NewlyPassing []SampleChange `json:"newly_passing"`
+	Added        []SampleChange `json:"added"`
+	Removed      []SampleChange `json:"removed"`
+}
+
+// SampleChange is one sample's status in the old run, the new run, or both
+type SampleChange struct {
+	ID        string `json:"id,omitempty"`
+	Location  string `json:"location"`
+	Product   string `json:"product"`
+	OldStatus string `json:"old_status,omitempty"`
+	NewStatus string `json:"new_status,omitempty"`
+	// Error is the first line of the new run's error for newly failing samples
+	Error string `json:"error,omitempty"`
+}
+
+// Empty reports whether the runs differ in nothing the diff tracks
+func (d ReportDiff) Empty() bool {
+	return len(d.NewlyFailing)+len(d.NewlyPassing)+len(d.Added)+len(d.Removed) == 0
+}
+
+// loadReport reads a JSON report written by `run --format json`
+func loadReport(path string) (Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Report{}, err
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return Report{}, fmt.Errorf("parsing %s: %v", path, err)
+	}
+	return report, nil
+}
+
+// resultLocation is a result's page:line, falling back to the file path for
+// reports written before samples carried their page
+func resultLocation(result TestResult) string {
+	page := result.Sample.Page
+	if page == "" {
+		page = result.Sample.FilePath
+	}
+	return fmt.Sprintf("%s:%d", page, result.Sample.LineNumber)
+}
+
+// failing reports whether a status counts as a failure in a diff
+func failing(status string) bool {
+	return status == StatusFailed || status == StatusTimeout
+}
+
+// DiffReports compares two runs. Samples are matched by ID, then by
+// location, so a sample whose code changed in place is still the same sample.
+func DiffReports(old, new Report) ReportDiff {
+	byID := make(map[string]int)
+	byLocation := make(map[string]int)
+	for i, result := range old.Results {
+		if result.Sample.ID != "" {
+			byID[result.Sample.ID] = i
+		}
+		byLocation[resultLocation(result)] = i
+	}
+
+	diff := ReportDiff{
+		NewlyFailing: []SampleChange{},
+		NewlyPassing: []SampleChange{},
+		Added:        []SampleChange{},
+		Removed:      []SampleChange{},
+	}
+	matched := make(map[int]bool)
+	for _, result := range new.Results {
+		i, ok := byID[result.Sample.ID]
+		if !ok || result.Sample.ID == "" || matched[i] {
+			i, ok = byLocation[resultLocation(result)]
+		}
+		change := SampleChange{
+			ID:        result.Sample.ID,
+			Location:  resultLocation(result),
+			Product:   sampleProduct(result.Sample),
+			NewStatus: result.Status,
+		}
+		if !ok || matched[i] {
+			diff.Added = append(diff.Added, change)
+			continue
+		}
+		matched[i] = true
+
+		change.OldStatus = old.Results[i].Status
+		switch {
+		case failing(result.Status) && !failing(change.OldStatus):
+			change.Error, _, _ = strings.Cut(strings.TrimSpace(result.ErrorMessage), "\n")
+			diff.NewlyFailing = append(diff.NewlyFailing, change)
+		case result.Status == StatusPassed && failing(change.OldStatus):
+			diff.NewlyPassing = append(diff.NewlyPassing, change)
+		}
+	}
+
+	for i, result := range old.Results {
+		if matched[i] {
+			continue
+		}
+		diff.Removed = append(diff.Removed, SampleChange{
+			ID:        result.Sample.ID,
+			Location:  resultLocation(result),
+			Product:   sampleProduct(result.Sample),
+			OldStatus: result.Status,
+		})
+	}
+	return diff
+}
+
+// writeReportDiff renders a report diff as plain text, JSON, or Markdown
+func writeReportDiff(w io.Writer, format string, diff ReportDiff) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	case FormatPlain:
+		if diff.Empty() {
+			fmt.Fprintln(w, "No sample changed status")
+			return nil
+		}
+		for _, change := range diff.NewlyFailing {
+			fmt.Fprintf(w, "❌ %s now %s (was %s)\n", change.Location, change.NewStatus, change.OldStatus)
+			if change.Error != "" {
+				fmt.Fprintf(w, "   %s\n", change.Error)
+			}
+		}
+		for _, change := range diff.NewlyPassing {
+			fmt.Fprintf(w, "✅ %s now passes (was %s)\n", change.Location, change.OldStatus)
+		}
+		for _, change := range diff.Added {
+			fmt.Fprintf(w, "➕ %s added (%s)\n", change.Location, change.NewStatus)
+		}
+		for _, change := range diff.Removed {
+			fmt.Fprintf(w, "➖ %s removed (was %s)\n", change.Location, change.OldStatus)
+		}
+		return nil
+	case FormatMarkdown:
+		return writeMarkdownReportDiff(w, diff)
+	default:
+		return fmt.Errorf("unknown diff format: %s", format)
+	}
+}
+
+func writeMarkdownReportDiff(w io.Writer, diff ReportDiff) error {
+	fmt.Fprintf(w, "## Docs sample changes\n\n")
+	if diff.Empty() {
+		fmt.Fprintf(w, "No sample changed status.\n")
+		return nil
+	}
+	fmt.Fprintf(w, "%d newly failing, %d newly passing, %d added, %d removed\n",
+		len(diff.NewlyFailing), len(diff.NewlyPassing), len(diff.Added), len(diff.Removed))
+
+	sections := []struct {
+		title   string
+		changes []SampleChange
+	}{
+		{"❌ Newly failing", diff.NewlyFailing},
+		{"✅ Newly passing", diff.NewlyPassing},
+		{"➕ Added", diff.Added},
+		{"➖ Removed", diff.Removed},
+	}
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n### %s (%d)\n\n", section.title, len(section.changes))
+		fmt.Fprintf(w, "| Sample | Product | Before | After |\n")
+		fmt.Fprintf(w, "|--------|---------|--------|-------|\n")
+		for _, change := range section.changes {
+			fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", change.Location, change.Product,
+				statusOrDash(change.OldStatus), statusOrDash(change.NewStatus))
+		}
+	}
+	return nil
+}
+
+// statusOrDash shows a missing status as a dash in tables
+func statusOrDash(status string) string {
+	if status == "" {
+		return "—"
+	}
+	return status
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestDiffReports(t *testing.T) {
+	result := func(id, page string, line int, status string) TestResult {
+		return TestResult{Sample: CodeSample{ID: id, Page: page, LineNumber: line}, Status: status}
+	}
+	old := Report{Results: []TestResult{
+		result("stt-aaaa", "fern/pages/stt.mdx", 10, StatusPassed),
+		result("stt-bbbb", "fern/pages/stt.mdx", 30, StatusFailed),
+		result("stt-cccc", "fern/pages/stt.mdx", 50, StatusPassed),
+		result("tts-dddd", "fern/pages/tts.mdx", 5, StatusPassed),
+		result("tts-eeee", "fern/pages/tts.mdx", 20, StatusTimeout),
+	}}
+	failed := result("stt-aaaa", "fern/pages/stt.mdx", 14, StatusFailed)
+	failed.ErrorMessage = "build failed\nmore detail"
+	new := Report{Results: []TestResult{
+		// Moved down the page and now failing: matched by ID
+		failed,
+		// Fixed
+		result("stt-bbbb", "fern/pages/stt.mdx", 34, StatusPassed),
+		// Code changed in place: matched by location
+		result("stt-ffff", "fern/pages/stt.mdx", 50, StatusPassed),
+		// Still timing out
+		result("tts-eeee", "fern/pages/tts.mdx", 20, StatusTimeout),
+		// New sample
+		result("tts-gggg", "fern/pages/tts.mdx", 40, StatusSkipped),
+	}}
+
+	diff := DiffReports(old, new)
+	checks := []struct {
+		name    string
+		changes []SampleChange
+		want    []string
+	}{
+		{"newly failing", diff.NewlyFailing, []string{"fern/pages/stt.mdx:14"}},
+		{"newly passing", diff.NewlyPassing, []string{"fern/pages/stt.mdx:34"}},
+		{"added", diff.Added, []string{"fern/pages/tts.mdx:40"}},
+		{"removed", diff.Removed, []string{"fern/pages/tts.mdx:5"}},
+	}
+	for _, check := range checks {
+		var got []string
+		for _, change := range check.changes {
+			got = append(got, change.Location)
+		}
+		if len(got) != len(check.want) || (len(got) > 0 && got[0] != check.want[0]) {
+			t.Errorf("%s = %v, want %v", check.name, got, check.want)
+		}
+	}
+	if len(diff.NewlyFailing) == 1 {
+		change := diff.NewlyFailing[0]
+		if change.OldStatus != StatusPassed || change.NewStatus != StatusFailed || change.Error != "build failed" {
+			t.Errorf("newly failing change = %+v", change)
+		}
+	}
+	if diff.Empty() {
+		t.Error("Empty() = true for a diff with changes")
+	}
+	if !DiffReports(old, old).Empty() {
+		t.Errorf("a report diffed with itself isn't empty: %+v", DiffReports(old, old))
+	}
+}
+
+func TestDiffReportsWithoutIDs(t *testing.T) {
+	// Reports from before stable IDs match by location, falling back to the file path
+	old := Report{Results: []TestResult{{Sample: CodeSample{FilePath: "docs/stt.mdx", LineNumber: 3}, Status: StatusPassed}}}
+	new := Report{Results: []TestResult{{Sample: CodeSample{FilePath: "docs/stt.mdx", LineNumber: 3}, Status: StatusTimeout}}}
+	diff := DiffReports(old, new)
+	if len(diff.NewlyFailing) != 1 || len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("DiffReports = %+v, want one newly failing sample", diff)
+	}
+}
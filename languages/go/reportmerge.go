@@ -0,0 +1,360 @@
+package main
+
+// Report merges
+// The Go, Python, and JavaScript executors each report on the same docs
+// tree, but a writer fixing a page wants one view of it. `report merge`
+// joins reports by page and by each sample's position on the page among
+// its language's samples, since a page shows an example as one tab per
+// language, and prints a table of every page's samples across languages
+// with a summary. A language reported more than once, e.g. by a rerun, is
+// deduplicated: results from later reports replace earlier ones at the same
+// page and line.
+//
+// Reports from the Python-driven executors name pages by file name only, so
+// they're matched to a full page path from another report when exactly one
+// has that name.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MergedReport is several languages' reports on one docs tree
+type MergedReport struct {
+	Languages []string     `json:"languages"`
+	Summary   MergeSummary `json:"summary"`
+	Pages     []MergedPage `json:"pages"`
+}
+
+// MergeSummary counts pages and samples across languages
+type MergeSummary struct {
+	Pages        int `json:"pages"`
+	PassingPages int `json:"passing_pages"`
+	FailingPages int `json:"failing_pages"`
+	Positions    int `json:"positions"`
+	// FailingEverywhere counts positions with samples in several languages
+	// that fail in all of them, which points at the API or the page rather
+	// than one SDK
+	FailingEverywhere int `json:"failing_everywhere"`
+	// Missing counts positions lacking a sample in some language
+	Missing int `json:"missing"`
+	// ByLanguage counts each language's results by status
+	ByLanguage map[string]map[string]int `json:"by_language"`
+	// Duplicates counts results replaced by a later report
+	Duplicates int `json:"duplicates"`
+}
+
+// MergedPage is one page's samples across languages
+type MergedPage struct {
+	Page    string         `json:"page"`
+	Failing bool           `json:"failing"`
+	Samples []MergedSample `json:"samples"`
+}
+
+// MergedSample is the samples at one position on a page, by language
+type MergedSample struct {
+	// Position is the sample's 1-based place among its language's samples
+	Position int                     `json:"position"`
+	Results  map[string]MergedResult `json:"results"`
+}
+
+// MergedResult is one language's sample at a position
+type MergedResult struct {
+	ID     string `json:"id,omitempty"`
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+	// Error is the first line of a failed sample's error
+	Error string `json:"error,omitempty"`
+}
+
+// languageResult is a result read from any executor's report
+type languageResult struct {
+	page   string
+	line   int
+	result MergedResult
+}
+
+// loadLanguageReport reads a JSON report from this executor or from the
+// Python-driven executors, returning its language and results
+func loadLanguageReport(file string) (string, []languageResult, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	var report struct {
+		Language string            `json:"language"`
+		Results  []json.RawMessage `json:"results"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return "", nil, fmt.Errorf("parsing %s: %v", file, err)
+	}
+	if report.Language == "" {
+		return "", nil, fmt.Errorf("%s doesn't name its language", file)
+	}
+
+	var results []languageResult
+	for _, raw := range report.Results {
+		var entry struct {
+			// This executor's results
+			Sample       *CodeSample `json:"sample"`
+			Status       string      `json:"status"`
+			ErrorMessage string      `json:"error_message"`
+			Stderr       string      `json:"stderr"`
+			// The Python-driven executors' results
+			File    string `json:"file"`
+			Line    int    `json:"line"`
+			Success bool   `json:"success"`
+			Error   string `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return "", nil, fmt.Errorf("parsing %s: %v", file, err)
+		}
+
+		var result languageResult
+		if entry.Sample != nil {
+			result.page = entry.Sample.Page
+			if result.page == "" {
+				result.page = pagePath(entry.Sample.FilePath)
+			}
+			result.line = entry.Sample.LineNumber
+			result.result = MergedResult{ID: entry.Sample.ID, Line: entry.Sample.LineNumber, Status: entry.Status, Error: entry.ErrorMessage}
+			if strings.TrimSpace(result.result.Error) == "" {
+				result.result.Error = entry.Stderr
+			}
+		} else {
+			result.page, result.line = strings.ReplaceAll(entry.File, "\\", "/"), entry.Line
+			result.result = MergedResult{Line: entry.Line, Status: StatusPassed}
+			if !entry.Success {
+				result.result.Status, result.result.Error = StatusFailed, entry.Error
+			}
+		}
+		if !failing(result.result.Status) {
+			result.result.Error = ""
+		}
+		result.result.Error, _, _ = strings.Cut(strings.TrimSpace(result.result.Error), "\n")
+		results = append(results, result)
+	}
+	return report.Language, results, nil
+}
+
+// MergeReports joins reports, given as language and results in the order
+// they were read
+func MergeReports(languages []string, reports [][]languageResult) MergedReport {
+	merged := MergedReport{Pages: []MergedPage{}, Summary: MergeSummary{ByLanguage: make(map[string]map[string]int)}}
+
+	// Bare file names take the full path of the one page with that name
+	byName := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, results := range reports {
+		for _, result := range results {
+			if strings.Contains(result.page, "/") && !seen[result.page] {
+				seen[result.page] = true
+				byName[path.Base(result.page)] = append(byName[path.Base(result.page)], result.page)
+			}
+		}
+	}
+
+	// Later reports replace a language's results at the same page and line
+	type key struct {
+		language string
+		page     string
+		line     int
+	}
+	latest := make(map[key]MergedResult)
+	for i, results := range reports {
+		language := languages[i]
+		if !containsString(merged.Languages, language) {
+			merged.Languages = append(merged.Languages, language)
+		}
+		for _, result := range results {
+			page := result.page
+			if !strings.Contains(page, "/") && len(byName[page]) == 1 {
+				page = byName[page][0]
+			}
+			k := key{language, page, result.line}
+			if _, ok := latest[k]; ok {
+				merged.Summary.Duplicates++
+			}
+			latest[k] = result.result
+		}
+	}
+
+	// Each language's samples on a page are numbered in line order
+	byPage := make(map[string]map[string][]MergedResult)
+	for k, result := range latest {
+		if byPage[k.page] == nil {
+			byPage[k.page] = make(map[string][]MergedResult)
+		}
+		byPage[k.page][k.language] = append(byPage[k.page][k.language], result)
+	}
+	pages := make([]string, 0, len(byPage))
+	for page := range byPage {
+		pages = append(pages, page)
+	}
+	sort.Strings(pages)
+
+	for _, page := range pages {
+		merged.Summary.Pages++
+		entry := MergedPage{Page: page}
+		positions := 0
+		for _, results := range byPage[page] {
+			sort.Slice(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+			if len(results) > positions {
+				positions = len(results)
+			}
+		}
+
+		for position := 0; position < positions; position++ {
+			sample := MergedSample{Position: position + 1, Results: make(map[string]MergedResult)}
+			failures := 0
+			for _, language := range merged.Languages {
+				results := byPage[page][language]
+				if position >= len(results) {
+					continue
+				}
+				result := results[position]
+				sample.Results[language] = result
+				if merged.Summary.ByLanguage[language] == nil {
+					merged.Summary.ByLanguage[language] = make(map[string]int)
+				}
+				merged.Summary.ByLanguage[language][result.Status]++
+				if failing(result.Status) {
+					failures++
+				}
+			}
+
+			merged.Summary.Positions++
+			if len(sample.Results) < len(merged.Languages) {
+				merged.Summary.Missing++
+			}
+			if failures > 0 {
+				entry.Failing = true
+				if failures == len(sample.Results) && failures > 1 {
+					merged.Summary.FailingEverywhere++
+				}
+			}
+			entry.Samples = append(entry.Samples, sample)
+		}
+
+		if entry.Failing {
+			merged.Summary.FailingPages++
+		} else {
+			merged.Summary.PassingPages++
+		}
+		merged.Pages = append(merged.Pages, entry)
+	}
+	return merged
+}
+
+// mergedStatusIcons show a status in a table cell
+var mergedStatusIcons = map[string]string{
+	StatusPassed:  "✅",
+	StatusFailed:  "❌",
+	StatusTimeout: "⏱️",
+	StatusSkipped: "⏭️",
+}
+
+// mergedCell renders one language's result at a position
+func mergedCell(sample MergedSample, language string) string {
+	result, ok := sample.Results[language]
+	if !ok {
+		return "—"
+	}
+	icon, ok := mergedStatusIcons[result.Status]
+	if !ok {
+		icon = result.Status
+	}
+	return fmt.Sprintf("%s L%d", icon, result.Line)
+}
+
+// writeMergedReport renders a merged report as plain text, JSON, or Markdown
+func writeMergedReport(w io.Writer, format string, merged MergedReport) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(merged)
+	case FormatPlain:
+		for _, page := range merged.Pages {
+			icon := "✅"
+			if page.Failing {
+				icon = "❌"
+			}
+			fmt.Fprintf(w, "%s %s\n", icon, page.Page)
+			for _, sample := range page.Samples {
+				cells := make([]string, len(merged.Languages))
+				for i, language := range merged.Languages {
+					cells[i] = language + " " + mergedCell(sample, language)
+				}
+				fmt.Fprintf(w, "   #%d  %s\n", sample.Position, strings.Join(cells, "  "))
+				for _, language := range merged.Languages {
+					if result := sample.Results[language]; result.Error != "" {
+						fmt.Fprintf(w, "       %s: %s\n", language, result.Error)
+					}
+				}
+			}
+		}
+		fmt.Fprintln(w)
+		writeMergeSummary(w, merged)
+		return nil
+	case FormatMarkdown:
+		return writeMarkdownMergedReport(w, merged)
+	default:
+		return fmt.Errorf("unknown merge format: %s", format)
+	}
+}
+
+func writeMergeSummary(w io.Writer, merged MergedReport) {
+	summary := merged.Summary
+	fmt.Fprintf(w, "📊 %d pages: %d passing, %d failing; %d sample positions, %d failing in every language, %d missing a language\n",
+		summary.Pages, summary.PassingPages, summary.FailingPages, summary.Positions, summary.FailingEverywhere, summary.Missing)
+	for _, language := range merged.Languages {
+		fmt.Fprintf(w, "   %-12s %s\n", language+":", formatCounts(summary.ByLanguage[language]))
+	}
+	if summary.Duplicates > 0 {
+		fmt.Fprintf(w, "   %d results were replaced by later reports\n", summary.Duplicates)
+	}
+}
+
+func writeMarkdownMergedReport(w io.Writer, merged MergedReport) error {
+	summary := merged.Summary
+	fmt.Fprintf(w, "## Docs samples across languages\n\n")
+	fmt.Fprintf(w, "%d pages: %d passing, %d failing. %d sample positions, %d failing in every language, %d missing a language.\n\n",
+		summary.Pages, summary.PassingPages, summary.FailingPages, summary.Positions, summary.FailingEverywhere, summary.Missing)
+
+	fmt.Fprintf(w, "| Page | # | %s |\n", strings.Join(merged.Languages, " | "))
+	fmt.Fprintf(w, "|------|---|%s\n", strings.Repeat("------|", len(merged.Languages)))
+	for _, page := range merged.Pages {
+		for i, sample := range page.Samples {
+			name := ""
+			if i == 0 {
+				name = "`" + page.Page + "`"
+			}
+			cells := make([]string, len(merged.Languages))
+			for j, language := range merged.Languages {
+				cells[j] = mergedCell(sample, language)
+			}
+			fmt.Fprintf(w, "| %s | %d | %s |\n", name, sample.Position, strings.Join(cells, " | "))
+		}
+	}
+
+	var failures []string
+	for _, page := range merged.Pages {
+		for _, sample := range page.Samples {
+			for _, language := range merged.Languages {
+				if result := sample.Results[language]; result.Error != "" {
+					failures = append(failures, fmt.Sprintf("- `%s:%d` (%s): %s", page.Page, result.Line, language, result.Error))
+				}
+			}
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(w, "\n### ❌ Failures\n\n%s\n", strings.Join(failures, "\n"))
+	}
+	return nil
+}
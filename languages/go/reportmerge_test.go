@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeReports(t *testing.T) {
+	result := func(page string, line int, status string) languageResult {
+		return languageResult{page: page, line: line, result: MergedResult{Line: line, Status: status}}
+	}
+	reports := [][]languageResult{
+		{
+			result("fern/pages/stt.mdx", 10, StatusPassed),
+			result("fern/pages/stt.mdx", 40, StatusFailed),
+			result("fern/pages/tts.mdx", 8, StatusPassed),
+		},
+		// The Python executor names pages by file name
+		{
+			result("stt.mdx", 12, StatusPassed),
+			result("stt.mdx", 44, StatusFailed),
+		},
+		// A rerun of Go replaces its earlier result at the same line
+		{
+			result("fern/pages/tts.mdx", 8, StatusFailed),
+		},
+	}
+	merged := MergeReports([]string{"go", "python", "go"}, reports)
+
+	if len(merged.Languages) != 2 || merged.Languages[0] != "go" || merged.Languages[1] != "python" {
+		t.Errorf("Languages = %v, want [go python]", merged.Languages)
+	}
+	want := MergeSummary{
+		Pages:             2,
+		PassingPages:      0,
+		FailingPages:      2,
+		Positions:         3,
+		FailingEverywhere: 1,
+		Missing:           1,
+		Duplicates:        1,
+	}
+	got := merged.Summary
+	got.ByLanguage = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Summary = %+v, want %+v", got, want)
+	}
+	if counts := merged.Summary.ByLanguage["go"]; counts[StatusPassed] != 1 || counts[StatusFailed] != 2 {
+		t.Errorf("go counts = %v", counts)
+	}
+
+	if len(merged.Pages) != 2 || merged.Pages[0].Page != "fern/pages/stt.mdx" {
+		t.Fatalf("Pages = %+v", merged.Pages)
+	}
+	second := merged.Pages[0].Samples[1]
+	if second.Position != 2 || second.Results["go"].Line != 40 || second.Results["python"].Line != 44 {
+		t.Errorf("second stt sample = %+v", second)
+	}
+	if _, ok := merged.Pages[1].Samples[0].Results["python"]; ok {
+		t.Errorf("tts has no python sample, got %+v", merged.Pages[1].Samples[0])
+	}
+}
+
+func TestMergeReportsAmbiguousName(t *testing.T) {
+	// A bare name matching two pages stays as it is
+	reports := [][]languageResult{
+		{
+			{page: "fern/pages/a/intro.mdx", line: 1, result: MergedResult{Line: 1, Status: StatusPassed}},
+			{page: "fern/pages/b/intro.mdx", line: 1, result: MergedResult{Line: 1, Status: StatusPassed}},
+		},
+		{{page: "intro.mdx", line: 2, result: MergedResult{Line: 2, Status: StatusPassed}}},
+	}
+	merged := MergeReports([]string{"go", "python"}, reports)
+	if merged.Summary.Pages != 3 {
+		t.Errorf("Pages = %d, want 3 with intro.mdx unmatched", merged.Summary.Pages)
+	}
+}
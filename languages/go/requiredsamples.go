@@ -0,0 +1,320 @@
+package main
+
+// Required-sample policies
+// Docs standards like "every TTS page shows a runnable Go sample and a curl
+// request" are easy to agree on and easy to forget. required_samples in the
+// framework config turns them into checks: each policy names the pages it
+// covers by glob, against page names as reported (relative to the docs
+// root, prefixed by the root's name for configured roots), and how many
+// samples of each kind they need. `dgtest required-samples` lists pages
+// short of a policy and fails on those whose policy is an error.
+//
+//	required_samples:
+//	  - name: tts
+//	    pages: ["fern/pages/docs/tts/**"]
+//	    require: {go: 1, curl: 1}
+//	    severity: error
+//
+// go counts the Go samples the executor tests; curl counts shell blocks
+// running curl; any other kind counts code blocks fenced with that
+// language, so python counts ```python and ```py blocks.
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severities of a required-sample policy
+const (
+	PolicySeverityError   = "error"
+	PolicySeverityWarning = "warning"
+)
+
+// Sample kinds with their own counting rules
+const (
+	SampleKindGo   = "go"
+	SampleKindCurl = "curl"
+)
+
+// fenceLanguageRegex matches a code block's opening fence and its language
+var fenceLanguageRegex = regexp.MustCompile("(?m)^[ \t]*```([\\w+-]*)[^\n]*\n")
+
+// fenceLanguageAliases map fence languages to the kind they count as
+var fenceLanguageAliases = map[string]string{
+	"golang":  "go",
+	"py":      "python",
+	"js":      "javascript",
+	"ts":      "typescript",
+	"cs":      "csharp",
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"console": "shell",
+}
+
+// curlRegex matches a curl invocation in a shell block
+var curlRegex = regexp.MustCompile(`(?m)^\s*(?:\$\s*)?curl\b`)
+
+// SamplePolicy requires samples of some kinds on a set of pages
+type SamplePolicy struct {
+	Name     string         `json:"name"`
+	Pages    []string       `json:"pages"`
+	Require  map[string]int `json:"require"`
+	Severity string         `json:"severity"`
+}
+
+// PolicyViolation is a page short of a kind of sample a policy requires
+type PolicyViolation struct {
+	Page     string `json:"page"`
+	Policy   string `json:"policy"`
+	Severity string `json:"severity"`
+	Kind     string `json:"kind"`
+	Required int    `json:"required"`
+	Found    int    `json:"found"`
+}
+
+// RequiredSamplesReport is the outcome of checking every policy
+type RequiredSamplesReport struct {
+	Policies []SamplePolicy `json:"policies"`
+	// Pages counts the pages at least one policy covers
+	Pages      int               `json:"pages"`
+	Violations []PolicyViolation `json:"violations"`
+}
+
+// samplePolicies reads required_samples from the framework config
+func (e *GoExecutor) samplePolicies() ([]SamplePolicy, error) {
+	configured, _ := e.FrameworkConfig["required_samples"].([]interface{})
+
+	var policies []SamplePolicy
+	for i, entry := range configured {
+		section, _ := entry.(map[string]interface{})
+		policy := SamplePolicy{
+			Name:     configString(section, "name", fmt.Sprintf("policy_%d", i)),
+			Pages:    configStrings(section, "pages"),
+			Require:  make(map[string]int),
+			Severity: configString(section, "severity", PolicySeverityError),
+		}
+		if pages := configString(section, "pages", ""); pages != "" {
+			policy.Pages = []string{pages}
+		}
+		for kind := range configSection(section, "require") {
+			policy.Require[sampleKind(kind)] = configInt(configSection(section, "require"), kind, 0)
+		}
+
+		switch {
+		case len(policy.Pages) == 0:
+			return nil, fmt.Errorf("required_samples policy %s names no pages", policy.Name)
+		case len(policy.Require) == 0:
+			return nil, fmt.Errorf("required_samples policy %s requires no samples", policy.Name)
+		case policy.Severity != PolicySeverityError && policy.Severity != PolicySeverityWarning:
+			return nil, fmt.Errorf("required_samples policy %s: unknown severity %q (expected %s or %s)", policy.Name, policy.Severity, PolicySeverityError, PolicySeverityWarning)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// sampleKind normalizes a fence language to the kind it counts as
+func sampleKind(language string) string {
+	language = strings.ToLower(language)
+	if kind, ok := fenceLanguageAliases[language]; ok {
+		return kind
+	}
+	return language
+}
+
+// countPageSamples counts a page's samples of each kind
+func (e *GoExecutor) countPageSamples(path, content string) map[string]int {
+	counts := map[string]int{SampleKindGo: len(e.extractGoSamplesFromContent(path, content))}
+
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	fences := fenceLanguageRegex.FindAllStringSubmatchIndex(content, -1)
+	// Fences pair up as opening and closing; closing fences have no language
+	open := true
+	for i, fence := range fences {
+		if !open {
+			open = true
+			continue
+		}
+		open = false
+		kind := sampleKind(content[fence[2]:fence[3]])
+		if kind == "" || kind == SampleKindGo {
+			continue
+		}
+		counts[kind]++
+
+		if kind == "shell" {
+			end := len(content)
+			if i+1 < len(fences) {
+				end = fences[i+1][0]
+			}
+			if curlRegex.MatchString(content[fence[1]:end]) {
+				counts[SampleKindCurl]++
+			}
+		}
+	}
+	return counts
+}
+
+// CheckRequiredSamples checks every page the policies cover
+func (e *GoExecutor) CheckRequiredSamples(ctx context.Context, roots []docsRoot) (RequiredSamplesReport, error) {
+	policies, err := e.samplePolicies()
+	if err != nil {
+		return RequiredSamplesReport{}, err
+	}
+	report := RequiredSamplesReport{Policies: policies, Violations: []PolicyViolation{}}
+	if len(policies) == 0 {
+		return report, nil
+	}
+
+	for _, root := range roots {
+		if root.Git != "" {
+			path, err := checkoutRoot(ctx, e.Audit, root)
+			if err != nil {
+				return report, fmt.Errorf("documentation root %q: %v", root.Name, err)
+			}
+			root.Path = path
+		}
+
+		err := walkPages(ctx, root, func(path string, content []byte) error {
+			page := root.pageName(path)
+			var covering []SamplePolicy
+			for _, policy := range policies {
+				for _, pattern := range policy.Pages {
+					if matchGlob(pattern, page) {
+						covering = append(covering, policy)
+						break
+					}
+				}
+			}
+			if len(covering) == 0 {
+				return nil
+			}
+
+			report.Pages++
+			counts := e.countPageSamples(path, string(content))
+			for _, policy := range covering {
+				kinds := make([]string, 0, len(policy.Require))
+				for kind := range policy.Require {
+					kinds = append(kinds, kind)
+				}
+				sort.Strings(kinds)
+				for _, kind := range kinds {
+					if counts[kind] < policy.Require[kind] {
+						report.Violations = append(report.Violations, PolicyViolation{
+							Page:     page,
+							Policy:   policy.Name,
+							Severity: policy.Severity,
+							Kind:     kind,
+							Required: policy.Require[kind],
+							Found:    counts[kind],
+						})
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil && root.Name != "" {
+			return report, fmt.Errorf("documentation root %q: %v", root.Name, err)
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+
+	sort.SliceStable(report.Violations, func(i, j int) bool { return report.Violations[i].Page < report.Violations[j].Page })
+	return report, nil
+}
+
+// errors counts the violations of error-severity policies
+func (r RequiredSamplesReport) errors() int {
+	count := 0
+	for _, violation := range r.Violations {
+		if violation.Severity == PolicySeverityError {
+			count++
+		}
+	}
+	return count
+}
+
+// writeRequiredSamples renders a policy check as plain text, JSON, or Markdown
+func writeRequiredSamples(w io.Writer, format string, report RequiredSamplesReport) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case FormatPlain:
+		for _, violation := range report.Violations {
+			icon := "❌"
+			if violation.Severity == PolicySeverityWarning {
+				icon = "⚠️ "
+			}
+			fmt.Fprintf(w, "%s %s: policy %s requires %d %s sample(s), found %d\n",
+				icon, violation.Page, violation.Policy, violation.Required, violation.Kind, violation.Found)
+		}
+		errors := report.errors()
+		fmt.Fprintf(w, "📏 %d pages checked against %d policies: %d errors, %d warnings\n",
+			report.Pages, len(report.Policies), errors, len(report.Violations)-errors)
+		return nil
+	case FormatMarkdown:
+		errors := report.errors()
+		fmt.Fprintf(w, "## Required samples\n\n%d pages checked against %d policies: %d errors, %d warnings.\n",
+			report.Pages, len(report.Policies), errors, len(report.Violations)-errors)
+		if len(report.Violations) == 0 {
+			return nil
+		}
+		fmt.Fprintf(w, "\n| Page | Policy | Needs | Found |\n|------|--------|-------|-------|\n")
+		for _, violation := range report.Violations {
+			icon := "❌"
+			if violation.Severity == PolicySeverityWarning {
+				icon = "⚠️"
+			}
+			fmt.Fprintf(w, "| %s `%s` | %s | %d %s | %d |\n", icon, violation.Page, violation.Policy, violation.Required, violation.Kind, violation.Found)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown required-samples format: %s", format)
+	}
+}
+
+// requiredSamplesCommand checks pages against the required_samples
+// policies, failing when an error-severity policy is violated
+func requiredSamplesCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("required-samples", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	format := flags.String("format", FormatPlain, "Report format: plain, json, or markdown")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	executor, err := docs.executor()
+	if err != nil {
+		return err
+	}
+	roots, err := executor.docsRoots(*docs.docsPath)
+	if err != nil {
+		return err
+	}
+	report, err := executor.CheckRequiredSamples(ctx, roots)
+	if err != nil {
+		return err
+	}
+	if len(report.Policies) == 0 {
+		return fmt.Errorf("no required_samples policies are configured")
+	}
+	if err := writeRequiredSamples(os.Stdout, *format, report); err != nil {
+		return err
+	}
+	if errors := report.errors(); errors > 0 {
+		return fmt.Errorf("%d required-sample violations with error severity", errors)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+// Per-sample resource usage
+// Figures come from the wait status of each process the executor spawns.
+// Samples are compiled with `go build` and the binary is run on its own, so
+// the compiler (with the children it reaps) and the sample are each
+// recorded separately: CPU time is summed and MaxRSSKB is the largest.
+
+import (
+	"os"
+)
+
+// ResourceUsage summarizes what the processes spawned for a sample consumed
+type ResourceUsage struct {
+	MaxRSSKB      int64   `json:"max_rss_kb"`
+	UserCPUTime   float64 `json:"user_cpu_time"`
+	SystemCPUTime float64 `json:"system_cpu_time"`
+	Subprocesses  int     `json:"subprocesses"`
+}
+
+// record adds the usage of a finished process; processes that never started
+// leave no state and are not counted
+func (u *ResourceUsage) record(state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+
+	u.Subprocesses++
+	u.UserCPUTime += state.UserTime().Seconds()
+	u.SystemCPUTime += state.SystemTime().Seconds()
+	if rss := maxRSSKB(state); rss > u.MaxRSSKB {
+		u.MaxRSSKB = rss
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// maxRSSKB is unavailable without rusage
+func maxRSSKB(state *os.ProcessState) int64 {
+	return 0
+}
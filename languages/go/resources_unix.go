@@ -0,0 +1,23 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSSKB reads the peak resident set size from the process rusage.
+// Darwin reports ru_maxrss in bytes, other unixes in kilobytes.
+func maxRSSKB(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(rusage.Maxrss) / 1024
+	}
+	return int64(rusage.Maxrss)
+}
@@ -0,0 +1,71 @@
+package main
+
+// Import path rewriting
+// Partners who maintain an SDK fork want to validate the public docs
+// against it without editing the samples. sdk.import_rewrites maps import
+// path prefixes to replacements, applied to samples as they are prepared,
+// e.g. github.com/deepgram/deepgram-go-sdk to github.com/ourorg/fork. A
+// prefix matches whole path elements, so .../v3/pkg/client becomes
+// github.com/ourorg/fork/v3/pkg/client.
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// importRewrite replaces one import path prefix
+type importRewrite struct {
+	From string
+	To   string
+}
+
+// importRewrites returns the configured rewrites, longest prefix first so
+// the most specific one wins
+func (e *GoExecutor) importRewrites() []importRewrite {
+	configured := configSection(configSection(e.LanguageConfig, "sdk"), "import_rewrites")
+
+	var rewrites []importRewrite
+	for from, to := range configured {
+		replacement, ok := to.(string)
+		from = strings.TrimSuffix(from, "/")
+		if !ok || from == "" {
+			continue
+		}
+		rewrites = append(rewrites, importRewrite{From: from, To: strings.TrimSuffix(replacement, "/")})
+	}
+	sort.Slice(rewrites, func(i, j int) bool { return len(rewrites[i].From) > len(rewrites[j].From) })
+	return rewrites
+}
+
+// rewriteImportPath applies the first matching rewrite to an import path
+func (e *GoExecutor) rewriteImportPath(importPath string) string {
+	return applyImportRewrites(e.importRewrites(), importPath)
+}
+
+func applyImportRewrites(rewrites []importRewrite, importPath string) string {
+	for _, rewrite := range rewrites {
+		if importPath == rewrite.From || strings.HasPrefix(importPath, rewrite.From+"/") {
+			return rewrite.To + strings.TrimPrefix(importPath, rewrite.From)
+		}
+	}
+	return importPath
+}
+
+// rewriteImports rewrites import path literals in a sample's code. Each
+// literal is rewritten once, so a replacement can't match a later rule.
+func (e *GoExecutor) rewriteImports(code string) string {
+	rewrites := e.importRewrites()
+	if len(rewrites) == 0 {
+		return code
+	}
+
+	var prefixes []string
+	for _, rewrite := range rewrites {
+		prefixes = append(prefixes, regexp.QuoteMeta(rewrite.From))
+	}
+	literals := regexp.MustCompile(`"(?:` + strings.Join(prefixes, "|") + `)(?:/[^"\s]*)?"`)
+	return literals.ReplaceAllStringFunc(code, func(literal string) string {
+		return `"` + applyImportRewrites(rewrites, strings.Trim(literal, `"`)) + `"`
+	})
+}
@@ -0,0 +1,207 @@
+package main
+
+// Documentation roots
+// Samples can live in more than one repository: the docs site, the SDK
+// wiki, a tutorials repo. documentation.roots lists each source, either a
+// local path or a git URL checked out into the user cache, with its own
+// pages path and walk globs. Every root's samples are merged into one run,
+// and pages from a named root are reported as <name>/<page> so IDs stay
+// unique across roots.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// docsRoot is one source of documentation pages
+type docsRoot struct {
+	Name         string
+	Path         string
+	Git          string
+	Ref          string
+	PagesPath    string
+	FilePatterns []string
+	ExcludePaths []string
+}
+
+// defaultPagesPath is where pages live inside a Fern docs tree
+const defaultPagesPath = "fern/pages"
+
+// gitCheckoutTimeout bounds cloning or updating a git docs root
+const gitCheckoutTimeout = 5 * time.Minute
+
+// docsRoots returns the roots for a run: the single tree at docsPath when
+// one is given, otherwise the roots listed in documentation.roots
+func (e *GoExecutor) docsRoots(docsPath string) ([]docsRoot, error) {
+	documentation := configSection(e.FrameworkConfig, "documentation")
+	defaults := docsRoot{
+		PagesPath:    configString(documentation, "pages_path", defaultPagesPath),
+		FilePatterns: configStrings(documentation, "file_patterns"),
+		ExcludePaths: configStrings(documentation, "exclude_paths"),
+	}
+
+	if docsPath != "" {
+		root := defaults
+		root.Path = docsPath
+		return []docsRoot{root}, nil
+	}
+
+	configured, _ := documentation["roots"].([]interface{})
+	if len(configured) == 0 {
+		return nil, fmt.Errorf("--docs-path is required when documentation.roots isn't configured")
+	}
+
+	var roots []docsRoot
+	names := make(map[string]bool)
+	for i, entry := range configured {
+		section, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("documentation.roots[%d] must be an object", i)
+		}
+		root := docsRoot{
+			Name:         configString(section, "name", ""),
+			Path:         configString(section, "path", ""),
+			Git:          configString(section, "git", ""),
+			Ref:          configString(section, "ref", ""),
+			PagesPath:    configString(section, "pages_path", defaults.PagesPath),
+			FilePatterns: configStrings(section, "file_patterns"),
+			ExcludePaths: configStrings(section, "exclude_paths"),
+		}
+		if root.FilePatterns == nil {
+			root.FilePatterns = defaults.FilePatterns
+		}
+		if root.ExcludePaths == nil {
+			root.ExcludePaths = defaults.ExcludePaths
+		}
+
+		switch {
+		case root.Name == "":
+			return nil, fmt.Errorf("documentation.roots[%d] needs a name", i)
+		case names[root.Name]:
+			return nil, fmt.Errorf("documentation.roots has two roots named %q", root.Name)
+		case (root.Path == "") == (root.Git == ""):
+			return nil, fmt.Errorf("documentation root %q needs exactly one of path or git", root.Name)
+		}
+		names[root.Name] = true
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// ExtractRoots extracts the samples from every root, checking out git roots first
+func (e *GoExecutor) ExtractRoots(ctx context.Context, roots []docsRoot) ([]CodeSample, error) {
+	var samples []CodeSample
+	for _, root := range roots {
+		if root.Git != "" {
+			path, err := checkoutRoot(ctx, e.Audit, root)
+			if err != nil {
+				return nil, fmt.Errorf("documentation root %q: %v", root.Name, err)
+			}
+			root.Path = path
+		}
+
+		rootSamples, err := e.extractRoot(ctx, root)
+		if err != nil {
+			return nil, fmt.Errorf("documentation root %q: %v", root.Name, err)
+		}
+		samples = append(samples, rootSamples...)
+	}
+	return samples, nil
+}
+
+// pageName names a page within its root, prefixed by the root's name if it has one
+func (root docsRoot) pageName(path string) string {
+	page := docsRelativePath(root.Path, path)
+	if root.Name != "" {
+		page = root.Name + "/" + page
+	}
+	return page
+}
+
+// pagePath names a page found outside a walk, such as a single run target,
+// starting from fern/pages when the path contains it
+func pagePath(path string) string {
+	page := filepath.ToSlash(path)
+	if i := strings.Index(page, defaultPagesPath+"/"); i >= 0 {
+		page = page[i:]
+	}
+	return page
+}
+
+// checkoutRoot clones a git root into the user cache, or updates an
+// existing clone, and returns its path. Clones are shallow since only the
+// checked-out pages are read.
+func checkoutRoot(ctx context.Context, audit *AuditLog, root docsRoot) (string, error) {
+	dir, err := checkoutDir(root)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gitCheckoutTimeout)
+	defer cancel()
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if root.Ref != "" {
+			args = append(args, "--branch", root.Ref)
+		}
+		if err := runGit(ctx, audit, "", append(args, root.Git, dir)...); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		return dir, nil
+	}
+
+	ref := root.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := runGit(ctx, audit, dir, "fetch", "--quiet", "--depth", "1", "origin", ref); err != nil {
+		return "", err
+	}
+	if err := runGit(ctx, audit, dir, "checkout", "--quiet", "--force", "--detach", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// checkoutDir returns where a git root is checked out
+func checkoutDir(root docsRoot) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, "dgtest", "roots", hashString(root.Git)[:16]), nil
+}
+
+// runGit runs git in dir, recording it in the audit log
+func runGit(ctx context.Context, audit *AuditLog, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	started := time.Now()
+	output, err := cmd.CombinedOutput()
+	audit.record(cmd, "", started, err)
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// gitOutput runs git in dir, recording it in the audit log, and returns
+// its trimmed output
+func gitOutput(ctx context.Context, audit *AuditLog, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	started := time.Now()
+	output, err := cmd.Output()
+	audit.record(cmd, "", started, err)
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,197 @@
+package main
+
+// Parallel sample execution
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RunSamples executes samples using the framework's execution settings and
+// returns their results in input order together with the run summary.
+// Samples that depend on others run after them on the same worker. Once
+// ctx is cancelled, samples that haven't finished are reported as skipped.
+func (e *GoExecutor) RunSamples(ctx context.Context, samples []CodeSample) ([]TestResult, RunSummary) {
+	workers, networkWorkers := e.workerCounts()
+
+	var runnable []CodeSample
+	for _, sample := range samples {
+		if e.skipReason(sample) == "" {
+			runnable = append(runnable, sample)
+		}
+	}
+
+	// Download every sample's modules up front so proxy hiccups can't fail
+	// individual samples mid-run
+	var prefetch time.Duration
+	if e.sharedModule == nil && len(runnable) > 1 && e.prefetchEnabled() {
+		started := time.Now()
+		e.PrefetchModules(ctx, runnable) // Samples resolve their own modules if this fails
+		prefetch = time.Since(started)
+	}
+
+	// Warm workspaces compile shared dependencies while they're prepared;
+	// otherwise compile them once before the first wave of samples competes
+	// to compile them in parallel
+	var warmUp time.Duration
+	if e.usesWorkspacePool(len(runnable)) {
+		pool, elapsed, err := e.startWorkspacePool(ctx, runnable, workers+networkWorkers)
+		warmUp = elapsed
+		if err == nil {
+			e.workspaces = pool
+			defer func() {
+				e.workspaces = nil
+				pool.close()
+			}()
+		}
+	} else if workers > 1 && len(runnable) > 1 && e.warmUpEnabled() {
+		warmUp = e.warmBuildCache(ctx, runnable)
+	}
+
+	// Samples run in units: alone, or in a chain with the samples they
+	// depend on. A chain with any network-bound sample uses the network pool.
+	graph := dependencyGraph(samples)
+	var cpuBound, networkBound []int
+	for u, unit := range graph.units {
+		network := false
+		for _, i := range unit {
+			if samples[i].RequiresNetwork && e.skipReason(samples[i]) == "" {
+				network = true
+			}
+		}
+		if network {
+			networkBound = append(networkBound, u)
+		} else {
+			cpuBound = append(cpuBound, u)
+		}
+	}
+
+	aggregator := NewSummaryAggregator(workers)
+	results := make([]TestResult, len(samples))
+
+	run := func(i int) {
+		if problem := graph.problems[i]; problem != "" {
+			results[i] = setupFailure(samples[i], errors.New(problem))
+			aggregator.Add(results[i])
+			return
+		}
+		if reason := e.skipReason(samples[i]); reason != "" {
+			results[i] = TestResult{
+				Sample:       samples[i],
+				Status:       StatusSkipped,
+				ErrorMessage: reason,
+			}
+			aggregator.Add(results[i])
+			return
+		}
+		if err := ctx.Err(); err != nil {
+			results[i] = cancelledResult(samples[i], err)
+			aggregator.Add(results[i])
+			return
+		}
+		if reason := graph.dependencyFailure(samples, results, i); reason != "" {
+			results[i] = TestResult{
+				Sample:       samples[i],
+				Status:       StatusSkipped,
+				ErrorMessage: reason,
+			}
+			aggregator.Add(results[i])
+			return
+		}
+
+		sample, err := graph.substituteCaptures(samples, results, i)
+		if err != nil {
+			results[i] = setupFailure(samples[i], err)
+			aggregator.Add(results[i])
+			return
+		}
+
+		aggregator.Begin()
+		results[i] = e.ExecuteSample(ctx, sample)
+		results[i].Sample = samples[i]
+		if results[i].Status == StatusPassed {
+			captured, err := captureValues(samples[i], results[i].Stdout)
+			if err != nil {
+				results[i].Success = false
+				results[i].Status = StatusFailed
+				results[i].ErrorCategory = ErrorCategoryRuntime
+				results[i].ErrorMessage = err.Error()
+			}
+			results[i].Captured = captured
+		}
+		aggregator.Finish(results[i])
+	}
+	runUnit := func(u int) {
+		for _, i := range graph.units[u] {
+			run(i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		runPool(cpuBound, workers, runUnit)
+	}()
+	go func() {
+		defer wg.Done()
+		runPool(networkBound, networkWorkers, runUnit)
+	}()
+	wg.Wait()
+
+	summary := aggregator.Summary()
+	summary.MaxConcurrentNetwork = networkWorkers
+	summary.PrefetchTime = prefetch.Seconds()
+	summary.WarmUpTime = warmUp.Seconds()
+	return results, summary
+}
+
+// usesWorkspacePool reports whether a run of this many runnable samples
+// executes in warm workspaces. Single samples and kept workspaces run cold.
+func (e *GoExecutor) usesWorkspacePool(runnable int) bool {
+	return e.executionStrategy() == StrategyWarm && runnable > 1 && !e.KeepWorkspace
+}
+
+// workerCounts returns the size of the compile-bound and network-bound
+// worker pools. Network-bound samples get their own pool so a large run can
+// compile many samples at once without putting as many requests on the API.
+func (e *GoExecutor) workerCounts() (workers, networkWorkers int) {
+	execution := configSection(e.FrameworkConfig, "execution")
+	workers = 1
+	if configBool(execution, "parallel_tests", false) {
+		workers = configInt(execution, "max_concurrent", 1)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	networkWorkers = configInt(execution, "max_concurrent_network", workers)
+	if networkWorkers < 1 {
+		networkWorkers = 1
+	}
+	return workers, networkWorkers
+}
+
+// runPool calls run for each index using up to workers goroutines
+func runPool(indexes []int, workers int, run func(int)) {
+	queue := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(indexes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				run(i)
+			}
+		}()
+	}
+
+	for _, i := range indexes {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,321 @@
+package main
+
+// S3-compatible storage
+// History and artifacts are kept as objects in a bucket, reached over the
+// S3 REST API with Signature Version 4 request signing. Google Cloud
+// Storage speaks the same API at https://storage.googleapis.com with HMAC
+// keys and region "auto". Each history record is its own object, so runs
+// from many repos and CI jobs never contend for one file.
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Storage keeps history and artifacts in an S3-compatible bucket
+type s3Storage struct {
+	endpoint     *url.URL
+	bucket       string
+	prefix       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	historyLimit int
+	client       *http.Client
+}
+
+// newS3Storage configures a bucket backend from storage, with credentials
+// from the environment variables it names (AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY by default)
+func newS3Storage(config map[string]interface{}, prefix string) (*s3Storage, error) {
+	bucket := configString(config, "bucket", "")
+	if bucket == "" {
+		return nil, fmt.Errorf("storage.bucket is required for the %s backend", StorageS3)
+	}
+	region := configString(config, "region", "us-east-1")
+	endpoint, err := url.Parse(configString(config, "endpoint", "https://s3."+region+".amazonaws.com"))
+	if err != nil {
+		return nil, fmt.Errorf("storage.endpoint: %v", err)
+	}
+
+	accessKeyEnv := configString(config, "access_key_env", "AWS_ACCESS_KEY_ID")
+	secretKeyEnv := configString(config, "secret_key_env", "AWS_SECRET_ACCESS_KEY")
+	storage := &s3Storage{
+		endpoint:     endpoint,
+		bucket:       bucket,
+		prefix:       prefix,
+		region:       region,
+		accessKey:    os.Getenv(accessKeyEnv),
+		secretKey:    os.Getenv(secretKeyEnv),
+		sessionToken: os.Getenv(configString(config, "session_token_env", "AWS_SESSION_TOKEN")),
+		historyLimit: configInt(config, "history_limit", 200),
+		client:       &http.Client{Timeout: 60 * time.Second},
+	}
+	if storage.accessKey == "" || storage.secretKey == "" {
+		return nil, fmt.Errorf("the %s storage backend needs credentials in %s and %s", StorageS3, accessKeyEnv, secretKeyEnv)
+	}
+	return storage, nil
+}
+
+// LoadHistory reads the most recent storage.history_limit records
+func (s *s3Storage) LoadHistory(ctx context.Context) ([]HistoryRecord, error) {
+	keys, err := s.list(ctx, s.key("history/"))
+	if err != nil {
+		return nil, err
+	}
+	// Keys start with the run's time, so the newest sort last
+	sort.Strings(keys)
+	if s.historyLimit > 0 && len(keys) > s.historyLimit {
+		keys = keys[len(keys)-s.historyLimit:]
+	}
+
+	var records []HistoryRecord
+	for _, key := range keys {
+		content, err := s.get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal(content, &record); err != nil {
+			return nil, fmt.Errorf("parsing %s: %v", key, err)
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// AppendHistory stores a record as its own object
+func (s *s3Storage) AppendHistory(ctx context.Context, record HistoryRecord) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	// Jobs on one host, or on hosts sharing a name, can finish in the same
+	// second, so a random suffix keeps their records apart
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	name := record.Time.UTC().Format("20060102T150405Z") + "-" + nonIdentifierRegex.ReplaceAllString(host, "-") +
+		"-" + hex.EncodeToString(suffix) + ".json"
+	return s.put(ctx, s.key(path.Join("history", name)), content)
+}
+
+// PutArtifact stores an object under the prefix
+func (s *s3Storage) PutArtifact(ctx context.Context, key string, content []byte) error {
+	return s.put(ctx, s.key(key), content)
+}
+
+// Location is the bucket URL
+func (s *s3Storage) Location() string {
+	return "s3://" + path.Join(s.bucket, s.prefix)
+}
+
+// key places a key under the prefix
+func (s *s3Storage) key(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+func (s *s3Storage) put(ctx context.Context, key string, content []byte) error {
+	_, err := s.do(ctx, http.MethodPut, key, nil, content)
+	return err
+}
+
+func (s *s3Storage) get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, key, nil, nil)
+}
+
+// list returns every key under a prefix, following continuation tokens
+func (s *s3Storage) list(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		body, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("listing %s: %v", s.Location(), err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// do sends a signed path-style request for an object, or for the bucket
+// when key is empty, and returns the response body
+func (s *s3Storage) do(ctx context.Context, method, key string, query url.Values, body []byte) ([]byte, error) {
+	target := *s.endpoint
+	target.Path = "/" + s.bucket
+	if key != "" {
+		target.Path += "/" + key
+	}
+	target.RawPath = s3EscapePath(target.Path)
+	target.RawQuery = s3CanonicalQuery(query)
+
+	request, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(request, body, time.Now())
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode/100 != 2 {
+		var failure struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		xml.Unmarshal(content, &failure)
+		return nil, fmt.Errorf("%s %s: %s %s %s", method, target.Path, response.Status, failure.Code, failure.Message)
+	}
+	return content, nil
+}
+
+// sign adds Signature Version 4 headers to a request
+func (s *s3Storage) sign(request *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name := range request.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(request.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// s3Escape percent-encodes everything but the unreserved characters, as
+// Signature Version 4 requires
+func s3Escape(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || b == '-' || b == '_' || b == '.' || b == '~' {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// s3EscapePath escapes each segment of a path, keeping the slashes
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery encodes a query with sorted keys and strict escaping
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
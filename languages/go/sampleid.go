@@ -0,0 +1,40 @@
+package main
+
+// Stable sample identifiers
+// Page and line don't identify a sample across runs: editing a page above
+// a sample shifts its line. A sample's ID is instead its page slug plus a
+// hash of its code with whitespace normalized, so it survives edits
+// elsewhere on the page and reformatting, and only changes when the sample
+// itself does. Identical samples on one page are numbered in page order.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var slugRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sampleID identifies a sample by its page and normalized code
+func sampleID(page, code string) string {
+	stem := strings.TrimSuffix(strings.TrimPrefix(page, defaultPagesPath+"/"), ".mdx")
+	slug := strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(stem), "-"), "-")
+
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(code), " ")))
+	return slug + "-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// assignSampleIDs sets the IDs of one page's samples
+func assignSampleIDs(samples []CodeSample) {
+	seen := make(map[string]int)
+	for i := range samples {
+		id := sampleID(samples[i].Page, samples[i].Code)
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+		samples[i].ID = id
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSampleID(t *testing.T) {
+	code := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	id := sampleID("fern/pages/docs/Speech To Text/live.mdx", code)
+	if !regexp.MustCompile(`^docs-speech-to-text-live-[0-9a-f]{8}$`).MatchString(id) {
+		t.Errorf("sampleID = %q, want a page slug and an 8-digit hash", id)
+	}
+
+	tests := []struct {
+		name       string
+		page, code string
+		same       bool
+	}{
+		{"reformatted", "fern/pages/docs/Speech To Text/live.mdx", "package main\nfunc main() { println(\"hi\") }", true},
+		{"reindented", "fern/pages/docs/Speech To Text/live.mdx", "  package main\n\n\n  func main() {\n    println(\"hi\")\n  }", true},
+		{"code changed", "fern/pages/docs/Speech To Text/live.mdx", "package main\n\nfunc main() {\n\tprintln(\"bye\")\n}\n", false},
+		{"other page", "fern/pages/docs/tts.mdx", code, false},
+	}
+	for _, test := range tests {
+		if got := sampleID(test.page, test.code); (got == id) != test.same {
+			t.Errorf("%s: sampleID = %q, first ID %q, want same = %t", test.name, got, id, test.same)
+		}
+	}
+}
+
+func TestAssignSampleIDs(t *testing.T) {
+	samples := []CodeSample{
+		{Page: "fern/pages/stt.mdx", Code: "a := 1"},
+		{Page: "fern/pages/stt.mdx", Code: "b := 2"},
+		{Page: "fern/pages/stt.mdx", Code: "a  :=  1"},
+		{Page: "fern/pages/stt.mdx", Code: "a := 1"},
+	}
+	assignSampleIDs(samples)
+
+	first := sampleID("fern/pages/stt.mdx", "a := 1")
+	want := []string{first, sampleID("fern/pages/stt.mdx", "b := 2"), first + "-2", first + "-3"}
+	for i, sample := range samples {
+		if sample.ID != want[i] {
+			t.Errorf("sample %d: ID = %q, want %q", i, sample.ID, want[i])
+		}
+	}
+}
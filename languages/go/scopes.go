@@ -0,0 +1,158 @@
+package main
+
+// API key scope analysis
+// Each sample's product, SDK method calls, and endpoint URLs are mapped to
+// the minimal API key scopes it needs. Pages are flagged when they show a
+// sample without telling the reader which scopes their key must have.
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultProductScopes are the scopes needed to call each product's API
+var defaultProductScopes = map[string][]string{
+	ProductSTTPrerecorded:   {"usage:write"},
+	ProductSTTLive:          {"usage:write"},
+	ProductTTS:              {"usage:write"},
+	ProductVoiceAgent:       {"usage:write"},
+	ProductTextIntelligence: {"usage:write"},
+}
+
+// defaultMethodScopes are the scopes needed by individual Management API
+// methods, which vary by resource and by read versus write access
+var defaultMethodScopes = map[string][]string{
+	"ListProjects":       {"project:read"},
+	"GetProjects":        {"project:read"},
+	"GetProject":         {"project:read"},
+	"UpdateProject":      {"project:write"},
+	"DeleteProject":      {"project:write"},
+	"ListKeys":           {"keys:read"},
+	"GetKeys":            {"keys:read"},
+	"GetKey":             {"keys:read"},
+	"CreateKey":          {"keys:write"},
+	"DeleteKey":          {"keys:write"},
+	"GetMembers":         {"members:read"},
+	"GetMemberScopes":    {"members:read"},
+	"RemoveMember":       {"members:write"},
+	"UpdateMemberScopes": {"members:write"},
+	"GetInvitations":     {"members:read"},
+	"SendInvitation":     {"members:write"},
+	"DeleteInvitation":   {"members:write"},
+	"LeaveProject":       {"members:write"},
+	"GetUsage":           {"usage:read"},
+	"GetRequests":        {"usage:read"},
+	"GetRequest":         {"usage:read"},
+	"GetFields":          {"usage:read"},
+	"GetBalances":        {"billing:read"},
+	"GetBalance":         {"billing:read"},
+}
+
+// defaultPathScopes are the scopes needed by raw HTTP calls, keyed by the
+// endpoint path prefix that appears in the sample's URL literals
+var defaultPathScopes = map[string][]string{
+	"/v1/listen":   {"usage:write"},
+	"/v1/speak":    {"usage:write"},
+	"/v1/read":     {"usage:write"},
+	"/v1/agent":    {"usage:write"},
+	"/v1/projects": {"project:read"},
+}
+
+// PageScopes reports the scopes a docs page's samples need and which of
+// them the page never mentions
+type PageScopes struct {
+	FilePath string   `json:"file_path"`
+	Required []string `json:"required"`
+	Missing  []string `json:"missing"`
+}
+
+// ScopeReport lists the scopes needed across a set of samples
+type ScopeReport struct {
+	Required []string     `json:"required"`
+	Pages    []PageScopes `json:"pages"`
+}
+
+// requiredScopes maps a sample's product, method calls, and URLs to scopes,
+// using api_scopes from the language config in place of the defaults
+func (e *GoExecutor) requiredScopes(product string, methodCalls, urls []string) []string {
+	config := configSection(e.LanguageConfig, "api_scopes")
+	productScopes := scopeTable(configSection(config, "products"), defaultProductScopes)
+	methodScopes := scopeTable(configSection(config, "methods"), defaultMethodScopes)
+	pathScopes := scopeTable(configSection(config, "paths"), defaultPathScopes)
+
+	scopes := make(map[string]bool)
+	for _, scope := range productScopes[product] {
+		scopes[scope] = true
+	}
+	for _, method := range methodCalls {
+		for _, scope := range methodScopes[method] {
+			scopes[scope] = true
+		}
+	}
+	for _, url := range urls {
+		for prefix, required := range pathScopes {
+			if strings.Contains(url, prefix) {
+				for _, scope := range required {
+					scopes[scope] = true
+				}
+			}
+		}
+	}
+
+	return sortedKeys(scopes)
+}
+
+// scopeTable overlays configured scope lists onto the defaults
+func scopeTable(configured map[string]interface{}, defaults map[string][]string) map[string][]string {
+	table := make(map[string][]string, len(defaults)+len(configured))
+	for key, scopes := range defaults {
+		table[key] = scopes
+	}
+	for key := range configured {
+		table[key] = configStrings(configured, key)
+	}
+	return table
+}
+
+// buildScopeReport collects the scopes required by samples and checks each
+// page for a mention of every scope its samples need
+func buildScopeReport(samples []CodeSample) (ScopeReport, error) {
+	report := ScopeReport{}
+	all := make(map[string]bool)
+	byPage := make(map[string]map[string]bool)
+	var pages []string
+
+	for _, sample := range samples {
+		if len(sample.RequiredScopes) == 0 {
+			continue
+		}
+		if byPage[sample.FilePath] == nil {
+			byPage[sample.FilePath] = make(map[string]bool)
+			pages = append(pages, sample.FilePath)
+		}
+		for _, scope := range sample.RequiredScopes {
+			all[scope] = true
+			byPage[sample.FilePath][scope] = true
+		}
+	}
+	sort.Strings(pages)
+
+	for _, page := range pages {
+		content, err := os.ReadFile(page)
+		if err != nil {
+			return report, err
+		}
+
+		pageScopes := PageScopes{FilePath: page, Required: sortedKeys(byPage[page])}
+		for _, scope := range pageScopes.Required {
+			if !strings.Contains(string(content), scope) {
+				pageScopes.Missing = append(pageScopes.Missing, scope)
+			}
+		}
+		report.Pages = append(report.Pages, pageScopes)
+	}
+
+	report.Required = sortedKeys(all)
+	return report, nil
+}
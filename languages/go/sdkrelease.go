@@ -0,0 +1,348 @@
+package main
+
+// SDK release revalidation
+// A new SDK release can break samples hours before the nightly run gets to
+// them. `run --triggered-by sdk-release v3.2.0` builds samples against that
+// release and runs only those it can affect: samples of the products whose
+// SDK packages changed since the previous release, samples importing a
+// changed package, and samples checked by validation rules tied to the
+// changed packages. Changes are read from the local SDK checkout's tags,
+// fetched first since a release that just shipped isn't there yet; when they can't be narrowed down, e.g. for a new major version or a
+// change to packages every product shares, every SDK sample is revalidated.
+// `webhook` listens for GitHub release events and starts such a run for
+// each published release.
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TriggerSDKRelease is the --triggered-by event of an SDK release
+const TriggerSDKRelease = "sdk-release"
+
+// ReleaseImpact is what an SDK release can affect
+type ReleaseImpact struct {
+	Release  string `json:"release"`
+	Previous string `json:"previous,omitempty"`
+	// ChangedPackages are the SDK import paths with changed Go files
+	ChangedPackages []string `json:"changed_packages,omitempty"`
+	Products        []string `json:"products,omitempty"`
+	Rules           []string `json:"rules,omitempty"`
+	// Everything is set when the release can't be narrowed to a subset
+	Everything bool   `json:"everything"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// everything marks an impact as unnarrowed, for a reason
+func (impact ReleaseImpact) everything(reason string) ReleaseImpact {
+	impact.Everything, impact.Reason = true, reason
+	return impact
+}
+
+// sdkReleaseModule is the SDK module path of a release, whose major
+// version suffix follows the release's
+func (e *GoExecutor) sdkReleaseModule(release string) string {
+	modulePath, _, ok := e.localSDKModule()
+	if !ok {
+		modulePath = configString(configSection(e.LanguageConfig, "sdk"), "module_name", sdkModulePrefix)
+	}
+	if isMajorVersionSuffix(path.Base(modulePath)) {
+		modulePath = path.Dir(modulePath)
+	}
+	if version, ok := parseVersion(release); ok && version.numbers[0] >= 2 {
+		modulePath += fmt.Sprintf("/v%d", version.numbers[0])
+	}
+	return modulePath
+}
+
+// previousRelease returns the highest release tag in the SDK checkout
+// before release
+func previousRelease(ctx context.Context, audit *AuditLog, dir, release string) (string, error) {
+	tags, err := gitOutput(ctx, audit, dir, "tag", "--list", "v*")
+	if err != nil {
+		return "", err
+	}
+	previous := ""
+	for _, tag := range strings.Fields(tags) {
+		if order, ok := compareVersions(tag, release); !ok || order >= 0 {
+			continue
+		}
+		if previous == "" {
+			previous = tag
+		} else if order, _ := compareVersions(tag, previous); order > 0 {
+			previous = tag
+		}
+	}
+	return previous, nil
+}
+
+// sdkReleaseImpact works out what changed in the SDK between the previous
+// release and this one, from the local checkout's tags after fetching them
+func (e *GoExecutor) sdkReleaseImpact(ctx context.Context, release string) ReleaseImpact {
+	impact := ReleaseImpact{Release: release}
+	_, dir, ok := e.localSDKModule()
+	if !ok {
+		return impact.everything("no local SDK checkout to compare releases in")
+	}
+
+	// Checkouts without a remote compare the tags they have
+	fetchCtx, cancel := context.WithTimeout(ctx, gitCheckoutTimeout)
+	fetchErr := runGit(fetchCtx, e.Audit, dir, "fetch", "--quiet", "--tags")
+	cancel()
+	if _, err := gitOutput(ctx, e.Audit, dir, "rev-parse", "--verify", "--quiet", release+"^{commit}"); err != nil {
+		reason := fmt.Sprintf("the SDK checkout has no tag %s", release)
+		if fetchErr != nil {
+			reason += fmt.Sprintf(" (%v)", fetchErr)
+		}
+		return impact.everything(reason)
+	}
+	previous, err := previousRelease(ctx, e.Audit, dir, release)
+	if err != nil || previous == "" {
+		return impact.everything("no earlier release tag in the SDK checkout")
+	}
+	impact.Previous = previous
+	if mustParseVersion(previous).numbers[0] != mustParseVersion(release).numbers[0] {
+		return impact.everything("a new major version")
+	}
+
+	files, err := gitOutput(ctx, e.Audit, dir, "diff", "--name-only", previous, release, "--", "*.go")
+	if err != nil {
+		return impact.everything(fmt.Sprintf("comparing %s with %s: %v", previous, release, err))
+	}
+	modulePath := e.sdkReleaseModule(release)
+
+	packages := make(map[string]bool)
+	products := make(map[string]bool)
+	rules := make(map[string]bool)
+	var shared []string
+	for _, file := range strings.Fields(files) {
+		// Tests and examples don't ship in what samples build against
+		if strings.HasSuffix(file, "_test.go") || strings.HasPrefix(file, "examples/") || strings.HasPrefix(file, "tests/") {
+			continue
+		}
+		dir := path.Dir(file)
+		packages[path.Join(modulePath, dir)] = true
+
+		if product := sdkAreaProduct(dir); product != "" {
+			products[product] = true
+		} else {
+			shared = append(shared, dir)
+		}
+		// Constructors live in the client packages, and live handlers in
+		// the websocket ones
+		if strings.HasPrefix(dir, "pkg/client/") {
+			rules[RuleClientConstruction] = true
+		}
+		if strings.Contains("/"+dir+"/", "/websocket/") {
+			rules[RuleStreamingWiring] = true
+		}
+	}
+	impact.ChangedPackages = sortedKeys(packages)
+	impact.Products = sortedKeys(products)
+	impact.Rules = sortedKeys(rules)
+	if len(shared) > 0 {
+		sort.Strings(shared)
+		return impact.everything(fmt.Sprintf("%s is shared by every product", shared[0]))
+	}
+	return impact
+}
+
+// mustParseVersion parses a version already known to be valid
+func mustParseVersion(version string) semanticVersion {
+	parsed, _ := parseVersion(version)
+	return parsed
+}
+
+// sdkAreaProduct returns the product of an SDK package directory from its
+// area after pkg/client or pkg/api, or "" for packages products share
+func sdkAreaProduct(dir string) string {
+	elements := strings.Split(dir, "/")
+	if len(elements) < 3 || elements[0] != "pkg" || (elements[1] != "client" && elements[1] != "api") {
+		return ""
+	}
+	for _, area := range sdkPackageProducts {
+		if elements[2] == area.area {
+			return area.product
+		}
+	}
+	return ""
+}
+
+// affectedByRelease keeps the samples an SDK release can affect
+func affectedByRelease(samples []CodeSample, impact ReleaseImpact) []CodeSample {
+	var affected []CodeSample
+	for _, sample := range samples {
+		usesSDK := false
+		importsChanged := false
+		for _, importPath := range sample.Imports {
+			if strings.HasPrefix(importPath, sdkModulePrefix) {
+				usesSDK = true
+			}
+			if containsString(impact.ChangedPackages, importPath) {
+				importsChanged = true
+			}
+		}
+		if !usesSDK {
+			continue
+		}
+		streaming := containsString(impact.Rules, RuleStreamingWiring) && sample.Feature == FeatureWebSocket
+		if impact.Everything || importsChanged || streaming || containsString(impact.Products, sampleProduct(sample)) {
+			affected = append(affected, sample)
+		}
+	}
+	return affected
+}
+
+// writeReleaseImpact prints what a release revalidates
+func writeReleaseImpact(w io.Writer, impact ReleaseImpact, samples int) {
+	if impact.Everything {
+		fmt.Fprintf(w, "🔁 SDK %s: revalidating all %d SDK samples (%s)\n", impact.Release, samples, impact.Reason)
+		return
+	}
+	fmt.Fprintf(w, "🔁 SDK %s (since %s): revalidating %d samples\n", impact.Release, impact.Previous, samples)
+	if len(impact.Products) > 0 {
+		fmt.Fprintf(w, "   Products: %s\n", strings.Join(impact.Products, ", "))
+	}
+	if len(impact.Rules) > 0 {
+		fmt.Fprintf(w, "   Rules:    %s\n", strings.Join(impact.Rules, ", "))
+	}
+	fmt.Fprintf(w, "   Changed:  %d packages\n", len(impact.ChangedPackages))
+}
+
+// releaseEvent is the part of a GitHub release webhook the listener reads
+type releaseEvent struct {
+	Action  string `json:"action"`
+	Release struct {
+		TagName    string `json:"tag_name"`
+		Prerelease bool   `json:"prerelease"`
+	} `json:"release"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// validWebhookSignature checks GitHub's X-Hub-Signature-256 of a payload
+func validWebhookSignature(secret string, payload []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// webhookCommand listens for SDK release webhooks and revalidates each
+// published release with `run --triggered-by sdk-release <tag>` plus the
+// run flags given after --
+func webhookCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("webhook", flag.ContinueOnError)
+	listen := flags.String("listen", "127.0.0.1:8090", "Address to receive webhooks on")
+	repository := flags.String("repository", "deepgram/deepgram-go-sdk", "Only revalidate releases of this GitHub repository")
+	secretEnv := flags.String("secret-env", "DGTEST_WEBHOOK_SECRET", "Environment variable holding the webhook secret")
+	prereleases := flags.Bool("prereleases", false, "Also revalidate prereleases")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	secret := os.Getenv(*secretEnv)
+	if secret == "" {
+		return fmt.Errorf("the webhook needs its secret in %s", *secretEnv)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	runArgs := flags.Args()
+
+	// Releases are revalidated one at a time, in the order they arrive
+	releases := make(chan string, 16)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for release := range releases {
+			fmt.Fprintf(os.Stderr, "🔁 Revalidating SDK %s\n", release)
+			// Flags stop at the release, so it goes last
+			command := append(append([]string{"run"}, runArgs...), "--triggered-by", TriggerSDKRelease, release)
+			run := exec.CommandContext(ctx, self, command...)
+			run.Stdout, run.Stderr = os.Stdout, os.Stderr
+			if err := run.Run(); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ Revalidating SDK %s: %v\n", release, err)
+			} else {
+				fmt.Fprintf(os.Stderr, "✅ SDK %s revalidated\n", release)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "webhooks are POSTed", http.StatusMethodNotAllowed)
+			return
+		}
+		payload, err := io.ReadAll(io.LimitReader(r.Body, 25<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !validWebhookSignature(secret, payload, r.Header.Get("X-Hub-Signature-256")) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-GitHub-Event") != "release" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var event releaseEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tag := event.Release.TagName
+		switch {
+		case event.Action != "published" || !strings.EqualFold(event.Repository.FullName, *repository):
+			w.WriteHeader(http.StatusNoContent)
+		case event.Release.Prerelease && !*prereleases:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if _, ok := parseVersion(tag); !ok {
+				http.Error(w, fmt.Sprintf("release tag %q isn't a version", tag), http.StatusUnprocessableEntity)
+				return
+			}
+			select {
+			case releases <- tag:
+				w.WriteHeader(http.StatusAccepted)
+			default:
+				http.Error(w, "too many releases queued", http.StatusServiceUnavailable)
+			}
+		}
+	})
+
+	server := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(os.Stderr, "🪝 Listening for %s releases on http://%s; Ctrl-C to stop\n", *repository, *listen)
+	err = server.ListenAndServe()
+	close(releases)
+	wg.Wait()
+	if err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestValidWebhookSignature(t *testing.T) {
+	// The example in GitHub's webhook documentation
+	secret := "It's a Secret to Everybody"
+	payload := []byte("Hello, World!")
+	valid := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	tests := []struct {
+		name, secret, payload, signature string
+		want                             bool
+	}{
+		{"valid", secret, string(payload), valid, true},
+		{"wrong secret", "another secret", string(payload), valid, false},
+		{"changed payload", secret, "Hello, World?", valid, false},
+		{"missing prefix", secret, string(payload), valid[len("sha256="):], false},
+		{"uppercase digest", secret, string(payload), "sha256=757107EA0EB2509FC211221CCE984B8A37570B6D7586C22C46F4379C8B043E17", false},
+		{"sha1 signature", secret, string(payload), "sha1=01dc10d0c83e72ed246219cdd91669667fe2ca59", false},
+		{"empty", secret, string(payload), "", false},
+	}
+	for _, test := range tests {
+		if got := validWebhookSignature(test.secret, []byte(test.payload), test.signature); got != test.want {
+			t.Errorf("%s: validWebhookSignature = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+// Storage backends for history and artifacts
+// A single repo's history fits in a JSON-lines file next to the docs, but
+// orgs running docs tests in many repos want every run's results in one
+// place. Run history and failure artifacts go through a Storage, configured
+// under storage: local disk by default, or an S3-compatible bucket (AWS S3,
+// or Google Cloud Storage through its XML API with HMAC keys). Runs from
+// different repos sharing a bucket are kept apart by storage.prefix.
+//
+// There is no SQLite backend. Go's standard library has no SQLite driver
+// and the executor has no dependencies, so local disk is the default
+// instead: history appends to a JSON-lines file and artifacts are plain
+// files, which is all a single machine's runs need.
+//
+// Each run stored with --store writes, under runs/<time>-<host>/, its
+// report and manifest, and for every failed or timed out sample the code
+// that ran and what it printed.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Storage keeps run history and artifacts
+type Storage interface {
+	// LoadHistory returns the stored history records, oldest first
+	LoadHistory(ctx context.Context) ([]HistoryRecord, error)
+	// AppendHistory stores a run's history record
+	AppendHistory(ctx context.Context, record HistoryRecord) error
+	// PutArtifact stores a file under a slash-separated key
+	PutArtifact(ctx context.Context, key string, content []byte) error
+	// Location describes where the storage keeps things, for messages
+	Location() string
+}
+
+// Storage backends
+const (
+	StorageDisk = "disk"
+	StorageS3   = "s3"
+)
+
+// defaultStoragePath is where the disk backend keeps things by default
+const defaultStoragePath = ".dgtest"
+
+// diskStorage keeps history in a JSON-lines file and artifacts in a
+// directory tree
+type diskStorage struct {
+	historyFile string
+	// root holds artifacts; empty when only history is kept
+	root string
+}
+
+// LoadHistory reads the history file
+func (d diskStorage) LoadHistory(ctx context.Context) ([]HistoryRecord, error) {
+	return loadHistory(d.historyFile)
+}
+
+// AppendHistory appends to the history file
+func (d diskStorage) AppendHistory(ctx context.Context, record HistoryRecord) error {
+	if dir := filepath.Dir(d.historyFile); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return appendHistory(d.historyFile, record)
+}
+
+// PutArtifact writes an artifact below the root directory
+func (d diskStorage) PutArtifact(ctx context.Context, key string, content []byte) error {
+	if d.root == "" {
+		return fmt.Errorf("no artifact directory configured")
+	}
+	file := filepath.Join(d.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, 0644)
+}
+
+// Location is the root directory, or the history file without one
+func (d diskStorage) Location() string {
+	if d.root == "" {
+		return d.historyFile
+	}
+	return d.root
+}
+
+// storage builds the backend configured under storage
+func (e *GoExecutor) storage() (Storage, error) {
+	config := configSection(e.FrameworkConfig, "storage")
+	prefix := strings.Trim(configString(config, "prefix", ""), "/")
+
+	switch backend := configString(config, "backend", StorageDisk); backend {
+	case StorageDisk:
+		root := filepath.Join(configString(config, "path", defaultStoragePath), filepath.FromSlash(prefix))
+		return diskStorage{historyFile: filepath.Join(root, "history.jsonl"), root: root}, nil
+	case StorageS3:
+		return newS3Storage(config, prefix)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (expected %s or %s)", backend, StorageDisk, StorageS3)
+	}
+}
+
+// storedRunKey names a run's directory of artifacts, sorting by time
+func storedRunKey(manifest *RunManifest) string {
+	started, host := time.Now(), ""
+	if manifest != nil {
+		started, host = manifest.Time, manifest.Host.Hostname
+	}
+	if host = nonIdentifierRegex.ReplaceAllString(host, "-"); host == "" {
+		host = "unknown"
+	}
+	return path.Join("runs", started.UTC().Format("20060102T150405Z")+"-"+host)
+}
+
+// storeRun writes a run's report, manifest, and failure artifacts. The
+// report should already be redacted.
+func storeRun(ctx context.Context, storage Storage, report Report) (string, error) {
+	run := storedRunKey(report.Manifest)
+
+	put := func(key string, value interface{}) error {
+		content, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		return storage.PutArtifact(ctx, path.Join(run, key), append(content, '\n'))
+	}
+	if err := put("report.json", report); err != nil {
+		return "", err
+	}
+	if err := put("manifest.json", report.Manifest); err != nil {
+		return "", err
+	}
+
+	for _, result := range report.Results {
+		if result.Status != StatusFailed && result.Status != StatusTimeout {
+			continue
+		}
+		dir := path.Join(run, "failures", failureArtifactName(result.Sample))
+		if err := storage.PutArtifact(ctx, path.Join(dir, "main.go"), []byte(result.Sample.Code+"\n")); err != nil {
+			return "", err
+		}
+		if err := storage.PutArtifact(ctx, path.Join(dir, "output.txt"), []byte(failureOutput(result))); err != nil {
+			return "", err
+		}
+	}
+	return run, nil
+}
+
+// failureArtifactName names a failed sample's artifact directory after its
+// page and line, which readers can find, and its ID, which is stable
+func failureArtifactName(sample CodeSample) string {
+	name := sampleTestName(sample.Page, sample.LineNumber)
+	if sample.ID != "" {
+		name += "-" + sample.ID
+	}
+	return name
+}
+
+// failureOutput is a failed sample's status, error, and output as text
+func failureOutput(result TestResult) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s:%d\n", result.Sample.Page, result.Sample.LineNumber)
+	fmt.Fprintf(&out, "status: %s\n", result.Status)
+	if result.ErrorCategory != "" {
+		fmt.Fprintf(&out, "category: %s\n", result.ErrorCategory)
+	}
+	if result.ErrorMessage != "" {
+		fmt.Fprintf(&out, "error: %s\n", result.ErrorMessage)
+	}
+	fmt.Fprintf(&out, "\n--- stdout ---\n%s\n", strings.TrimRight(result.Stdout, "\n"))
+	fmt.Fprintf(&out, "\n--- stderr ---\n%s\n", strings.TrimRight(result.Stderr, "\n"))
+	return out.String()
+}
@@ -0,0 +1,154 @@
+package main
+
+// Streaming handler wiring by SDK version
+// The live SDK moved from callback-style handlers (a type with Message,
+// Open, ... methods passed to NewWebSocket) to a choice of callback or
+// channel handlers through separate constructors. A sample wiring handlers
+// the old way, or passing a channel handler to a callback constructor,
+// compiles against one SDK version and not another, so the wiring is
+// checked against the version the docs target.
+
+import (
+	"go/ast"
+	"path"
+	"strings"
+)
+
+// RuleStreamingWiring is the validation rule for live handler wiring
+const RuleStreamingWiring = "streaming_handler_wiring"
+
+// streamingPatterns lists the live client constructors of one SDK major
+// version by the handler style they take
+type streamingPatterns struct {
+	Callback []string
+	Channel  []string
+}
+
+// defaultStreamingPatterns are the constructors of each SDK major version,
+// overridable by streaming_patterns in the language config
+var defaultStreamingPatterns = map[string]streamingPatterns{
+	"v1": {Callback: []string{"NewWebSocket", "NewWebSocketWithDefaults", "NewWebSocketForDemo"}},
+	"v2": {Callback: []string{"NewWebSocket", "NewWebSocketWithDefaults", "NewWebSocketForDemo"}},
+	"v3": {
+		Callback: []string{"NewWSUsingCallback", "NewWSUsingCallbackWithDefaults", "NewWSUsingCallbackForDemo"},
+		Channel:  []string{"NewWSUsingChan", "NewWSUsingChanWithDefaults", "NewWSUsingChanForDemo"},
+	},
+}
+
+// callbackHandlerMethods are the methods of a callback-style live handler
+var callbackHandlerMethods = map[string]bool{
+	"Open":           true,
+	"Message":        true,
+	"Metadata":       true,
+	"SpeechStarted":  true,
+	"UtteranceEnd":   true,
+	"Close":          true,
+	"Error":          true,
+	"UnhandledEvent": true,
+}
+
+// targetSDKVersion returns the SDK major version samples are validated
+// against: the local checkout's, else sdk.current_version from the config
+func (e *GoExecutor) targetSDKVersion() string {
+	if modulePath, _, ok := e.localSDKModule(); ok {
+		if element := path.Base(modulePath); isMajorVersionSuffix(element) {
+			return element
+		}
+		return "v1"
+	}
+	return configString(configSection(e.LanguageConfig, "sdk"), "current_version", "")
+}
+
+// streamingPatternsFor returns the live constructors of an SDK major version
+func (e *GoExecutor) streamingPatternsFor(version string) (streamingPatterns, bool) {
+	configured := configSection(configSection(e.LanguageConfig, "streaming_patterns"), version)
+	if len(configured) > 0 {
+		return streamingPatterns{
+			Callback: configStrings(configured, "callback"),
+			Channel:  configStrings(configured, "channel"),
+		}, true
+	}
+	patterns, ok := defaultStreamingPatterns[version]
+	return patterns, ok
+}
+
+// streamingWiringProblem explains how a sample's live handler wiring doesn't
+// fit the targeted SDK version. checked is false for samples without a live
+// client or when the targeted version's constructors aren't known.
+func (e *GoExecutor) streamingWiringProblem(sample CodeSample) (problem string, checked bool) {
+	analysis := analyzeSample(sample.Code)
+	if !analysis.Parsed {
+		return "", false
+	}
+
+	var constructors []string
+	for _, constructor := range analysis.ClientConstructors {
+		name := constructor[strings.LastIndex(constructor, ".")+1:]
+		if strings.Contains(name, "WS") || strings.Contains(name, "WebSocket") {
+			constructors = append(constructors, name)
+		}
+	}
+	if len(constructors) == 0 {
+		return "", false
+	}
+
+	version := e.targetSDKVersion()
+	patterns, ok := e.streamingPatternsFor(version)
+	if !ok {
+		return "", false
+	}
+
+	callbackMethods, channelMethods := handlerStyles(analysis.File)
+	for _, name := range constructors {
+		switch {
+		case containsString(patterns.Callback, name):
+			if channelMethods && !callbackMethods {
+				return name + " takes a callback handler, but the sample defines a channel handler", true
+			}
+		case containsString(patterns.Channel, name):
+			if callbackMethods && !channelMethods {
+				return name + " takes a channel handler, but the sample defines a callback handler", true
+			}
+		default:
+			supported := append(append([]string{}, patterns.Callback...), patterns.Channel...)
+			return name + " isn't a live constructor in SDK " + version + "; use one of " + strings.Join(supported, ", "), true
+		}
+	}
+	return "", true
+}
+
+// handlerStyles reports whether a sample declares callback-style handler
+// methods and methods returning channels, as channel handlers do
+func handlerStyles(file *ast.File) (callback, channel bool) {
+	for _, decl := range file.Decls {
+		method, ok := decl.(*ast.FuncDecl)
+		if !ok || method.Recv == nil {
+			continue
+		}
+		if callbackHandlerMethods[method.Name.Name] {
+			callback = true
+		}
+		if method.Type.Results == nil {
+			continue
+		}
+		for _, result := range method.Type.Results.List {
+			if returnsChannel(result.Type) {
+				channel = true
+			}
+		}
+	}
+	return callback, channel
+}
+
+// returnsChannel reports whether a type is a channel, or a slice or pointer of them
+func returnsChannel(expr ast.Expr) bool {
+	switch t := expr.(type) {
+	case *ast.ChanType:
+		return true
+	case *ast.ArrayType:
+		return returnsChannel(t.Elt)
+	case *ast.StarExpr:
+		return returnsChannel(t.X)
+	}
+	return false
+}
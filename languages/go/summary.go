@@ -0,0 +1,118 @@
+package main
+
+// Run summary statistics
+// Results arrive from concurrent workers, so the aggregator guards its
+// counters with a mutex and tracks how many samples are in flight.
+
+import (
+	"sync"
+	"time"
+)
+
+// RunSummary describes a whole test run
+type RunSummary struct {
+	Total                int            `json:"total"`
+	ByStatus             map[string]int `json:"by_status"`
+	ByProduct            map[string]int `json:"by_product"`
+	ByErrorCategory      map[string]int `json:"by_error_category"`
+	WallTime             float64        `json:"wall_time"`
+	PrefetchTime         float64        `json:"prefetch_time"`
+	WarmUpTime           float64        `json:"warm_up_time"`
+	CumulativeTime       float64        `json:"cumulative_time"`
+	MaxConcurrent        int            `json:"max_concurrent"`
+	MaxConcurrentNetwork int            `json:"max_concurrent_network"`
+	PeakParallelism      int            `json:"peak_parallelism"`
+	EffectiveParallelism float64        `json:"effective_parallelism"`
+}
+
+// SummaryAggregator collects results from concurrent workers into a RunSummary
+type SummaryAggregator struct {
+	mu       sync.Mutex
+	summary  RunSummary
+	started  time.Time
+	inFlight int
+}
+
+// NewSummaryAggregator creates an aggregator for a run limited to maxConcurrent workers
+func NewSummaryAggregator(maxConcurrent int) *SummaryAggregator {
+	return &SummaryAggregator{
+		summary: RunSummary{
+			ByStatus:        make(map[string]int),
+			ByProduct:       make(map[string]int),
+			ByErrorCategory: make(map[string]int),
+			MaxConcurrent:   maxConcurrent,
+		},
+		started: time.Now(),
+	}
+}
+
+// Begin marks a sample as started
+func (a *SummaryAggregator) Begin() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.inFlight++
+	if a.inFlight > a.summary.PeakParallelism {
+		a.summary.PeakParallelism = a.inFlight
+	}
+}
+
+// Finish records a sample previously passed to Begin
+func (a *SummaryAggregator) Finish(result TestResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.inFlight--
+	a.record(result)
+}
+
+// Add records a sample that was never started, such as a skipped one
+func (a *SummaryAggregator) Add(result TestResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.record(result)
+}
+
+func (a *SummaryAggregator) record(result TestResult) {
+	a.summary.Total++
+	a.summary.ByStatus[result.Status]++
+	a.summary.ByProduct[sampleProduct(result.Sample)]++
+	if result.ErrorCategory != "" {
+		a.summary.ByErrorCategory[result.ErrorCategory]++
+	}
+	a.summary.CumulativeTime += result.ExecutionTime
+}
+
+// Summary returns a snapshot of the statistics collected so far
+func (a *SummaryAggregator) Summary() RunSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	summary := a.summary
+	summary.ByStatus = copyCounts(a.summary.ByStatus)
+	summary.ByProduct = copyCounts(a.summary.ByProduct)
+	summary.ByErrorCategory = copyCounts(a.summary.ByErrorCategory)
+	summary.WallTime = time.Since(a.started).Seconds()
+	if summary.WallTime > 0 {
+		summary.EffectiveParallelism = summary.CumulativeTime / summary.WallTime
+	}
+
+	return summary
+}
+
+// sampleProduct returns the API product a sample exercises
+func sampleProduct(sample CodeSample) string {
+	if sample.Product != "" {
+		return sample.Product
+	}
+	return ProductUnclassified
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	copied := make(map[string]int, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
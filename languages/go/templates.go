@@ -0,0 +1,230 @@
+package main
+
+// Sample templates
+// New samples are most often broken when written from scratch or copied
+// from a stale page. `dgtest new-sample --type prerecorded --page x.mdx`
+// inserts a scaffold copied from a canonical sample of that type that
+// passed its most recent stored run, so a writer starts from code known to
+// work today. templates pins the canonical sample of a type by ID;
+// otherwise the shortest standalone sample of the type's product that
+// currently passes is used.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sampleTemplateType is the product and, when it matters, feature a
+// template type draws its canonical sample from
+type sampleTemplateType struct {
+	Product string
+	Feature string
+}
+
+// sampleTemplateTypes are the built-in template types
+var sampleTemplateTypes = map[string]sampleTemplateType{
+	"prerecorded":   {ProductSTTPrerecorded, ""},
+	"live":          {ProductSTTLive, ""},
+	"tts":           {ProductTTS, FeatureREST},
+	"tts-streaming": {ProductTTS, FeatureWebSocket},
+	"agent":         {ProductVoiceAgent, ""},
+	"manage":        {ProductManagement, ""},
+	"analyze":       {ProductTextIntelligence, ""},
+}
+
+// canonicalSample is a scaffold's source and the run that vouches for it
+type canonicalSample struct {
+	Sample   CodeSample
+	PassedAt time.Time
+}
+
+// templateTypes returns the built-in types and those pinned in templates
+func (e *GoExecutor) templateTypes() map[string]bool {
+	types := make(map[string]bool)
+	for name := range sampleTemplateTypes {
+		types[name] = true
+	}
+	for name := range configSection(e.FrameworkConfig, "templates") {
+		types[name] = true
+	}
+	return types
+}
+
+// lastPassed returns when a sample passed, if it passed its most recent
+// run in the history
+func lastPassed(sample CodeSample, history []HistoryRecord) (time.Time, bool) {
+	location := fmt.Sprintf("%s:%d", sample.Page, sample.LineNumber)
+	for i := len(history) - 1; i >= 0; i-- {
+		if recorded, ok := history[i].sample(sample.ID, location); ok {
+			return history[i].Time, recorded.Status == StatusPassed
+		}
+	}
+	return time.Time{}, false
+}
+
+// standaloneSample reports whether a sample makes a scaffold on its own:
+// a whole program that isn't a step of a chain, an error demonstration,
+// or specific to one platform
+func standaloneSample(sample CodeSample) bool {
+	for _, key := range []string{metadataStep, metadataDependsOn, metadataExpectedError, metadataFault, metadataTier, metadataOS} {
+		if sample.Metadata[key] != "" {
+			return false
+		}
+	}
+	return strings.Contains(sample.Code, "func main()")
+}
+
+// canonicalSample picks the sample a template type's scaffold is copied
+// from, which must have passed its most recent stored run
+func (e *GoExecutor) canonicalSample(templateType string, samples []CodeSample, history []HistoryRecord, now time.Time) (canonicalSample, error) {
+	if pinned := configString(configSection(e.FrameworkConfig, "templates"), templateType, ""); pinned != "" {
+		for _, sample := range samples {
+			if sample.ID != pinned {
+				continue
+			}
+			passed, ok := lastPassed(sample, history)
+			if !ok || e.openQuarantine(sample, now) != nil {
+				return canonicalSample{}, fmt.Errorf("the %s template's sample %s (%s:%d) isn't passing in the stored history; fix it or pin another under templates",
+					templateType, pinned, sample.Page, sample.LineNumber)
+			}
+			return canonicalSample{sample, passed}, nil
+		}
+		return canonicalSample{}, fmt.Errorf("the %s template's sample %s isn't in the docs", templateType, pinned)
+	}
+
+	kind, ok := sampleTemplateTypes[templateType]
+	if !ok {
+		return canonicalSample{}, fmt.Errorf("unknown sample type %q (expected one of %s)", templateType, strings.Join(sortedKeys(e.templateTypes()), ", "))
+	}
+	var candidates []canonicalSample
+	for _, sample := range samples {
+		if sampleProduct(sample) != kind.Product || (kind.Feature != "" && sample.Feature != kind.Feature) {
+			continue
+		}
+		if !standaloneSample(sample) || e.openQuarantine(sample, now) != nil {
+			continue
+		}
+		if passed, ok := lastPassed(sample, history); ok {
+			candidates = append(candidates, canonicalSample{sample, passed})
+		}
+	}
+	if len(candidates) == 0 {
+		return canonicalSample{}, fmt.Errorf("no standalone %s sample passed its most recent stored run; run the docs with --store or --history first", kind.Product)
+	}
+
+	// The shortest passing sample is the least for a writer to delete
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].Sample, candidates[j].Sample
+		if lines, other := strings.Count(a.Code, "\n"), strings.Count(b.Code, "\n"); lines != other {
+			return lines < other
+		}
+		if a.Page != b.Page {
+			return a.Page < b.Page
+		}
+		return a.LineNumber < b.LineNumber
+	})
+	return candidates[0], nil
+}
+
+// insertSample adds a fenced Go block to a page before line (1-based), or
+// at the end when line is 0, keeping the page's line endings
+func insertSample(content, code string, line int) (string, error) {
+	newline := "\n"
+	if strings.Contains(content, "\r\n") {
+		newline = "\r\n"
+	}
+	block := []string{"```go"}
+	block = append(block, strings.Split(strings.TrimRight(code, "\n"), "\n")...)
+	block = append(block, "```")
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if line == 0 {
+		// Separate the block from the page's last paragraph
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, "")
+		lines = append(lines, block...)
+		lines = append(lines, "")
+		return strings.Join(lines, newline), nil
+	}
+	if line < 1 || line > len(lines) {
+		return "", fmt.Errorf("line %d is outside the page's %d lines", line, len(lines))
+	}
+	fenced := false
+	for _, existing := range lines[:line-1] {
+		if strings.HasPrefix(strings.TrimSpace(existing), "```") {
+			fenced = !fenced
+		}
+	}
+	if fenced {
+		return "", fmt.Errorf("line %d is inside a code block", line)
+	}
+	inserted := append([]string{}, lines[:line-1]...)
+	inserted = append(inserted, block...)
+	inserted = append(inserted, "")
+	inserted = append(inserted, lines[line-1:]...)
+	return strings.Join(inserted, newline), nil
+}
+
+// newSampleCommand inserts a verified scaffold into a page
+func newSampleCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("new-sample", flag.ContinueOnError)
+	docs := addDocsFlags(flags)
+	templateType := flags.String("type", "", "Sample type: prerecorded, live, tts, tts-streaming, agent, manage, analyze, or one from templates")
+	page := flags.String("page", "", "Page to insert the sample into")
+	line := flags.Int("line", 0, "Insert the sample before this line of the page (default: at the end)")
+	historyPath := flags.String("history", "", "Read results from this JSON-lines history file instead of the configured storage")
+	printOnly := flags.Bool("print", false, "Print the scaffold instead of inserting it")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *templateType == "" || (*page == "" && !*printOnly) {
+		return fmt.Errorf("usage: new-sample --type prerecorded --page path.mdx [--line N]")
+	}
+
+	executor, samples, err := docs.load(ctx)
+	if err != nil {
+		return err
+	}
+	var storage Storage = diskStorage{historyFile: *historyPath}
+	if *historyPath == "" {
+		if storage, err = executor.storage(); err != nil {
+			return err
+		}
+	}
+	history, err := storage.LoadHistory(ctx)
+	if err != nil {
+		return err
+	}
+
+	canonical, err := executor.canonicalSample(*templateType, samples, history, time.Now())
+	if err != nil {
+		return err
+	}
+	source := canonical.Sample
+	if *printOnly {
+		fmt.Println(source.Code)
+		return nil
+	}
+
+	content, err := os.ReadFile(*page)
+	if err != nil {
+		return err
+	}
+	updated, err := insertSample(string(content), source.Code, *line)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*page, []byte(updated), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✏️  Inserted the %s scaffold from %s:%d (%s, passed %s) into %s\n",
+		*templateType, source.Page, source.LineNumber, source.ID, canonical.PassedAt.Format("2006-01-02"), *page)
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+// API key tier simulation
+// Docs tell readers what happens when their key lacks a scope or has
+// expired, but the mock accepts any key. With a tier selected, the mock
+// checks each request against the scopes the tier's key holds: requests
+// needing a scope it lacks get a 403, and every request with an expired key
+// gets a 401, as the real API answers.
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// KeyTier is a simulated API key permission tier
+type KeyTier struct {
+	Name    string   `json:"name"`
+	Scopes  []string `json:"scopes"`
+	Expired bool     `json:"expired,omitempty"`
+
+	// pathScopes are the scopes needed by requests outside the Management
+	// API, keyed by path prefix
+	pathScopes map[string][]string
+}
+
+// allScopes are the scopes held by an owner key
+var allScopes = []string{
+	"billing:read", "keys:read", "keys:write", "members:read", "members:write",
+	"project:read", "project:write", "usage:read", "usage:write",
+}
+
+// defaultKeyTiers are the tiers available without mocking.tiers
+var defaultKeyTiers = map[string]KeyTier{
+	"owner":      {Scopes: allScopes},
+	"member":     {Scopes: []string{"keys:read", "members:read", "project:read", "usage:read", "usage:write"}},
+	"usage_only": {Scopes: []string{"usage:write"}},
+	"expired":    {Scopes: allScopes, Expired: true},
+}
+
+// managementResources maps Management API path segments to the resource
+// part of the scope they need; the project itself is the fallback
+var managementResources = map[string]string{
+	"keys":     "keys",
+	"members":  "members",
+	"scopes":   "members",
+	"invites":  "members",
+	"leave":    "members",
+	"usage":    "usage",
+	"requests": "usage",
+	"balances": "billing",
+}
+
+// keyTiers overlays mocking.tiers onto the default tiers
+func (e *GoExecutor) keyTiers() map[string]KeyTier {
+	tiers := make(map[string]KeyTier, len(defaultKeyTiers))
+	for name, tier := range defaultKeyTiers {
+		tiers[name] = tier
+	}
+	configured := configSection(configSection(e.FrameworkConfig, "mocking"), "tiers")
+	for name := range configured {
+		config := configSection(configured, name)
+		tiers[name] = KeyTier{Scopes: configStrings(config, "scopes"), Expired: configBool(config, "expired", false)}
+	}
+
+	pathScopes := scopeTable(configSection(configSection(e.LanguageConfig, "api_scopes"), "paths"), defaultPathScopes)
+	for name, tier := range tiers {
+		tier.Name = name
+		tier.Scopes = append([]string{}, tier.Scopes...)
+		sort.Strings(tier.Scopes)
+		tier.pathScopes = pathScopes
+		tiers[name] = tier
+	}
+	return tiers
+}
+
+// keyTier returns the named tier, or nil for "" (every request allowed)
+func (e *GoExecutor) keyTier(name string) (*KeyTier, error) {
+	if name == "" {
+		name = configString(configSection(e.FrameworkConfig, "mocking"), "tier", "")
+	}
+	if name == "" {
+		return nil, nil
+	}
+	tiers := e.keyTiers()
+	tier, ok := tiers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown key tier %q (expected %s)", name, strings.Join(tierNames(tiers), ", "))
+	}
+	return &tier, nil
+}
+
+// missingScopes returns the scopes in required that the tier's key lacks
+func (t *KeyTier) missingScopes(required []string) []string {
+	var missing []string
+	for _, scope := range required {
+		if !containsString(t.Scopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// status is the status the API answers a request needing scopes with
+// under this tier
+func (t *KeyTier) status(required []string) int {
+	switch {
+	case t.Expired:
+		return http.StatusUnauthorized
+	case len(t.missingScopes(required)) > 0:
+		return http.StatusForbidden
+	}
+	return http.StatusOK
+}
+
+// requestScopes returns the scopes a request to the mock needs. Management
+// API requests need read access to their resource for GETs and write
+// access otherwise; other requests need the scopes of their path prefix.
+func (t *KeyTier) requestScopes(r *http.Request) []string {
+	if strings.HasPrefix(r.URL.Path, "/v1/projects") {
+		resource := "project"
+		for _, segment := range strings.Split(r.URL.Path, "/") {
+			if mapped, ok := managementResources[segment]; ok {
+				resource = mapped
+			}
+		}
+		access := "write"
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			access = "read"
+		}
+		return []string{resource + ":" + access}
+	}
+
+	var scopes []string
+	for prefix, required := range t.pathScopes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			scopes = append(scopes, required...)
+		}
+	}
+	return scopes
+}
+
+// wrap rejects requests the tier's key can't make in front of the mock's handler
+func (t *KeyTier) wrap(next http.Handler) http.Handler {
+	if t == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.Expired {
+			writeMockError(w, http.StatusUnauthorized, "INVALID_AUTH", "Invalid credentials. The API key has expired.")
+			return
+		}
+		if missing := t.missingScopes(t.requestScopes(r)); len(missing) > 0 {
+			writeMockError(w, http.StatusForbidden, "FORBIDDEN", "Insufficient permissions. The API key is missing the "+strings.Join(missing, ", ")+" scope.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tierNames returns the sorted names of tiers
+func tierNames(tiers map[string]KeyTier) []string {
+	names := make([]string, 0, len(tiers))
+	for name := range tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,312 @@
+package main
+
+// Code transformers
+// Samples are prepared for execution by a pipeline of named transformers,
+// each rewriting the code the previous one produced. The built-ins run in
+// this order, and all but wrap-main and inject-timeouts run by default:
+//
+//	wrap-main                wraps bare statements in func main
+//	add-package              adds a package clause to snippets without one
+//	substitute-placeholders  replaces placeholders like "YOUR_API_KEY"
+//	rewrite-imports          applies sdk.import_rewrites
+//	rewrite-base-url         points the Host option and API URLs at the base URL
+//	inject-timeouts          gives context.Background() a deadline
+//	streaming-watchdog       stops streaming samples written to run forever
+//	network-note             marks samples with URLs as network-bound
+//
+// execution.transformers in the language config enables and disables them,
+// globally or for a class of samples keyed like execution.policies (product,
+// feature, compile-only/network, or sample type; the first key naming a
+// transformer wins), and adds regexp replacements as custom transformers,
+// which run after the transformer they name in after, else last:
+//
+//	execution:
+//	  transformers:
+//	    enable: [wrap-main]
+//	    custom:
+//	      - {name: shorten-sleeps, pattern: 'time\.Sleep\([^)]*\)', replacement: 'time.Sleep(0)', after: rewrite-imports}
+//	    overrides:
+//	      stt-live: {enable: [inject-timeouts], disable: [shorten-sleeps]}
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Built-in transformers
+const (
+	TransformWrapMain               = "wrap-main"
+	TransformAddPackage             = "add-package"
+	TransformSubstitutePlaceholders = "substitute-placeholders"
+	TransformRewriteImports         = "rewrite-imports"
+	TransformRewriteBaseURL         = "rewrite-base-url"
+	TransformInjectTimeouts         = "inject-timeouts"
+	TransformNetworkNote            = "network-note"
+)
+
+// codeTransformer is one named step preparing a sample's code
+type codeTransformer struct {
+	Name string
+	// Default is whether the transformer runs unless disabled
+	Default bool
+	Apply   func(sample CodeSample, code string) string
+}
+
+// defaultPlaceholders apply when execution.transformers.placeholders is unset
+var defaultPlaceholders = map[string]string{`"YOUR_API_KEY"`: `"test_key"`}
+
+// packageClauseRegex matches a file's package clause
+var packageClauseRegex = regexp.MustCompile(`(?m)^package\s+\w+[^\n]*\n`)
+
+// transformersConfig returns the language config's execution.transformers
+func (e *GoExecutor) transformersConfig() map[string]interface{} {
+	return configSection(configSection(e.LanguageConfig, "execution"), "transformers")
+}
+
+// builtinTransformers returns the built-in transformers in pipeline order
+func (e *GoExecutor) builtinTransformers() []codeTransformer {
+	return []codeTransformer{
+		{TransformWrapMain, false, func(_ CodeSample, code string) string { return wrapMain(code) }},
+		{TransformAddPackage, true, func(_ CodeSample, code string) string {
+			if !strings.HasPrefix(code, "package") {
+				code = "package main\n\n" + code
+			}
+			return code
+		}},
+		{TransformSubstitutePlaceholders, true, func(_ CodeSample, code string) string { return e.substitutePlaceholders(code) }},
+		{TransformRewriteImports, true, func(_ CodeSample, code string) string { return e.rewriteImports(code) }},
+		{TransformRewriteBaseURL, true, func(_ CodeSample, code string) string { return e.rewriteBaseURL(code) }},
+		{TransformInjectTimeouts, false, func(sample CodeSample, code string) string {
+			return injectTimeouts(code, e.contextTimeout(sample))
+		}},
+		{TransformStreamingWatchdog, true, e.streamingWatchdog},
+		{TransformNetworkNote, true, func(_ CodeSample, code string) string {
+			if strings.Contains(code, "http://") || strings.Contains(code, "https://") {
+				code = "// Note: This would make real network calls\n" + code
+			}
+			return code
+		}},
+	}
+}
+
+// codeTransformers returns every transformer, built-in and custom, in
+// pipeline order, checking the names execution.transformers refers to
+func (e *GoExecutor) codeTransformers() ([]codeTransformer, error) {
+	config := e.transformersConfig()
+	transformers := e.builtinTransformers()
+	known := make(map[string]bool)
+	for _, transformer := range transformers {
+		known[transformer.Name] = true
+	}
+
+	custom, _ := config["custom"].([]interface{})
+	for i, entry := range custom {
+		rule, _ := entry.(map[string]interface{})
+		name := configString(rule, "name", "")
+		if name == "" {
+			return nil, fmt.Errorf("custom transformer %d has no name", i)
+		}
+		if known[name] {
+			return nil, fmt.Errorf("custom transformer %s: a transformer has that name", name)
+		}
+		pattern, err := regexp.Compile(configString(rule, "pattern", ""))
+		if err == nil && pattern.String() == "" {
+			err = fmt.Errorf("no pattern")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("custom transformer %s: %v", name, err)
+		}
+		replacement := configString(rule, "replacement", "")
+		transformer := codeTransformer{name, true, func(_ CodeSample, code string) string {
+			return pattern.ReplaceAllString(code, replacement)
+		}}
+
+		position := len(transformers)
+		if after := configString(rule, "after", ""); after != "" {
+			position = -1
+			for j, existing := range transformers {
+				if existing.Name == after {
+					position = j + 1
+				}
+			}
+			if position < 0 {
+				return nil, fmt.Errorf("custom transformer %s runs after unknown transformer %q", name, after)
+			}
+		}
+		transformers = append(transformers[:position], append([]codeTransformer{transformer}, transformers[position:]...)...)
+		known[name] = true
+	}
+
+	sections := []string{""}
+	overrides := configSection(config, "overrides")
+	for key := range overrides {
+		sections = append(sections, key)
+	}
+	sort.Strings(sections)
+	for _, key := range sections {
+		section, name := config, "execution.transformers"
+		if key != "" {
+			section, name = configSection(overrides, key), name+".overrides."+key
+		}
+		for _, list := range []string{"enable", "disable"} {
+			for _, transformer := range configStrings(section, list) {
+				if !known[transformer] {
+					return nil, fmt.Errorf("%s.%s: unknown transformer %q (expected one of %s)", name, list, transformer, strings.Join(sortedKeys(known), ", "))
+				}
+			}
+		}
+	}
+	return transformers, nil
+}
+
+// samplePipeline returns the transformers that prepare a sample, in order
+func (e *GoExecutor) samplePipeline(sample CodeSample) []codeTransformer {
+	config := e.transformersConfig()
+	overrides := configSection(config, "overrides")
+
+	class := PolicyCompileOnly
+	if sample.RequiresNetwork {
+		class = PolicyNetwork
+	}
+	var matched []map[string]interface{}
+	for _, key := range []string{sample.Product, sample.Feature, class, sample.SampleType} {
+		if _, ok := overrides[key]; ok && key != "" {
+			matched = append(matched, configSection(overrides, key))
+		}
+	}
+	matched = append(matched, config)
+
+	var pipeline []codeTransformer
+	for _, transformer := range e.transformers {
+		enabled := transformer.Default
+		for _, section := range matched {
+			if containsString(configStrings(section, "disable"), transformer.Name) {
+				enabled = false
+				break
+			}
+			if containsString(configStrings(section, "enable"), transformer.Name) {
+				enabled = true
+				break
+			}
+		}
+		if enabled {
+			pipeline = append(pipeline, transformer)
+		}
+	}
+	return pipeline
+}
+
+// transformerNames lists a pipeline's transformers
+func transformerNames(pipeline []codeTransformer) []string {
+	names := make([]string, len(pipeline))
+	for i, transformer := range pipeline {
+		names[i] = transformer.Name
+	}
+	return names
+}
+
+// substitutePlaceholders replaces placeholders in a sample's code with the
+// values in execution.transformers.placeholders, or the defaults, longest
+// placeholder first
+func (e *GoExecutor) substitutePlaceholders(code string) string {
+	placeholders := defaultPlaceholders
+	if configured := configSection(e.transformersConfig(), "placeholders"); len(configured) > 0 {
+		placeholders = make(map[string]string)
+		for placeholder, value := range configured {
+			if replacement, ok := value.(string); ok {
+				placeholders[placeholder] = replacement
+			}
+		}
+	}
+
+	keys := sortedStringKeys(placeholders)
+	sort.SliceStable(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, placeholder := range keys {
+		code = strings.ReplaceAll(code, placeholder, placeholders[placeholder])
+	}
+	return code
+}
+
+// wrapMain wraps a fragment of bare statements in func main, keeping its
+// leading imports at file scope. Code that parses as a file is unchanged.
+func wrapMain(code string) string {
+	parses := func(src string) bool {
+		if !strings.HasPrefix(strings.TrimSpace(src), "package") {
+			src = "package main\n\n" + src
+		}
+		_, err := parser.ParseFile(token.NewFileSet(), "main.go", src, 0)
+		return err == nil
+	}
+	if parses(code) {
+		return code
+	}
+
+	imports, body := splitLeadingImports(code)
+	wrapped := imports + "\nfunc main() {\n" + body + "\n}\n"
+	if !parses(wrapped) {
+		return code
+	}
+	return wrapped
+}
+
+// contextTimeout is the deadline inject-timeouts gives a sample's contexts:
+// execution.transformers.context_timeout_seconds, else a second under the
+// sample's time limit, so requests fail with a deadline error before the
+// sample is killed
+func (e *GoExecutor) contextTimeout(sample CodeSample) time.Duration {
+	if seconds := configInt(e.transformersConfig(), "context_timeout_seconds", 0); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	timeout := e.samplePolicy(sample).Timeout - time.Second
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	return timeout
+}
+
+// injectTimeouts replaces context.Background() with a context that expires
+// after timeout. The helper imports time under its own name, so it can't
+// clash with the sample's imports; code without a package clause is
+// unchanged.
+func injectTimeouts(code string, timeout time.Duration) string {
+	if !packageClauseRegex.MatchString(code) || !strings.Contains(code, "context.Background()") {
+		return code
+	}
+
+	code = addImport(code, "dgtestTime", "time")
+	code = strings.ReplaceAll(code, "context.Background()", "dgtestContext()")
+	return code + fmt.Sprintf(`
+// dgtestContext is context.Background() with a deadline, added by the
+// inject-timeouts transformer. The context keeps its cancel func, which
+// the sample exiting releases.
+func dgtestContext() context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), %d*dgtestTime.Millisecond)
+	return dgtestDeadline{ctx, cancel}
+}
+
+type dgtestDeadline struct {
+	context.Context
+	cancel context.CancelFunc
+}
+`, timeout.Milliseconds())
+}
+
+// addImport imports a package under a name after the package clause and
+// the imports other transformers added, unless one already added it
+func addImport(code, name, importPath string) string {
+	declaration := fmt.Sprintf("import %s %q\n", name, importPath)
+	clause := packageClauseRegex.FindStringIndex(code)
+	if clause == nil || strings.Contains(code, declaration) {
+		return code
+	}
+	position := clause[1]
+	for strings.HasPrefix(code[position:], "import dgtest") {
+		position += strings.Index(code[position:], "\n") + 1
+	}
+	return code[:position] + declaration + code[position:]
+}
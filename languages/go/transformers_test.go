@@ -0,0 +1,162 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parsesAsFile reports whether code parses as a Go file
+func parsesAsFile(code string) bool {
+	_, err := parser.ParseFile(token.NewFileSet(), "main.go", code, 0)
+	return err == nil
+}
+
+func TestWrapMain(t *testing.T) {
+	tests := []struct {
+		name, code, want string
+	}{
+		{
+			name: "file",
+			code: "package main\n\nfunc main() {}\n",
+			want: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name: "declarations without a package clause",
+			code: "func main() {\n\tprintln(1)\n}\n",
+			want: "func main() {\n\tprintln(1)\n}\n",
+		},
+		{
+			name: "statements",
+			code: "x := 1\nprintln(x)",
+			want: "\nfunc main() {\nx := 1\nprintln(x)\n}\n",
+		},
+		{
+			name: "statements after imports",
+			code: "import \"fmt\"\n\nfmt.Println(1)",
+			want: "import \"fmt\"\n\nfunc main() {\nfmt.Println(1)\n}\n",
+		},
+		{
+			name: "broken either way",
+			code: "x := (",
+			want: "x := (",
+		},
+	}
+	for _, test := range tests {
+		if got := wrapMain(test.code); got != test.want {
+			t.Errorf("%s: wrapMain = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestInjectTimeouts(t *testing.T) {
+	tests := []struct {
+		name, code string
+		changed    bool
+	}{
+		{"no package clause", "ctx := context.Background()", false},
+		{"no background context", "package main\n\nfunc main() {}\n", false},
+		{"background context", "package main\n\nimport \"context\"\n\nfunc main() {\n\t_ = context.Background()\n\t_ = context.Background()\n}\n", true},
+		{"sample imports time", "package main\n\nimport (\n\t\"context\"\n\t\"time\"\n)\n\nfunc main() {\n\tctx, cancel := context.WithTimeout(context.Background(), time.Second)\n\tdefer cancel()\n\t_ = ctx\n}\n", true},
+	}
+	for _, test := range tests {
+		got := injectTimeouts(test.code, 1500*time.Millisecond)
+		if !test.changed {
+			if got != test.code {
+				t.Errorf("%s: injectTimeouts changed the code to %q", test.name, got)
+			}
+			continue
+		}
+		if !parsesAsFile(got) {
+			t.Errorf("%s: injectTimeouts produced code that doesn't parse:\n%s", test.name, got)
+		}
+		// Only the helper still calls context.Background()
+		if n := strings.Count(got, "context.Background()") - strings.Count(got, "// dgtestContext is context.Background()"); n != 1 {
+			t.Errorf("%s: %d uses of context.Background() left, want 1:\n%s", test.name, n, got)
+		}
+		if !strings.Contains(got, "import dgtestTime \"time\"\n") || !strings.Contains(got, "1500*dgtestTime.Millisecond") {
+			t.Errorf("%s: injectTimeouts = %s", test.name, got)
+		}
+		if again := addImport(got, "dgtestTime", "time"); again != got {
+			t.Errorf("%s: addImport imported dgtestTime twice", test.name)
+		}
+	}
+}
+
+func TestSamplePipeline(t *testing.T) {
+	e, err := NewGoExecutor(map[string]interface{}{
+		"sdk": map[string]interface{}{"repository_path": "sdk", "source_path": "."},
+		"execution": map[string]interface{}{"transformers": map[string]interface{}{
+			"disable": []interface{}{TransformNetworkNote},
+			"custom": []interface{}{
+				map[string]interface{}{"name": "shorten-sleeps", "pattern": `time\.Sleep\([^)]*\)`, "replacement": "time.Sleep(0)", "after": TransformRewriteImports},
+				map[string]interface{}{"name": "strip-todos", "pattern": `// TODO.*`},
+			},
+			"overrides": map[string]interface{}{
+				"stt":             map[string]interface{}{"enable": []interface{}{TransformInjectTimeouts}, "disable": []interface{}{"shorten-sleeps"}},
+				"live":            map[string]interface{}{"enable": []interface{}{"shorten-sleeps", TransformWrapMain}},
+				PolicyNetwork:     map[string]interface{}{"enable": []interface{}{TransformNetworkNote}},
+				PolicyCompileOnly: map[string]interface{}{"disable": []interface{}{"strip-todos"}},
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		sample CodeSample
+		want   []string
+	}{
+		{
+			name:   "defaults",
+			sample: CodeSample{Product: "tts", RequiresNetwork: true},
+			want:   []string{TransformAddPackage, TransformSubstitutePlaceholders, TransformRewriteImports, "shorten-sleeps", TransformRewriteBaseURL, TransformStreamingWatchdog, TransformNetworkNote, "strip-todos"},
+		},
+		{
+			name:   "product override",
+			sample: CodeSample{Product: "stt", RequiresNetwork: true},
+			want:   []string{TransformAddPackage, TransformSubstitutePlaceholders, TransformRewriteImports, TransformRewriteBaseURL, TransformInjectTimeouts, TransformStreamingWatchdog, TransformNetworkNote, "strip-todos"},
+		},
+		{
+			// The product's override wins over the feature's for shorten-sleeps
+			name:   "product before feature",
+			sample: CodeSample{Product: "stt", Feature: "live"},
+			want:   []string{TransformWrapMain, TransformAddPackage, TransformSubstitutePlaceholders, TransformRewriteImports, TransformRewriteBaseURL, TransformInjectTimeouts, TransformStreamingWatchdog},
+		},
+	}
+	for _, test := range tests {
+		if got := transformerNames(e.samplePipeline(test.sample)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: pipeline = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNewGoExecutorTransformerErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		transformers map[string]interface{}
+		want         string
+	}{
+		{"unnamed custom", map[string]interface{}{"custom": []interface{}{map[string]interface{}{"pattern": "x"}}}, "has no name"},
+		{"custom shadows a built-in", map[string]interface{}{"custom": []interface{}{map[string]interface{}{"name": TransformWrapMain, "pattern": "x"}}}, "a transformer has that name"},
+		{"no pattern", map[string]interface{}{"custom": []interface{}{map[string]interface{}{"name": "x"}}}, "no pattern"},
+		{"bad pattern", map[string]interface{}{"custom": []interface{}{map[string]interface{}{"name": "x", "pattern": "("}}}, "custom transformer x"},
+		{"unknown after", map[string]interface{}{"custom": []interface{}{map[string]interface{}{"name": "x", "pattern": "x", "after": "nope"}}}, `unknown transformer "nope"`},
+		{"unknown enable", map[string]interface{}{"enable": []interface{}{"nope"}}, `execution.transformers.enable: unknown transformer "nope"`},
+		{"unknown override", map[string]interface{}{"overrides": map[string]interface{}{"stt": map[string]interface{}{"disable": []interface{}{"nope"}}}}, "execution.transformers.overrides.stt.disable"},
+	}
+	for _, test := range tests {
+		_, err := NewGoExecutor(map[string]interface{}{
+			"sdk":       map[string]interface{}{"repository_path": "sdk", "source_path": "."},
+			"execution": map[string]interface{}{"transformers": test.transformers},
+		}, nil)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: error = %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
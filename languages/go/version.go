@@ -0,0 +1,190 @@
+package main
+
+// Executor version
+// Repos share configs, and a config written for a newer executor can run
+// silently differently on an older one. `dgtest version` reports what a
+// binary was built from, and require_version (or --require-version) makes
+// every docs command fail fast on a binary older than the config expects.
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Build metadata, set at build time with e.g.
+// -ldflags "-X main.executorVersion=v1.2.3 -X main.executorCommit=abc1234 -X main.executorBuildDate=2026-01-02"
+var (
+	executorVersion   = devVersion
+	executorCommit    = ""
+	executorBuildDate = ""
+)
+
+// devVersion is the version of builds without -ldflags
+const devVersion = "dev"
+
+// VersionInfo describes an executor build
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// currentVersion describes this build, taking the commit and time from the
+// toolchain's VCS stamp when they weren't set with -ldflags
+func currentVersion() VersionInfo {
+	info := VersionInfo{
+		Version:   executorVersion,
+		Commit:    executorCommit,
+		BuildDate: executorBuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		modified := false
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && executorCommit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	return info
+}
+
+// checkRequiredVersion fails when this executor is older than required, a
+// minimum version such as "v1.4.0" or ">= 1.4". Development builds can't be
+// ordered and only get a warning.
+func checkRequiredVersion(required string) error {
+	required = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(required), ">="))
+	if required == "" {
+		return nil
+	}
+	if _, ok := parseVersion(required); !ok {
+		return fmt.Errorf("require_version %q isn't a version like v1.4.0", required)
+	}
+
+	if executorVersion == devVersion {
+		fmt.Fprintf(os.Stderr, "⚠️  Development build of dgtest; can't check the config's require_version %s\n", required)
+		return nil
+	}
+	order, ok := compareVersions(executorVersion, required)
+	if !ok {
+		return fmt.Errorf("can't compare executor version %q with require_version %s", executorVersion, required)
+	}
+	if order < 0 {
+		return fmt.Errorf("config requires dgtest %s or newer, but this is %s; rebuild or download a newer executor", required, executorVersion)
+	}
+	return nil
+}
+
+// semanticVersion is a parsed vMAJOR.MINOR.PATCH[-PRERELEASE]
+type semanticVersion struct {
+	numbers    [3]int
+	prerelease string
+}
+
+// parseVersion parses a version with or without its "v", where missing
+// minor and patch numbers are zero and +build metadata is ignored
+func parseVersion(version string) (semanticVersion, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "+")
+
+	var parsed semanticVersion
+	version, parsed.prerelease, _ = strings.Cut(version, "-")
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return parsed, false
+		}
+		parsed.numbers[i] = number
+	}
+	return parsed, true
+}
+
+// compareVersions orders two versions, returning -1, 0, or 1. A prerelease
+// comes before its release.
+func compareVersions(a, b string) (int, bool) {
+	left, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	right, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+
+	for i := range left.numbers {
+		switch {
+		case left.numbers[i] < right.numbers[i]:
+			return -1, true
+		case left.numbers[i] > right.numbers[i]:
+			return 1, true
+		}
+	}
+	switch {
+	case left.prerelease == right.prerelease:
+		return 0, true
+	case left.prerelease == "":
+		return 1, true
+	case right.prerelease == "":
+		return -1, true
+	case left.prerelease < right.prerelease:
+		return -1, true
+	}
+	return 1, true
+}
+
+// versionCommand prints the executor's build metadata
+func versionCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	format := flags.String("format", FormatPlain, "Output format: plain or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	info := currentVersion()
+	switch *format {
+	case FormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	case FormatPlain:
+		fmt.Printf("dgtest %s\n", info.Version)
+		if info.Commit != "" {
+			fmt.Printf("   Commit:   %s\n", info.Commit)
+		}
+		if info.BuildDate != "" {
+			fmt.Printf("   Built:    %s\n", info.BuildDate)
+		}
+		fmt.Printf("   Go:       %s\n", info.GoVersion)
+		fmt.Printf("   Platform: %s\n", info.Platform)
+		return nil
+	default:
+		return fmt.Errorf("unknown version format: %s", *format)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+// Docs walk filtering
+// Not everything under fern/pages should be tested: archived pages,
+// changelogs, and partials included elsewhere would only add noise. The
+// walk honours each root's file_patterns and exclude_paths, defaulting to
+// those in the documentation config, plus a .dgtestignore file at the root. All patterns
+// are globs against slash-separated paths relative to the docs root, where
+// ** matches any number of directories.
+//
+// .dgtestignore holds one pattern per line; blank lines and lines starting
+// with # are ignored. As in .gitignore, a pattern without a slash matches a
+// file or directory name at any depth and a trailing slash matches only
+// directories. Negation isn't supported.
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the ignore file read from the docs root
+const ignoreFileName = ".dgtestignore"
+
+// walkFilter decides which files and directories the docs walk visits
+type walkFilter struct {
+	include []string
+	exclude []string
+	// dirOnly holds excluded patterns that match only directories
+	dirOnly []string
+}
+
+// newWalkFilter builds the filter for a docs root from its globs and its
+// .dgtestignore
+func newWalkFilter(root docsRoot) (walkFilter, error) {
+	filter := walkFilter{
+		include: root.FilePatterns,
+		exclude: append([]string{}, root.ExcludePaths...),
+	}
+	if len(filter.include) == 0 {
+		filter.include = []string{"**/*.mdx"}
+	}
+
+	file, err := os.Open(filepath.Join(root.Path, ignoreFileName))
+	if os.IsNotExist(err) {
+		return filter, nil
+	}
+	if err != nil {
+		return filter, err
+	}
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		pattern := strings.TrimSpace(lines.Text())
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		dirOnly := strings.HasSuffix(pattern, "/")
+		pattern = strings.TrimSuffix(pattern, "/")
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+		} else {
+			pattern = "**/" + pattern
+		}
+
+		if dirOnly {
+			filter.dirOnly = append(filter.dirOnly, pattern)
+		} else {
+			filter.exclude = append(filter.exclude, pattern)
+		}
+	}
+	return filter, lines.Err()
+}
+
+// skipDir reports whether the walk should skip a directory
+func (f walkFilter) skipDir(rel string) bool {
+	for _, pattern := range append(append([]string{}, f.exclude...), f.dirOnly...) {
+		// A pattern ending in /** excludes the directory itself
+		if matchGlob(pattern, rel) || matchGlob(strings.TrimSuffix(pattern, "/**"), rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeFile reports whether the walk should extract samples from a file
+func (f walkFilter) includeFile(rel string) bool {
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, rel) {
+			return false
+		}
+	}
+	for _, pattern := range f.include {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated path against a glob in which **
+// matches zero or more path elements and other elements follow path.Match
+func matchGlob(pattern, name string) bool {
+	return matchElements(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElements(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchElements(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, err := path.Match(pattern[0], name[0]); err != nil || !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// walkPages calls visit with the path and content of every page under a
+// root's pages path that the root's filter includes
+func walkPages(ctx context.Context, root docsRoot, visit func(path string, content []byte) error) error {
+	pagesPath := filepath.Join(root.Path, filepath.FromSlash(root.PagesPath))
+
+	filter, err := newWalkFilter(root)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(pagesPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel := docsRelativePath(root.Path, path)
+		if d.IsDir() {
+			if path != pagesPath && filter.skipDir(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !filter.includeFile(rel) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return visit(path, content)
+	})
+}
+
+// walkIncludes reports whether walkPages visits a file in a root
+func (root docsRoot) walkIncludes(file string) (bool, error) {
+	filter, err := newWalkFilter(root)
+	if err != nil {
+		return false, err
+	}
+	return filter.visits(root, file), nil
+}
+
+// visits reports whether a walk of root with this filter visits a file:
+// it's under the pages path, no directory above it is skipped, and the
+// filter includes it
+func (f walkFilter) visits(root docsRoot, file string) bool {
+	pagesPath := filepath.Join(root.Path, filepath.FromSlash(root.PagesPath))
+	within, err := filepath.Rel(pagesPath, file)
+	if err != nil || !filepath.IsLocal(within) {
+		return false
+	}
+	dir := pagesPath
+	for _, element := range strings.Split(filepath.ToSlash(filepath.Dir(within)), "/") {
+		if element == "." {
+			break
+		}
+		dir = filepath.Join(dir, element)
+		if f.skipDir(docsRelativePath(root.Path, dir)) {
+			return false
+		}
+	}
+	return f.includeFile(docsRelativePath(root.Path, file))
+}
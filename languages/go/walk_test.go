@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.mdx", "stt.mdx", true},
+		{"**/*.mdx", "docs/stt/live.mdx", true},
+		{"**/*.mdx", "docs/stt/live.md", false},
+		{"docs/*.mdx", "docs/stt.mdx", true},
+		{"docs/*.mdx", "docs/stt/live.mdx", false},
+		{"docs/**", "docs", true},
+		{"docs/**", "docs/stt/live.mdx", true},
+		{"docs/**/live.mdx", "docs/live.mdx", true},
+		{"docs/**/live.mdx", "docs/a/b/live.mdx", true},
+		{"**/changelog/**", "docs/changelog/2024.mdx", true},
+		{"**/changelog/**", "docs/changelogs/2024.mdx", false},
+		{"archive", "archive/old.mdx", false},
+		{"[", "[", false},
+	}
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %t, want %t", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestNewWalkFilter(t *testing.T) {
+	root := t.TempDir()
+	ignore := "# generated pages\n\n_partials/\nfern/pages/archive/**\n/drafts/*.mdx\nscratch.mdx\n"
+	if err := os.WriteFile(filepath.Join(root, ignoreFileName), []byte(ignore), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := newWalkFilter(docsRoot{Path: root, ExcludePaths: []string{"**/changelog/**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := []struct {
+		rel  string
+		skip bool
+	}{
+		{"fern/pages", false},
+		{"fern/pages/_partials", true},
+		{"fern/pages/archive", true},
+		{"fern/pages/docs/changelog", true},
+		{"drafts", false},
+	}
+	for _, test := range dirs {
+		if got := filter.skipDir(test.rel); got != test.skip {
+			t.Errorf("skipDir(%q) = %t, want %t", test.rel, got, test.skip)
+		}
+	}
+
+	files := []struct {
+		rel     string
+		include bool
+	}{
+		{"fern/pages/stt.mdx", true},
+		{"fern/pages/stt.md", false},
+		{"fern/pages/docs/scratch.mdx", false},
+		{"fern/pages/archive/old.mdx", false},
+		{"fern/pages/docs/changelog/2024.mdx", false},
+		{"drafts/tts.mdx", false},
+		{"fern/pages/drafts/tts.mdx", true},
+	}
+	for _, test := range files {
+		if got := filter.includeFile(test.rel); got != test.include {
+			t.Errorf("includeFile(%q) = %t, want %t", test.rel, got, test.include)
+		}
+	}
+}
+
+func TestNewWalkFilterDefaults(t *testing.T) {
+	filter, err := newWalkFilter(docsRoot{Path: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.includeFile("fern/pages/stt.mdx") || filter.includeFile("fern/pages/stt.md") {
+		t.Errorf("default filter should include only .mdx pages, got %+v", filter)
+	}
+
+	filter, err = newWalkFilter(docsRoot{Path: t.TempDir(), FilePatterns: []string{"**/*.md"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.includeFile("fern/pages/stt.mdx") || !filter.includeFile("fern/pages/stt.md") {
+		t.Errorf("file_patterns should replace the default, got %+v", filter)
+	}
+}
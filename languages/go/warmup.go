@@ -0,0 +1,95 @@
+package main
+
+// Build cache warm-up
+// When many samples start at once, each one compiles the SDK and its
+// dependencies from scratch, stampeding the compiler and exhausting memory
+// on CI runners. Compiling every imported package once up front means the
+// parallel samples only compile their own main package.
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// warmBuildCache compiles every package the samples import into the shared
+// build cache and returns how long it took. Failures are not fatal: packages
+// that can't be built are left for the samples to report.
+func (e *GoExecutor) warmBuildCache(ctx context.Context, samples []CodeSample) time.Duration {
+	started := time.Now()
+
+	packages := e.importUnion(samples)
+	if len(packages) == 0 {
+		return 0
+	}
+
+	dir, err := os.MkdirTemp("", "go-warmup-*")
+	if err != nil {
+		return time.Since(started)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(ctx, e.warmUpTimeout())
+	defer cancel()
+
+	if err := e.createImportAllModule(ctx, dir, packages); err != nil {
+		return time.Since(started)
+	}
+	// Without requirements `go list` would drop every non-stdlib package
+	var usage ResourceUsage
+	e.resolveDependencies(ctx, dir, "", packages, &usage)
+
+	// Build packages individually named so one unresolvable import doesn't
+	// stop the rest from being cached
+	list := exec.CommandContext(ctx, "go", append([]string{"list", "-e", "-f", "{{if not .Error}}{{.ImportPath}}{{end}}"}, packages...)...)
+	list.Dir = dir
+	list.Env = e.goToolEnv()
+	listed := time.Now()
+	output, err := list.Output()
+	e.Audit.record(list, "", listed, err)
+	if err != nil {
+		return time.Since(started)
+	}
+
+	buildable := strings.Fields(string(output))
+	if len(buildable) == 0 {
+		return time.Since(started)
+	}
+
+	build := exec.CommandContext(ctx, "go", append([]string{"build"}, buildable...)...)
+	build.Dir = dir
+	build.Env = e.goToolEnv()
+	built := time.Now()
+	err = build.Run()
+	e.Audit.record(build, "", built, err)
+
+	return time.Since(started)
+}
+
+// warmUpEnabled reports whether to warm the build cache before running samples
+func (e *GoExecutor) warmUpEnabled() bool {
+	execution := configSection(e.LanguageConfig, "execution")
+	return configBool(execution, "warm_up", true)
+}
+
+func (e *GoExecutor) warmUpTimeout() time.Duration {
+	execution := configSection(e.LanguageConfig, "execution")
+	return time.Duration(configInt(execution, "warm_up_timeout_seconds", 300)) * time.Second
+}
+
+// importUnion returns every distinct package imported by the samples,
+// after import rewrites
+func (e *GoExecutor) importUnion(samples []CodeSample) []string {
+	seen := make(map[string]bool)
+	for _, sample := range samples {
+		for _, importPath := range sample.Imports {
+			if importPath != "" && importPath != "C" {
+				seen[e.rewriteImportPath(importPath)] = true
+			}
+		}
+	}
+
+	return sortedKeys(seen)
+}
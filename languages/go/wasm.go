@@ -0,0 +1,71 @@
+//go:build js && wasm
+
+package main
+
+// Browser validator
+// Built for WebAssembly, the executor serves the docs preview site instead
+// of a command line: it defines a global dgtest object whose diagnostics
+// function checks the Go samples in an MDX document's text with the same
+// extraction and validation rules as `dgtest diagnostics` and CI, so
+// writers see broken snippets as they edit. Nothing is executed.
+//
+//	dgtest.configure(configJSON) // the --config JSON; returns an error message or null
+//	dgtest.diagnostics(uri, text) // returns {"uri": ..., "diagnostics": [...]} as JSON
+//	dgtest.version                // the executor's version
+
+import (
+	"context"
+	"encoding/json"
+	"syscall/js"
+)
+
+// serveWASM defines the dgtest object and serves it until the page unloads
+func serveWASM() bool {
+	executor, err := NewGoExecutor(defaultLanguageConfig(), defaultFrameworkConfig())
+	if err != nil {
+		panic(err)
+	}
+
+	api := js.Global().Get("Object").New()
+	api.Set("version", currentVersion().Version)
+	api.Set("configure", js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return "configure expects the configuration as JSON"
+		}
+		langConfig, frameworkConfig, err := parseConfig([]byte(args[0].String()))
+		if err != nil {
+			return err.Error()
+		}
+		// NewGoExecutor panics without the SDK paths, which would stop the
+		// validator for the rest of the page's life
+		sdk := configSection(langConfig, "sdk")
+		for _, key := range []string{"repository_path", "source_path"} {
+			if _, ok := sdk[key].(string); !ok {
+				return "configuration has no language.sdk." + key
+			}
+		}
+		configured, err := NewGoExecutor(langConfig, frameworkConfig)
+		if err != nil {
+			return err.Error()
+		}
+		executor = configured
+		return nil
+	}))
+	api.Set("diagnostics", js.FuncOf(func(_ js.Value, args []js.Value) interface{} {
+		if len(args) < 2 {
+			return js.Global().Get("Error").New("diagnostics expects a document URI and text")
+		}
+		uri, text := args[0].String(), args[1].String()
+		result, err := json.Marshal(map[string]interface{}{
+			"uri":         uri,
+			"diagnostics": executor.DocumentDiagnostics(context.Background(), uri, text),
+		})
+		if err != nil {
+			return js.Global().Get("Error").New(err.Error())
+		}
+		return string(result)
+	}))
+	js.Global().Set("dgtest", api)
+
+	select {}
+}
@@ -0,0 +1,9 @@
+//go:build !(js && wasm)
+
+package main
+
+// serveWASM serves the browser validator in WebAssembly builds; elsewhere
+// there is nothing to serve and the command line runs
+func serveWASM() bool {
+	return false
+}
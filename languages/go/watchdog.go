@@ -0,0 +1,171 @@
+package main
+
+// Streaming watchdogs
+// Live samples in the docs are often written to run until the reader stops
+// them: they stream a microphone, block on select {}, or wait for a signal.
+// Under test they'd run into the time limit and fail as timeouts. The
+// streaming-watchdog transformer, on by default, renames a streaming
+// sample's main and runs it under a generated one that waits for it with a
+// deadline. When the deadline passes, the watchdog stops the live client
+// the sample connected, giving it a moment to close, and exits cleanly, so
+// the sample passes if nothing went wrong before then. The published
+// snippet is unchanged.
+//
+// The deadline is execution.transformers.watchdog_seconds, else two seconds
+// under the sample's time limit.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// TransformStreamingWatchdog is the streaming watchdog transformer
+const TransformStreamingWatchdog = "streaming-watchdog"
+
+// watchdogGrace is how long a stopped client gets to close
+const watchdogGrace = time.Second
+
+// mainFuncRegex matches the declaration of a file's main function
+var mainFuncRegex = regexp.MustCompile(`(?m)^func main\(\)\s*\{`)
+
+// watchdogTimeout is how long a streaming sample runs before it is stopped
+func (e *GoExecutor) watchdogTimeout(sample CodeSample) time.Duration {
+	if seconds := configInt(e.transformersConfig(), "watchdog_seconds", 0); seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	timeout := e.samplePolicy(sample).Timeout - 2*watchdogGrace
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	return timeout
+}
+
+// liveConstructors returns the name of every live client constructor, of
+// any SDK version
+func (e *GoExecutor) liveConstructors() map[string]bool {
+	constructors := make(map[string]bool)
+	versions := []string{e.targetSDKVersion()}
+	for version := range defaultStreamingPatterns {
+		versions = append(versions, version)
+	}
+	for _, version := range versions {
+		patterns, _ := e.streamingPatternsFor(version)
+		for _, name := range append(patterns.Callback, patterns.Channel...) {
+			constructors[name] = true
+		}
+	}
+	return constructors
+}
+
+// streamingWatchdog runs a streaming sample's main under a watchdog. Other
+// samples, and code without a package clause or a single main, are unchanged.
+func (e *GoExecutor) streamingWatchdog(sample CodeSample, code string) string {
+	if sample.Feature != FeatureWebSocket || !packageClauseRegex.MatchString(code) {
+		return code
+	}
+	if len(mainFuncRegex.FindAllStringIndex(code, -1)) != 1 {
+		return code
+	}
+
+	code = registerClientStops(code, e.liveConstructors())
+	code = mainFuncRegex.ReplaceAllString(code, "func dgtestMain() {")
+	for _, pkg := range [][2]string{{"dgtestCtx", "context"}, {"dgtestFmt", "fmt"}, {"dgtestOS", "os"}, {"dgtestTime", "time"}} {
+		code = addImport(code, pkg[0], pkg[1])
+	}
+	timeout := e.watchdogTimeout(sample)
+	return code + fmt.Sprintf(`
+// dgtestStops stop the live clients the sample connected, added by the
+// streaming-watchdog transformer
+var dgtestStops []func()
+
+// main runs the sample until it returns or the watchdog's deadline passes,
+// then stops its clients and exits cleanly
+func main() {
+	ctx, cancel := dgtestCtx.WithTimeout(dgtestCtx.Background(), %d*dgtestTime.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dgtestMain()
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	dgtestFmt.Fprintf(dgtestOS.Stderr, "\nwatchdog: stopping the sample after %s\n")
+	for _, stop := range dgtestStops {
+		func() {
+			defer func() { recover() }()
+			stop()
+		}()
+	}
+	select {
+	case <-done:
+	case <-dgtestTime.After(%d * dgtestTime.Millisecond):
+	}
+	dgtestOS.Exit(0)
+}
+`, timeout.Milliseconds(), timeout, watchdogGrace.Milliseconds())
+}
+
+// registerClientStops registers a stop for every variable assigned a live
+// client, right after the assignment, so the watchdog can shut it down
+func registerClientStops(code string, constructors map[string]bool) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		return code
+	}
+
+	// Only statements of a block or case are followed, since a client
+	// assigned in an if's init statement can't be
+	insertions := make(map[int]string)
+	ast.Inspect(file, func(node ast.Node) bool {
+		var statements []ast.Stmt
+		switch block := node.(type) {
+		case *ast.BlockStmt:
+			statements = block.List
+		case *ast.CaseClause:
+			statements = block.Body
+		case *ast.CommClause:
+			statements = block.Body
+		}
+		for _, statement := range statements {
+			assign, ok := statement.(*ast.AssignStmt)
+			if !ok || len(assign.Rhs) != 1 || len(assign.Lhs) == 0 {
+				continue
+			}
+			call, ok := assign.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !constructors[selector.Sel.Name] {
+				continue
+			}
+			if client, ok := assign.Lhs[0].(*ast.Ident); ok && client.Name != "_" {
+				insertions[fset.Position(assign.End()).Offset] = fmt.Sprintf("; dgtestStops = append(dgtestStops, func() { %s.Stop() })", client.Name)
+			}
+		}
+		return true
+	})
+
+	// Insert from the end so earlier offsets stay valid
+	offsets := make([]int, 0, len(insertions))
+	for offset := range insertions {
+		offsets = append(offsets, offset)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	for _, offset := range offsets {
+		code = code[:offset] + insertions[offset] + code[offset:]
+	}
+	return code
+}
@@ -0,0 +1,252 @@
+package main
+
+// Server-side WebSockets for the mock API
+// The Voice Agent API is a WebSocket conversation, so the mock speaks just
+// enough RFC 6455 to hold one: the upgrade handshake, masked client frames,
+// fragmented messages, and ping, pong, and close control frames. There are
+// no extensions, so clients asking for compression get plain frames.
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// WebSocket close codes the mock sends
+const (
+	wsCloseNormal          = 1000
+	wsClosePolicyViolation = 1008
+)
+
+// wsGUID is appended to the client's key to derive Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsMaxMessage bounds a message from the client
+const wsMaxMessage = 16 << 20
+
+// wsMaxCloseReason is the longest close reason a control frame can carry
+const wsMaxCloseReason = 123
+
+// errWebSocketClosed is returned by readMessage once the client has closed
+var errWebSocketClosed = errors.New("websocket closed")
+
+// wsConn is the server end of a WebSocket connection
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	// mu serializes writes, which come from the conversation and from
+	// pongs sent while reading
+	mu     sync.Mutex
+	closed bool
+}
+
+// isWebSocketUpgrade reports whether a request asks to upgrade to a WebSocket
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// upgradeWebSocket completes the handshake for a WebSocket upgrade request,
+// accepting the first subprotocol the client offers, if any
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || key == "" || !headerContainsToken(r.Header, "Connection", "upgrade") {
+		writeMockError(w, http.StatusBadRequest, "BAD_REQUEST", "Invalid WebSocket upgrade request.")
+		return nil, fmt.Errorf("invalid WebSocket upgrade request")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeMockError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "The mock server can't upgrade this connection.")
+		return nil, fmt.Errorf("connection can't be hijacked")
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// The server's header timeout may have left a deadline on the connection
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+	if protocols := r.Header.Get("Sec-WebSocket-Protocol"); protocols != "" {
+		response += "Sec-WebSocket-Protocol: " + strings.TrimSpace(strings.Split(protocols, ",")[0]) + "\r\n"
+	}
+	if _, err := buffered.WriteString(response + "\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := buffered.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, reader: buffered.Reader}, nil
+}
+
+// headerContainsToken reports whether a comma-separated header lists token
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, element := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(element), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readMessage reads the next text or binary message, answering pings on
+// the way. It returns errWebSocketClosed once the client closes.
+func (c *wsConn) readMessage() (opcode int, message []byte, err error) {
+	for {
+		final, frameOpcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frameOpcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			c.conn.Close()
+			return 0, nil, errWebSocketClosed
+		case wsContinuation:
+			if opcode == 0 {
+				return 0, nil, fmt.Errorf("continuation frame without a message")
+			}
+		case wsText, wsBinary:
+			if opcode != 0 {
+				return 0, nil, fmt.Errorf("new message before the last one finished")
+			}
+			opcode = frameOpcode
+		default:
+			return 0, nil, fmt.Errorf("unknown opcode %#x", frameOpcode)
+		}
+
+		if len(message)+len(payload) > wsMaxMessage {
+			return 0, nil, fmt.Errorf("message larger than %d bytes", wsMaxMessage)
+		}
+		message = append(message, payload...)
+		if final {
+			return opcode, message, nil
+		}
+	}
+}
+
+// readFrame reads one frame, unmasking its payload
+func (c *wsConn) readFrame() (final bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	final = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0F)
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if !masked {
+		return false, 0, nil, fmt.Errorf("unmasked frame from the client")
+	}
+	if length > wsMaxMessage {
+		return false, 0, nil, fmt.Errorf("frame larger than %d bytes", wsMaxMessage)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return final, opcode, payload, nil
+}
+
+// writeFrame sends one unmasked, final frame
+func (c *wsConn) writeFrame(opcode int, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errWebSocketClosed
+	}
+	if opcode == wsClose {
+		c.closed = true
+	}
+
+	header := []byte{0x80 | byte(opcode)}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, byte(length>>8), byte(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// close sends a close frame with a code and reason, truncated to fit a
+// control frame, and drops the connection
+func (c *wsConn) close(code int, reason string) error {
+	if len(reason) > wsMaxCloseReason {
+		cut := wsMaxCloseReason
+		for cut > 0 && !utf8.RuneStart(reason[cut]) {
+			cut--
+		}
+		reason = reason[:cut]
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	err := c.writeFrame(wsClose, append(payload, reason...))
+	c.conn.Close()
+	if err == errWebSocketClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// clientFrame encodes a frame as a client sends it, masked unless mask is nil
+func clientFrame(final bool, opcode int, payload []byte, mask []byte) []byte {
+	first := byte(opcode)
+	if final {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	maskBit := byte(0)
+	if mask != nil {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	if mask == nil {
+		return append(frame, payload...)
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame reads an unmasked frame the server wrote
+func readServerFrame(r io.Reader) (final bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(r, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	return header[0]&0x80 != 0, int(header[0] & 0x0F), payload, nil
+}
+
+// pipeConn connects a server wsConn to the client end of a pipe
+func pipeConn(t *testing.T) (*wsConn, net.Conn) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return &wsConn{conn: server, reader: bufio.NewReader(server)}, client
+}
+
+func TestReadFrame(t *testing.T) {
+	mask := []byte{0x37, 0xfa, 0x21, 0x3d}
+	tests := []struct {
+		name    string
+		frame   []byte
+		final   bool
+		opcode  int
+		payload []byte
+		fails   bool
+	}{
+		{"text", clientFrame(true, wsText, []byte("Hello"), mask), true, wsText, []byte("Hello"), false},
+		{"empty", clientFrame(true, wsPing, nil, mask), true, wsPing, []byte{}, false},
+		{"fragment", clientFrame(false, wsBinary, []byte{1, 2, 3}, mask), false, wsBinary, []byte{1, 2, 3}, false},
+		{"16-bit length", clientFrame(true, wsBinary, bytes.Repeat([]byte{7}, 300), mask), true, wsBinary, bytes.Repeat([]byte{7}, 300), false},
+		{"64-bit length", clientFrame(true, wsBinary, bytes.Repeat([]byte{9}, 70000), mask), true, wsBinary, bytes.Repeat([]byte{9}, 70000), false},
+		{"unmasked", clientFrame(true, wsText, []byte("Hello"), nil), false, 0, nil, true},
+		{"too large", []byte{0x82, 0x80 | 127, 0, 0, 0, 0, 0x10, 0, 0, 0}, false, 0, nil, true},
+	}
+	for _, test := range tests {
+		conn, client := pipeConn(t)
+		go client.Write(test.frame)
+
+		final, opcode, payload, err := conn.readFrame()
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: readFrame succeeded, want an error", test.name)
+			}
+			continue
+		}
+		if err != nil || final != test.final || opcode != test.opcode || !bytes.Equal(payload, test.payload) {
+			t.Errorf("%s: readFrame = %t, %#x, %d bytes, %v", test.name, final, opcode, len(payload), err)
+		}
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		header []byte
+	}{
+		{"empty", 0, []byte{0x81, 0}},
+		{"7-bit length", 125, []byte{0x81, 125}},
+		{"16-bit length", 126, []byte{0x81, 126, 0, 126}},
+		{"16-bit maximum", 0xFFFF, []byte{0x81, 126, 0xFF, 0xFF}},
+		{"64-bit length", 0x10000, []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+	for _, test := range tests {
+		conn, client := pipeConn(t)
+		payload := bytes.Repeat([]byte("a"), test.length)
+		errs := make(chan error, 1)
+		go func() { errs <- conn.writeFrame(wsText, payload) }()
+
+		frame := make([]byte, len(test.header)+test.length)
+		if _, err := io.ReadFull(client, frame); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := <-errs; err != nil {
+			t.Errorf("%s: writeFrame: %v", test.name, err)
+		}
+		if !bytes.Equal(frame[:len(test.header)], test.header) || !bytes.Equal(frame[len(test.header):], payload) {
+			t.Errorf("%s: header = % x, want % x", test.name, frame[:len(test.header)], test.header)
+		}
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	conn, client := pipeConn(t)
+	mask := []byte{1, 2, 3, 4}
+	go func() {
+		client.Write(clientFrame(false, wsText, []byte("Hel"), mask))
+		client.Write(clientFrame(true, wsPing, []byte("ping"), mask))
+		client.Write(clientFrame(true, wsContinuation, []byte("lo"), mask))
+	}()
+	pongs := make(chan []byte, 1)
+	go func() {
+		_, opcode, payload, err := readServerFrame(client)
+		if err == nil && opcode == wsPong {
+			pongs <- payload
+		}
+		close(pongs)
+	}()
+
+	opcode, message, err := conn.readMessage()
+	if err != nil || opcode != wsText || string(message) != "Hello" {
+		t.Errorf("readMessage = %#x, %q, %v, want a text message Hello", opcode, message, err)
+	}
+	if pong := <-pongs; string(pong) != "ping" {
+		t.Errorf("ping answered with %q", pong)
+	}
+}
+
+func TestCloseTruncatesReason(t *testing.T) {
+	conn, client := pipeConn(t)
+	// Each é is two bytes, so 123 bytes falls mid-rune
+	reason := strings.Repeat("é", 100)
+	go conn.close(wsClosePolicyViolation, reason)
+
+	_, opcode, payload, err := readServerFrame(client)
+	if err != nil || opcode != wsClose {
+		t.Fatalf("read close frame: %#x, %v", opcode, err)
+	}
+	if code := binary.BigEndian.Uint16(payload); code != wsClosePolicyViolation {
+		t.Errorf("close code = %d", code)
+	}
+	sent := payload[2:]
+	if len(sent) != 122 || !utf8.Valid(sent) || !strings.HasPrefix(reason, string(sent)) {
+		t.Errorf("close reason is %d bytes, valid UTF-8 %t", len(sent), utf8.Valid(sent))
+	}
+	if err := conn.writeFrame(wsText, []byte("late")); err != errWebSocketClosed {
+		t.Errorf("writeFrame after close = %v, want errWebSocketClosed", err)
+	}
+}
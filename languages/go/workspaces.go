@@ -0,0 +1,160 @@
+package main
+
+// Warm workspace pool
+// Cold execution creates a module for every sample: a temp directory,
+// `go mod init`, goimports, and `go mod tidy` before the sample even
+// starts compiling. With execution.strategy set to "warm", a run instead
+// prepares one module workspace per worker up front, with every sample's
+// requirements resolved and every imported package compiled with the
+// samples' own build flags. Each sample is copied into a free workspace and
+// built there incrementally, and the workspace is cleaned for the next one.
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Execution strategies accepted by execution.strategy
+const (
+	StrategyCold = "cold"
+	StrategyWarm = "warm"
+)
+
+// workspacePool hands out warm module workspaces to samples
+type workspacePool struct {
+	root string
+	dirs chan string
+}
+
+// executionStrategy returns execution.strategy, cold unless set to warm
+func (e *GoExecutor) executionStrategy() string {
+	execution := configSection(e.LanguageConfig, "execution")
+	if configString(execution, "strategy", StrategyCold) == StrategyWarm {
+		return StrategyWarm
+	}
+	return StrategyCold
+}
+
+// startWorkspacePool prepares size warm workspaces for samples and returns
+// the pool with how long preparing it took. A pool is only useful once its
+// module resolves, so failures here fall back to cold workspaces.
+func (e *GoExecutor) startWorkspacePool(ctx context.Context, samples []CodeSample, size int) (*workspacePool, time.Duration, error) {
+	started := time.Now()
+
+	root, err := os.MkdirTemp("", "go-pool-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	pool := &workspacePool{root: root, dirs: make(chan string, size)}
+
+	// The template module imports every package the samples import, so one
+	// resolution covers them all and one build compiles them all
+	template := filepath.Join(root, "template")
+	if err := os.Mkdir(template, 0755); err != nil {
+		pool.close()
+		return nil, 0, err
+	}
+
+	prepareCtx, cancel := context.WithTimeout(ctx, e.warmUpTimeout())
+	defer cancel()
+
+	packages := e.importUnion(samples)
+	if err := e.createImportAllModule(prepareCtx, template, packages); err != nil {
+		pool.close()
+		return nil, 0, err
+	}
+	var usage ResourceUsage
+	e.resolveDependencies(prepareCtx, template, "", packages, &usage)
+
+	goMod, err := os.ReadFile(filepath.Join(template, "go.mod"))
+	if err != nil {
+		pool.close()
+		return nil, 0, err
+	}
+	goSum, err := os.ReadFile(filepath.Join(template, "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		pool.close()
+		return nil, 0, err
+	}
+
+	// Compile with the samples' flags so instrumented builds are warm too.
+	// Packages that don't build are left for their samples to report.
+	args := e.buildCommandArgs()
+	build := exec.CommandContext(prepareCtx, args[0], args[1:]...)
+	build.Dir = template
+	build.Env = e.goToolEnv()
+	built := time.Now()
+	err = build.Run()
+	e.Audit.record(build, "", built, err)
+
+	for i := 0; i < size; i++ {
+		dir := filepath.Join(root, "w"+strconv.Itoa(i))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			pool.close()
+			return nil, 0, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "go.mod"), goMod, 0644); err != nil {
+			pool.close()
+			return nil, 0, err
+		}
+		if goSum != nil {
+			if err := os.WriteFile(filepath.Join(dir, "go.sum"), goSum, 0644); err != nil {
+				pool.close()
+				return nil, 0, err
+			}
+		}
+		pool.dirs <- dir
+	}
+	os.RemoveAll(template)
+
+	return pool, time.Since(started), nil
+}
+
+// acquire waits for a free workspace
+func (p *workspacePool) acquire(ctx context.Context) (string, error) {
+	select {
+	case dir := <-p.dirs:
+		return dir, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// release empties a workspace of everything but its module files and
+// returns it to the pool. A workspace that can't be cleaned is dropped.
+func (p *workspacePool) release(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.Name() == "go.mod" || entry.Name() == "go.sum" {
+			continue
+		}
+		if err := removeWorkspace(filepath.Join(dir, entry.Name())); err != nil {
+			return
+		}
+	}
+	p.dirs <- dir
+}
+
+// close removes every workspace
+func (p *workspacePool) close() {
+	os.RemoveAll(p.root)
+}
+
+// warmWorkspace copies a prepared sample into a pool workspace
+func (e *GoExecutor) warmWorkspace(ctx context.Context, dir string, sample CodeSample, usage *ResourceUsage) error {
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(e.prepareCodeForExecution(sample)), 0644); err != nil {
+		return err
+	}
+	if err := e.writeAudioFixtures(dir, sample); err != nil {
+		return err
+	}
+	e.fixImports(ctx, dir, sample.ID, usage)
+	return nil
+}